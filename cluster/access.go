@@ -0,0 +1,349 @@
+package cluster
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/provision"
+	"mikrodock-cli/utils/certs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AccessInfo describes the client certificate granted to a user
+type AccessInfo struct {
+	User     string
+	Serial   string
+	NotAfter time.Time
+	Revoked  bool
+}
+
+// The Docker daemon does not check CRLs : once a certificate has been revoked,
+// an nginx guard terminates the TLS connections on 2376 and forwards them to the Docker socket
+const tlsGuardName = "mikro-tlsguard"
+const tlsGuardConf = `user root;
+worker_processes 1;
+events { worker_connections 1024; }
+stream {
+  server {
+    listen 2376 ssl;
+    ssl_certificate /etc/docker/cert.pem;
+    ssl_certificate_key /etc/docker/key.pem;
+    ssl_client_certificate /etc/docker/ca.cert;
+    ssl_verify_client on;
+    ssl_crl /etc/docker/crl.pem;
+    proxy_pass unix:/var/run/docker.sock;
+  }
+}
+`
+
+var accessUserRegexp = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+func (c *Cluster) accessUserPath(user string) string {
+	return path.Join(c.AccessPath(), user)
+}
+
+func (c *Cluster) revokedPath() string {
+	return path.Join(c.AccessPath(), "revoked")
+}
+
+// GrantAccess issues a client only certificate for user from the Docker CA, valid for ttl
+func (c *Cluster) GrantAccess(user string, ttl time.Duration) (*AccessInfo, error) {
+	if !accessUserRegexp.MatchString(user) {
+		return nil, fmt.Errorf("Invalid user name %s", user)
+	}
+	if info, err := c.AccessOf(user); err == nil && !info.Revoked && time.Now().Before(info.NotAfter) {
+		return nil, fmt.Errorf("%s already has access until %s, revoke it first", user, info.NotAfter.Format("2006-01-02"))
+	}
+
+	dir := c.accessUserPath(user)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	certGen, err := c.certGenerator(DockerCA)
+	if err != nil {
+		return nil, err
+	}
+	err = certGen.GenerateCert(&certs.CertOpts{
+		CAFile:       path.Join(c.DockerConfigPath(), "ca.cert"),
+		CAKeyFile:    path.Join(c.DockerConfigPath(), "ca.key"),
+		CertFile:     path.Join(dir, "cert.pem"),
+		KeyFile:      path.Join(dir, "key.pem"),
+		KeyBits:      2048,
+		MainHost:     user,
+		AliasIPs:     []string{},
+		AliasHosts:   []string{},
+		MasterMode:   false,
+		Organization: "Mikrodock",
+		Validity:     ttl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Cannot issue certificate : %s", err)
+	}
+	if err = copyFile(path.Join(c.DockerConfigPath(), "ca.cert"), path.Join(dir, "ca.pem"), 0644); err != nil {
+		return nil, err
+	}
+
+	return c.AccessOf(user)
+}
+
+// ExportAccess writes a tar.gz bundle of the certificates of user, named like DOCKER_CERT_PATH expects them.
+// A nodes file lists the Docker endpoint of every partikle
+func (c *Cluster) ExportAccess(user string, bundle string) error {
+	dir := c.accessUserPath(user)
+
+	var nodes strings.Builder
+	for _, p := range c.Partikles {
+		if p != nil {
+			nodes.WriteString(p.Name() + " " + p.Driver.GetDockerURL() + "\n")
+		}
+	}
+
+	out, err := os.OpenFile(bundle, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range []string{"ca.pem", "cert.pem", "key.pem", "nodes"} {
+		var buf []byte
+		if name == "nodes" {
+			buf = []byte(nodes.String())
+		} else if buf, err = ioutil.ReadFile(path.Join(dir, name)); err != nil {
+			return err
+		}
+		mode := int64(0644)
+		if name == "key.pem" {
+			mode = 0600
+		}
+		if err = tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(buf)), ModTime: time.Now()}); err != nil {
+			return err
+		}
+		if _, err = tw.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// AccessOf reads the certificate granted to user
+func (c *Cluster) AccessOf(user string) (*AccessInfo, error) {
+	cert, err := certs.ReadCertificate(path.Join(c.accessUserPath(user), "cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := c.revokedSerials()
+	if err != nil {
+		return nil, err
+	}
+	serial := certs.FormatSerial(cert.SerialNumber)
+	info := &AccessInfo{User: user, Serial: serial, NotAfter: cert.NotAfter}
+	for _, s := range revoked {
+		info.Revoked = info.Revoked || s == serial
+	}
+	return info, nil
+}
+
+// Accesses lists the users having been granted a certificate
+func (c *Cluster) Accesses() ([]AccessInfo, error) {
+	dirs, err := ioutil.ReadDir(c.AccessPath())
+	if os.IsNotExist(err) {
+		return []AccessInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]AccessInfo, 0, len(dirs))
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		info, err := c.AccessOf(dir.Name())
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read access of %s : %s", dir.Name(), err)
+		}
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+// revokedSerials reads the revoked list, one "serial user date" line per certificate
+func (c *Cluster) revokedSerials() ([]string, error) {
+	file, err := os.Open(c.revokedPath())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	serials := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) != 0 {
+			serials = append(serials, fields[0])
+		}
+	}
+	return serials, scanner.Err()
+}
+
+// RevokeAccess revokes the certificate of user and publishes the new CRL on every partikle
+func (c *Cluster) RevokeAccess(user string) error {
+	info, err := c.AccessOf(user)
+	if err != nil {
+		return fmt.Errorf("Cannot read access of %s : %s", user, err)
+	}
+	if info.Revoked {
+		return fmt.Errorf("The access of %s is already revoked", user)
+	}
+
+	if c.CertIssuer != "" {
+		vault, err := certs.ParseVaultURL(c.CertIssuer)
+		if err != nil {
+			return err
+		}
+		if err = vault.Revoke(info.Serial); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(c.revokedPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(file, "%s %s %s\n", info.Serial, user, time.Now().Format(time.RFC3339))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return c.PublishCRL()
+}
+
+// PublishCRL writes the CRL again and uploads it to every partikle
+func (c *Cluster) PublishCRL() error {
+	if err := c.writeCRL(); err != nil {
+		return fmt.Errorf("Cannot write CRL : %s", err)
+	}
+	for _, p := range c.Partikles {
+		if p == nil {
+			return errors.New("A partikle of the cluster cannot be loaded")
+		}
+		logger.Info("Access.CRL", "Publishing CRL on "+p.Name())
+		if err := p.UploadCRL(); err != nil {
+			return fmt.Errorf("Cannot publish CRL on %s : %s", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+// HasCRL tells if a certificate has been revoked, the partikles then need the CRL and the TLS guard
+func (c *Cluster) HasCRL() bool {
+	_, err := os.Stat(c.CRLPath())
+	return err == nil
+}
+
+// writeCRL signs the CRL with every Docker CA in use, the ones of a CA rotation included
+func (c *Cluster) writeCRL() error {
+	if c.CertIssuer != "" {
+		vault, err := certs.ParseVaultURL(c.CertIssuer)
+		if err != nil {
+			return err
+		}
+		return vault.FetchCRL(c.CRLPath())
+	}
+
+	revoked, err := c.revokedSerials()
+	if err != nil {
+		return err
+	}
+	serials := make([]*big.Int, len(revoked))
+	for i, s := range revoked {
+		if serials[i], err = certs.ParseSerial(s); err != nil {
+			return err
+		}
+	}
+
+	dir := c.DockerConfigPath()
+	cas := [][2]string{{path.Join(dir, "ca.cert"), path.Join(dir, "ca.key")}}
+	for _, prefix := range []string{"ca.old", "ca.new"} {
+		cert := path.Join(dir, prefix+".cert")
+		key := path.Join(dir, prefix+".key")
+		if _, err := os.Stat(key); err == nil {
+			cas = append(cas, [2]string{cert, key})
+		}
+	}
+	return certs.GenerateCRL(c.CRLPath(), cas, serials, 0)
+}
+
+// UploadCRL uploads the CRL of the cluster and makes the TLS guard of the partikle reload it
+func (p *Partikle) UploadCRL() error {
+	if err := p.UploadFile(p.Galaksy.CRLPath(), "/etc/docker/crl.pem"); err != nil {
+		return err
+	}
+
+	running, _, err := p.Driver.SSHCommand("docker inspect -f '{{.State.Running}}' " + tlsGuardName + " 2>/dev/null || true")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(running) == "true" {
+		_, errOut, err := p.Driver.SSHCommand("docker kill -s HUP " + tlsGuardName)
+		if err != nil {
+			return fmt.Errorf("Cannot reload TLS guard : %s %s", err, errOut)
+		}
+		return nil
+	}
+	return p.enableTLSGuard()
+}
+
+// enableTLSGuard moves the Docker TCP socket to localhost and runs the TLS guard on 2376 in its place
+func (p *Partikle) enableTLSGuard() error {
+	logger.Info("Access.CRL", "Enabling the TLS guard on "+p.Name())
+
+	if _, errOut, err := p.Driver.SSHCommand(fmt.Sprintf("printf %%s '%s' > /etc/docker/tlsguard.conf", tlsGuardConf)); err != nil {
+		return fmt.Errorf("Cannot write TLS guard config : %s %s", err, errOut)
+	}
+	// ConfigureDocker binds Docker to localhost once the cluster has a CRL, the options written before are moved here
+	sed := fmt.Sprintf("sed -i 's#-H %s#-H %s#' /etc/default/docker", provision.DockerTCPHost, provision.GuardedDockerTCPHost)
+	if _, errOut, err := p.Driver.SSHCommand(sed); err != nil {
+		return fmt.Errorf("Cannot reconfigure Docker : %s %s", err, errOut)
+	}
+
+	if err := p.StopDocker(); err != nil {
+		return err
+	}
+	if err := p.StartDocker(); err != nil {
+		return err
+	}
+
+	run := "docker rm -f " + tlsGuardName + " >/dev/null 2>&1; docker run -d --name " + tlsGuardName +
+		" --restart always --network host -v /etc/docker:/etc/docker:ro -v /var/run/docker.sock:/var/run/docker.sock" +
+		" nginx:alpine nginx -g 'daemon off;' -c /etc/docker/tlsguard.conf"
+	if _, errOut, err := p.Driver.SSHCommand(run); err != nil {
+		return fmt.Errorf("Cannot start TLS guard : %s %s", err, errOut)
+	}
+
+	return p.afterDockerRestart()
+}
@@ -0,0 +1,399 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/certs"
+	"mikrodock-cli/utils/secrets"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	consulAPI "github.com/hashicorp/consul/api"
+	"golang.org/x/crypto/acme"
+)
+
+// The ACME responder serves the key authorizations of the HTTP01 challenges, the proxies route
+// /.well-known/acme-challenge/ of every host to it
+const (
+	ingressResponder      = "mikro-acme"
+	ingressResponderImage = "busybox:1.29"
+	ingressResponderPort  = 8080
+	ingressChallengeDir   = ingressConfigDir + "/acme/.well-known/acme-challenge"
+)
+
+var acmeToken = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ACMEOptions chooses the ACME server and how the challenges are answered
+type ACMEOptions struct {
+	Directory string
+	// CAFile trusts the TLS certificate of a private ACME server, such as the pebble.minica.pem of Pebble
+	CAFile    string
+	Email     string
+	Challenge string
+	// DNSHook manages the TXT records of certs.DNS01, see certs.ExecDNSSolver
+	DNSHook string
+}
+
+// IngressCert is a certificate of the ingress obtained from an ACME server. Its key is sealed with the
+// stack secrets key, it is only unsealed to be uploaded to the proxies
+type IngressCert struct {
+	Domains   []string
+	Directory string
+	Challenge string
+	DNSHook   string `json:",omitempty"`
+	NotAfter  time.Time
+	CertPEM   []byte
+	SealedKey []byte
+}
+
+// fileName is the name of the certificate on the proxies, the first domain without its wildcard
+func (ic IngressCert) fileName() string {
+	return strings.Replace(ic.Domains[0], "*", "wildcard", 1)
+}
+
+// acmeAccount is the ACME account of the cluster, its key is sealed like the ones of the certificates
+type acmeAccount struct {
+	Directory string
+	URI       string
+	SealedKey []byte
+}
+
+// runACMEResponder runs the ACME responder on the partikle and registers it in the Consul catalog
+func (c *Cluster) runACMEResponder(p *Partikle) error {
+	err := p.replaceContainer(ingressResponder, &container.Config{
+		Image:  ingressResponderImage,
+		Cmd:    []string{"httpd", "-f", "-p", fmt.Sprint(ingressResponderPort), "-h", "/www"},
+		Labels: map[string]string{NetworkLabel: "ingress"},
+	}, &container.HostConfig{
+		Binds:         []string{ingressConfigDir + "/acme:/www:ro"},
+		NetworkMode:   container.NetworkMode(OverlayNetwork),
+		RestartPolicy: container.RestartPolicy{Name: "always"},
+	})
+	if err != nil {
+		return fmt.Errorf("Cannot run the ACME responder of %s : %s", p.Name(), err)
+	}
+	ip, err := p.containerIP(ingressResponder, OverlayNetwork)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return err
+	}
+	registration := &consulAPI.AgentServiceRegistration{
+		ID:      ingressResponder + "-" + p.Name(),
+		Name:    ingressResponder,
+		Address: ip,
+		Port:    ingressResponderPort,
+		Tags: []string{
+			"traefik.enable=true",
+			"traefik.frontend.rule=PathPrefix:/.well-known/acme-challenge/",
+			"traefik.frontend.priority=1000",
+		},
+		Meta: map[string]string{"partikle": p.Name()},
+	}
+	if err = client.Agent().ServiceRegister(registration); err != nil {
+		return fmt.Errorf("Cannot register %s : %s", registration.ID, err)
+	}
+	return nil
+}
+
+// ingressHTTPSolver answers certs.HTTP01 from the ACME responders of every proxy, the ACME server may reach any of them
+type ingressHTTPSolver struct {
+	proxies []*Partikle
+}
+
+func (s ingressHTTPSolver) Present(domain string, token string, value string) error {
+	if !acmeToken.MatchString(token) {
+		return fmt.Errorf("Invalid ACME token %s", token)
+	}
+	for _, p := range s.proxies {
+		if err := p.Driver.Copy(int64(len(value)), 0644, token, strings.NewReader(value), ingressChallengeDir, nil); err != nil {
+			return fmt.Errorf("Cannot upload the challenge of %s to %s : %s", domain, p.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (s ingressHTTPSolver) CleanUp(domain string, token string, value string) error {
+	if !acmeToken.MatchString(token) {
+		return fmt.Errorf("Invalid ACME token %s", token)
+	}
+	for _, p := range s.proxies {
+		if _, errOut, err := p.Driver.SSHCommand("rm -f " + ingressChallengeDir + "/" + token); err != nil {
+			logger.Warn("Cluster.Ingress.ACME", "Cannot remove the challenge of "+domain+" from "+p.Name()+" : "+err.Error()+" "+errOut)
+		}
+	}
+	return nil
+}
+
+// ingressProxyPartikles returns the partikles recorded as running the reverse proxy
+func (c *Cluster) ingressProxyPartikles() ([]*Partikle, error) {
+	names, err := c.IngressProxies()
+	if err != nil {
+		return nil, err
+	}
+	proxies := make([]*Partikle, 0, len(names))
+	for _, name := range names {
+		if p := c.PartikleByName(name); p != nil {
+			proxies = append(proxies, p)
+		} else {
+			logger.Warn("Cluster.Ingress", "Cannot find the proxy "+name)
+		}
+	}
+	return proxies, nil
+}
+
+// acmeClient returns a client of the registered account, the account is created when the directory changed
+func (c *Cluster) acmeClient(ctx context.Context, opts ACMEOptions) (*acme.Client, error) {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	content, err := helper.Get(IngressPrefix + "/acme/account")
+	if err != nil {
+		return nil, err
+	}
+	account := acmeAccount{}
+	if content != nil {
+		if err = json.Unmarshal(content, &account); err != nil {
+			return nil, fmt.Errorf("Cannot read the ACME account : %s", err)
+		}
+	}
+	sealKey, err := c.sealKey(helper)
+	if err != nil {
+		return nil, err
+	}
+	var key []byte
+	if account.Directory == opts.Directory && len(account.SealedKey) != 0 {
+		if key, err = secrets.Unseal(sealKey, account.SealedKey); err != nil {
+			return nil, fmt.Errorf("Cannot unseal the ACME account key : %s", err)
+		}
+	}
+
+	client, err := certs.NewACMEClient(opts.Directory, opts.CAFile, key)
+	if err != nil {
+		return nil, err
+	}
+	registered, err := certs.RegisterACME(ctx, client, opts.Email)
+	if err != nil {
+		return nil, err
+	}
+	if account.Directory == opts.Directory && account.URI == registered.URI {
+		return client, nil
+	}
+
+	if key, err = certs.ACMEAccountKey(client); err != nil {
+		return nil, err
+	}
+	account = acmeAccount{Directory: opts.Directory, URI: registered.URI}
+	if account.SealedKey, err = secrets.Seal(sealKey, key); err != nil {
+		return nil, fmt.Errorf("Cannot seal the ACME account key : %s", err)
+	}
+	if content, err = json.Marshal(account); err != nil {
+		return nil, err
+	}
+	tree := helper.NewTree(IngressPrefix)
+	tree.AddSubCategory("acme").AddChild("account", content)
+	if err = helper.SendTree(tree); err != nil {
+		return nil, fmt.Errorf("Cannot record the ACME account : %s", err)
+	}
+	return client, nil
+}
+
+// ObtainIngressCert obtains a certificate of the domains from the ACME server, records it and installs it on the proxies
+func (c *Cluster) ObtainIngressCert(domains []string, opts ACMEOptions) (*IngressCert, error) {
+	domains, err := certs.ValidateACMEDomains(domains, opts.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Directory == "" {
+		opts.Directory = certs.LetsEncryptDirectory
+	}
+	proxies, err := c.ingressProxyPartikles()
+	if err != nil {
+		return nil, err
+	}
+	if len(proxies) == 0 {
+		return nil, errors.New("No proxy runs the ingress, deploy it first")
+	}
+
+	var solver certs.ACMESolver = ingressHTTPSolver{proxies: proxies}
+	if opts.Challenge == certs.DNS01 {
+		if opts.DNSHook == "" {
+			return nil, fmt.Errorf("The %s challenge needs a DNS hook", certs.DNS01)
+		}
+		solver = certs.ExecDNSSolver{Hook: opts.DNSHook}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	client, err := c.acmeClient(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, keyPEM, err := certs.ObtainACME(ctx, client, domains, opts.Challenge, solver)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := certs.ParseCertificatePEM(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read the certificate of %s : %s", domains[0], err)
+	}
+
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	sealKey, err := c.sealKey(helper)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := secrets.Seal(sealKey, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot seal the key of %s : %s", domains[0], err)
+	}
+	ic := IngressCert{
+		Domains:   domains,
+		Directory: opts.Directory,
+		Challenge: opts.Challenge,
+		DNSHook:   opts.DNSHook,
+		NotAfter:  cert.NotAfter,
+		CertPEM:   certPEM,
+		SealedKey: sealed,
+	}
+	content, err := json.Marshal(ic)
+	if err != nil {
+		return nil, err
+	}
+	tree := helper.NewTree(IngressPrefix)
+	tree.AddSubCategory("certs").AddChild(domains[0], content)
+	if err = helper.SendTree(tree); err != nil {
+		return nil, fmt.Errorf("Cannot record the certificate of %s : %s", domains[0], err)
+	}
+	return &ic, c.syncIngressCerts(proxies)
+}
+
+// IngressCerts returns the certificates of the ingress, by domain
+func (c *Cluster) IngressCerts() ([]IngressCert, error) {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := helper.Keys(IngressPrefix + "/certs/")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list the ingress certificates : %s", err)
+	}
+	list := make([]IngressCert, 0, len(keys))
+	for _, key := range keys {
+		content, err := helper.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		var ic IngressCert
+		if err = json.Unmarshal(content, &ic); err != nil {
+			return nil, fmt.Errorf("Cannot read %s : %s", key, err)
+		}
+		list = append(list, ic)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Domains[0] < list[j].Domains[0]
+	})
+	return list, nil
+}
+
+// RenewIngressCerts obtains again the certificates expiring within the duration, from their ACME server and with
+// their challenge. opts gives the local CA file and email, and replaces the recorded DNS hook when it has one
+func (c *Cluster) RenewIngressCerts(within time.Duration, opts ACMEOptions) ([]IngressCert, error) {
+	list, err := c.IngressCerts()
+	if err != nil {
+		return nil, err
+	}
+	renewed := make([]IngressCert, 0)
+	failed := 0
+	for _, ic := range list {
+		if time.Until(ic.NotAfter) > within {
+			continue
+		}
+		renewOpts := ACMEOptions{
+			Directory: ic.Directory,
+			CAFile:    opts.CAFile,
+			Email:     opts.Email,
+			Challenge: ic.Challenge,
+			DNSHook:   ic.DNSHook,
+		}
+		if opts.DNSHook != "" {
+			renewOpts.DNSHook = opts.DNSHook
+		}
+		fresh, err := c.ObtainIngressCert(ic.Domains, renewOpts)
+		if err != nil {
+			logger.Warn("Cluster.Ingress.ACME", "Cannot renew the certificate of "+ic.Domains[0]+" : "+err.Error())
+			failed++
+			continue
+		}
+		renewed = append(renewed, *fresh)
+	}
+	if failed > 0 {
+		return renewed, fmt.Errorf("Cannot renew %d certificates", failed)
+	}
+	return renewed, nil
+}
+
+// traefikCertsConfig lists the certificates for the file provider. The expiry of each certificate changes the
+// file when it is renewed, Traefik reloads it
+func traefikCertsConfig(list []IngressCert) string {
+	var config bytes.Buffer
+	for _, ic := range list {
+		fmt.Fprintf(&config, "# %s expires %s\n", strings.Join(ic.Domains, " "), ic.NotAfter.Format(time.RFC3339))
+		config.WriteString("[[tls]]\n  entryPoints = [\"https\"]\n  [tls.certificate]\n")
+		fmt.Fprintf(&config, "  certFile = \"/etc/traefik/certs/%s.crt\"\n", ic.fileName())
+		fmt.Fprintf(&config, "  keyFile = \"/etc/traefik/certs/%s.key\"\n\n", ic.fileName())
+	}
+	return config.String()
+}
+
+// syncIngressCerts uploads the recorded certificates to the proxies, then their configuration to reload them
+func (c *Cluster) syncIngressCerts(proxies []*Partikle) error {
+	list, err := c.IngressCerts()
+	if err != nil {
+		return err
+	}
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return err
+	}
+	sealKey, err := c.sealKey(helper)
+	if err != nil {
+		return err
+	}
+	keys := make([][]byte, len(list))
+	for i, ic := range list {
+		if keys[i], err = secrets.Unseal(sealKey, ic.SealedKey); err != nil {
+			return fmt.Errorf("Cannot unseal the key of %s : %s", ic.Domains[0], err)
+		}
+	}
+
+	config := traefikCertsConfig(list)
+	for _, p := range proxies {
+		for i, ic := range list {
+			if err = p.Driver.Copy(int64(len(ic.CertPEM)), 0644, ic.fileName()+".crt", bytes.NewReader(ic.CertPEM), ingressConfigDir+"/certs", nil); err != nil {
+				return fmt.Errorf("Cannot upload the certificate of %s to %s : %s", ic.Domains[0], p.Name(), err)
+			}
+			if err = p.Driver.Copy(int64(len(keys[i])), 0600, ic.fileName()+".key", bytes.NewReader(keys[i]), ingressConfigDir+"/certs", nil); err != nil {
+				return fmt.Errorf("Cannot upload the key of %s to %s : %s", ic.Domains[0], p.Name(), err)
+			}
+		}
+		if err = p.Driver.Copy(int64(len(config)), 0644, "certs.toml", strings.NewReader(config), ingressConfigDir+"/dynamic", nil); err != nil {
+			return fmt.Errorf("Cannot reload the certificates of %s : %s", p.Name(), err)
+		}
+	}
+	return nil
+}
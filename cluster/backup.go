@@ -0,0 +1,122 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+// backupTimeFormat timestamps the snapshot files, it sorts in chronological order
+const backupTimeFormat = "20060102-150405"
+
+const backupExt = ".snap"
+
+// BackupInfo is a Consul snapshot of the cluster
+type BackupInfo struct {
+	File string
+	Time time.Time
+	Size int64
+}
+
+// CreateBackup saves a Consul snapshot of the cluster in dir, named after the cluster and the current time
+func (c *Cluster) CreateBackup(dir string) (string, error) {
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return "", fmt.Errorf("Cannot connect to Consul : %s", err)
+	}
+	// Snapshots hold the ACL tokens, they need the bootstrap token
+	snapshot, _, err := client.Snapshot().Save(&consulAPI.QueryOptions{Token: c.consulAdminToken()})
+	if err != nil {
+		return "", fmt.Errorf("Cannot take the Consul snapshot : %s", err)
+	}
+	defer snapshot.Close()
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	file := path.Join(dir, c.Name+"-"+time.Now().UTC().Format(backupTimeFormat)+backupExt)
+	tmp, err := os.OpenFile(file+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(tmp, snapshot)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("Cannot write the snapshot : %s", err)
+	}
+	return file, os.Rename(tmp.Name(), file)
+}
+
+// Backups lists the snapshots of the cluster found in dir, the oldest first
+func (c *Cluster) Backups(dir string) ([]BackupInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]BackupInfo, 0, len(entries))
+	prefix := c.Name + "-"
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, backupExt) {
+			continue
+		}
+		taken, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), backupExt))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{File: path.Join(dir, name), Time: taken, Size: entry.Size()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.Before(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// PruneBackups removes the oldest snapshots of dir, keeping the keep latest ones
+func (c *Cluster) PruneBackups(dir string, keep int) ([]string, error) {
+	backups, err := c.Backups(dir)
+	if err != nil {
+		return nil, err
+	}
+	removed := make([]string, 0)
+	for len(backups) > keep {
+		if err = os.Remove(backups[0].File); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backups[0].File)
+		backups = backups[1:]
+	}
+	return removed, nil
+}
+
+// RestoreBackup replaces the whole Consul state of the cluster with a snapshot
+func (c *Cluster) RestoreBackup(file string) error {
+	snapshot, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return fmt.Errorf("Cannot connect to Consul : %s", err)
+	}
+	if err = client.Snapshot().Restore(&consulAPI.WriteOptions{Token: c.consulAdminToken()}, snapshot); err != nil {
+		return fmt.Errorf("Cannot restore %s : %s", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/certs"
+	"os"
+	"path"
+)
+
+// CAKind selects the certificate authority to rotate
+type CAKind string
+
+const (
+	DockerCA CAKind = "docker"
+	ConsulCA CAKind = "consul"
+)
+
+// Phases of a CA rotation, in order
+const (
+	caPhaseNewCA     = "new-ca"
+	caPhaseDualTrust = "dual-trust"
+	caPhaseReissue   = "reissue"
+	caPhaseDropOld   = "drop-old"
+)
+
+// caRotation is the checkpoint of a CA rotation, saved after every phase and partikle
+type caRotation struct {
+	Kind  CAKind
+	Phase string
+	Done  []string
+}
+
+func ParseCAKind(name string) (CAKind, error) {
+	switch CAKind(name) {
+	case DockerCA, ConsulCA:
+		return CAKind(name), nil
+	default:
+		return "", fmt.Errorf("Unknown CA %s (docker or consul)", name)
+	}
+}
+
+func (c *Cluster) caDir(kind CAKind) string {
+	if kind == ConsulCA {
+		return c.ConsulConfPath()
+	}
+	return c.DockerConfigPath()
+}
+
+// certGenerator returns the generator issuing the certificates signed by the kind CA
+func (c *Cluster) certGenerator(kind CAKind) (certs.CertGenerator, error) {
+	issuer := c.CertIssuer
+	if kind == ConsulCA {
+		issuer = c.ConsulCertIssuer
+	}
+	if issuer == "" {
+		return certs.NewX509CertGenerator(), nil
+	}
+	vault, err := certs.ParseVaultURL(issuer)
+	if err != nil {
+		return nil, err
+	}
+	return vault, nil
+}
+
+// caGenerator returns the generator creating the kind CA at init, an external CA is copied
+func (c *Cluster) caGenerator(kind CAKind) (certs.CertGenerator, error) {
+	external := c.ExternalCA
+	if kind == ConsulCA {
+		external = c.ExternalConsulCA
+	}
+	if external != nil {
+		return external, nil
+	}
+	return c.certGenerator(kind)
+}
+
+func caOrganization(kind CAKind) string {
+	if kind == ConsulCA {
+		return "Mikrodock-Consul-CA"
+	}
+	return "Mikrodock-CA"
+}
+
+// generatedCA tells if the kind CA has been generated by the cluster and can be rotated by it
+func (c *Cluster) generatedCA(kind CAKind) (bool, error) {
+	if (kind == DockerCA && c.CertIssuer != "") || (kind == ConsulCA && c.ConsulCertIssuer != "") {
+		return false, nil
+	}
+	cert, err := certs.ReadCertificate(path.Join(c.caDir(kind), "ca.cert"))
+	if err != nil {
+		return false, err
+	}
+	if len(cert.Subject.Organization) != 1 || cert.Subject.Organization[0] != caOrganization(kind) {
+		return false, nil
+	}
+	return cert.CheckSignatureFrom(cert) == nil, nil
+}
+
+func (c *Cluster) caRotationPath() string {
+	return path.Join(c.DeployDir, "ca-rotation.json")
+}
+
+// CARotationInProgress returns the CA being rotated, or an empty string
+func (c *Cluster) CARotationInProgress() (CAKind, string, error) {
+	rotation, err := c.loadCARotation()
+	if err != nil || rotation == nil {
+		return "", "", err
+	}
+	return rotation.Kind, rotation.Phase, nil
+}
+
+// RotateCA replaces a CA without breaking the trust between the partikles :
+// a bundle trusting both CAs is distributed, the leaf certificates are reissued
+// from the new CA, then the old CA is dropped from the bundle.
+// An interrupted rotation is resumed where it stopped
+func (c *Cluster) RotateCA(kind CAKind) error {
+	rotation, err := c.loadCARotation()
+	if err != nil {
+		return err
+	}
+	if rotation == nil {
+		generated, err := c.generatedCA(kind)
+		if err != nil {
+			return fmt.Errorf("Cannot read the %s CA : %s", kind, err)
+		}
+		if !generated {
+			return fmt.Errorf("The %s CA is issued by an external authority, it must be rotated there", kind)
+		}
+		rotation = &caRotation{Kind: kind, Phase: caPhaseNewCA}
+		if err = c.saveCARotation(rotation); err != nil {
+			return err
+		}
+	} else if rotation.Kind != kind {
+		return fmt.Errorf("A rotation of the %s CA is in progress, finish it first", rotation.Kind)
+	} else {
+		logger.Info("CA.Rotate", "Resuming rotation of the "+string(kind)+" CA at phase "+rotation.Phase)
+	}
+
+	dir := c.caDir(kind)
+	caCert := path.Join(dir, "ca.cert")
+	caKey := path.Join(dir, "ca.key")
+	oldCert := path.Join(dir, "ca.old.cert")
+	oldKey := path.Join(dir, "ca.old.key")
+	newCert := path.Join(dir, "ca.new.cert")
+	newKey := path.Join(dir, "ca.new.key")
+
+	for {
+		var next string
+		switch rotation.Phase {
+		case caPhaseNewCA:
+			logger.Info("CA.Rotate", "Generating the new "+string(kind)+" CA")
+			if err = copyFile(caCert, oldCert, 0644); err != nil {
+				return err
+			}
+			if err = copyFile(caKey, oldKey, 0600); err != nil {
+				return err
+			}
+			if err = certs.NewX509CertGenerator().GenerateCACert(newCert, newKey, caOrganization(kind), 2048); err != nil {
+				return err
+			}
+			next = caPhaseDualTrust
+
+		case caPhaseDualTrust:
+			logger.Info("CA.Rotate", "Distributing a bundle trusting both CAs")
+			// The old CA stays first : it still signs the certificates
+			if err = writeBundle(caCert, oldCert, newCert); err != nil {
+				return err
+			}
+			if err = c.rollCADeployment(rotation, false); err != nil {
+				return err
+			}
+			next = caPhaseReissue
+
+		case caPhaseReissue:
+			logger.Info("CA.Rotate", "Reissuing the certificates from the new CA")
+			if err = writeBundle(caCert, newCert, oldCert); err != nil {
+				return err
+			}
+			if err = copyFile(newKey, caKey, 0600); err != nil {
+				return err
+			}
+			if err = c.rollCADeployment(rotation, true); err != nil {
+				return err
+			}
+			next = caPhaseDropOld
+
+		case caPhaseDropOld:
+			logger.Info("CA.Rotate", "Dropping the old CA")
+			if err = writeBundle(caCert, newCert); err != nil {
+				return err
+			}
+			if err = c.rollCADeployment(rotation, false); err != nil {
+				return err
+			}
+			for _, file := range []string{oldCert, oldKey, newCert, newKey} {
+				os.Remove(file)
+			}
+			logger.Info("CA.Rotate", "The "+string(kind)+" CA has been rotated")
+			return os.Remove(c.caRotationPath())
+
+		default:
+			return fmt.Errorf("Unknown CA rotation phase %s", rotation.Phase)
+		}
+
+		rotation.Phase = next
+		rotation.Done = nil
+		if err = c.saveCARotation(rotation); err != nil {
+			return err
+		}
+	}
+}
+
+// rollCADeployment deploys the certificates on the partikles not done yet in the current phase
+func (c *Cluster) rollCADeployment(rotation *caRotation, reissue bool) error {
+	// The TLS guard needs a CRL from every CA of the bundle
+	if rotation.Kind == DockerCA && c.HasCRL() {
+		if err := c.writeCRL(); err != nil {
+			return fmt.Errorf("Cannot write CRL : %s", err)
+		}
+	}
+	partikles, err := c.rollingOrder()
+	if err != nil {
+		return err
+	}
+
+	if reissue && rotation.Kind == ConsulCA {
+		if err = c.GenerateConsulCLICert(); err != nil {
+			return fmt.Errorf("Cannot reissue the Consul CLI certificate : %s", err)
+		}
+	}
+
+	done := make(map[string]bool)
+	for _, name := range rotation.Done {
+		done[name] = true
+	}
+
+	for _, p := range partikles {
+		if done[p.Name()] {
+			continue
+		}
+		logger.Info("CA.Rotate", "Deploying certificates on "+p.Name())
+		if err = p.DeployCerts(reissue && rotation.Kind == DockerCA, reissue && rotation.Kind == ConsulCA); err != nil {
+			return fmt.Errorf("Cannot deploy certificates on %s : %s", p.Name(), err)
+		}
+		rotation.Done = append(rotation.Done, p.Name())
+		if err = c.saveCARotation(rotation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) loadCARotation() (*caRotation, error) {
+	buf, err := ioutil.ReadFile(c.caRotationPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rotation := &caRotation{}
+	if err = json.Unmarshal(buf, rotation); err != nil {
+		return nil, fmt.Errorf("Corrupted CA rotation checkpoint : %s", err)
+	}
+	return rotation, nil
+}
+
+func (c *Cluster) saveCARotation(rotation *caRotation) error {
+	buf, err := json.MarshalIndent(rotation, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.caRotationPath(), buf, 0600)
+}
+
+// writeBundle concatenates the PEM certificates, the first one is the signing CA
+func writeBundle(destination string, sources ...string) error {
+	bundle := make([]byte, 0)
+	for _, source := range sources {
+		buf, err := ioutil.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		bundle = append(bundle, buf...)
+	}
+	return ioutil.WriteFile(destination, bundle, 0644)
+}
+
+func copyFile(source string, destination string, mode os.FileMode) error {
+	buf, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(destination, buf, mode)
+}
@@ -0,0 +1,206 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/certs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CertInfo describes a certificate stored in the deployment directory
+type CertInfo struct {
+	Owner    string
+	Path     string
+	IsCA     bool
+	Subject  string
+	NotAfter time.Time
+}
+
+// Certificates lists the CA and leaf certificates of the cluster and of its partikles
+func (c *Cluster) Certificates() ([]CertInfo, error) {
+	dirs := [][2]string{
+		{"docker", c.DockerConfigPath()},
+		{"consul", c.ConsulConfPath()},
+	}
+	for _, p := range c.Partikles {
+		if p != nil {
+			dirs = append(dirs, [2]string{p.Name(), p.CertsPath()})
+		}
+	}
+
+	infos := make([]CertInfo, 0)
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name := file.Name()
+			if file.IsDir() || strings.Contains(name, "key") || !(strings.HasSuffix(name, ".cert") || strings.HasSuffix(name, ".pem")) {
+				continue
+			}
+			certPath := path.Join(dir[1], name)
+			cert, err := certs.ReadCertificate(certPath)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot read %s : %s", certPath, err)
+			}
+			subject := cert.Subject.CommonName
+			if subject == "" && len(cert.Subject.Organization) != 0 {
+				subject = cert.Subject.Organization[0]
+			}
+			infos = append(infos, CertInfo{
+				Owner:    dir[0],
+				Path:     certPath,
+				IsCA:     cert.IsCA,
+				Subject:  subject,
+				NotAfter: cert.NotAfter,
+			})
+		}
+	}
+	return infos, nil
+}
+
+// GenerateConsulCLICert issues the client certificate used by the CLI to reach Consul
+func (c *Cluster) GenerateConsulCLICert() error {
+	certGen, err := c.certGenerator(ConsulCA)
+	if err != nil {
+		return err
+	}
+	return certGen.GenerateCert(&certs.CertOpts{
+		CAFile:       path.Join(c.ConsulConfPath(), "ca.cert"),
+		CAKeyFile:    path.Join(c.ConsulConfPath(), "ca.key"),
+		CertFile:     c.ConsulCLICertPath(),
+		KeyFile:      c.ConsulCLIKeyPath(),
+		KeyBits:      2048,
+		MainHost:     "cli.mikrodock.local",
+		AliasIPs:     []string{},
+		AliasHosts:   []string{},
+		MasterMode:   false,
+		Organization: "Mikrodock-Consul",
+	})
+}
+
+// RotateCerts reissues every leaf certificate from the current CAs.
+// Partikles are restarted one at a time, the konsultants first. Only a cluster of 3 konsultants or more keeps
+// running : restarting the single konsultant of a cluster stops Consul, the cluster store and kinetik
+func (c *Cluster) RotateCerts() error {
+	partikles, err := c.rollingOrder()
+	if err != nil {
+		return err
+	}
+
+	if err = c.GenerateConsulCLICert(); err != nil {
+		return fmt.Errorf("Cannot rotate the Consul CLI certificate : %s", err)
+	}
+
+	for _, p := range partikles {
+		logger.Info("Certs.Rotate", "Rotating certificates of "+p.Name())
+		if err := p.RotateCerts(); err != nil {
+			return fmt.Errorf("Cannot rotate certificates of %s : %s", p.Name(), err)
+		}
+	}
+
+	logger.Info("Certs.Rotate", "Certificates rotated")
+	return nil
+}
+
+// rollingOrder returns the partikles in the order they are restarted, the konsultants first.
+// It warns when the konsultants cannot keep a Consul quorum during the restarts
+func (c *Cluster) rollingOrder() ([]*Partikle, error) {
+	partikles := make([]*Partikle, 0, len(c.Partikles))
+	for _, p := range c.Partikles {
+		if p == nil {
+			return nil, fmt.Errorf("A partikle of the cluster cannot be loaded")
+		}
+		partikles = append(partikles, p)
+	}
+	sort.Slice(partikles, func(i, j int) bool {
+		if partikles[i].IsConsulServer() != partikles[j].IsConsulServer() {
+			return partikles[i].IsConsulServer()
+		}
+		return partikles[i].Name() < partikles[j].Name()
+	})
+	if servers := len(c.ConsulServers()); servers < 3 {
+		logger.Warn("Certs.Rotate", fmt.Sprintf("The cluster has %d konsultant(s) : Consul, the cluster store and kinetik are down while a konsultant restarts, add konsultants for a rotation without downtime", servers))
+	}
+	return partikles, nil
+}
+
+// RotateCerts reissues the certificates of the partikle, uploads them and restarts Docker (and Consul)
+func (p *Partikle) RotateCerts() error {
+	return p.DeployCerts(true, true)
+}
+
+// DeployCerts uploads the current CAs and certificates of the partikle, reissuing the leaf
+// certificates first if asked, then restarts Docker (and Consul)
+func (p *Partikle) DeployCerts(reissueDocker bool, reissueConsul bool) error {
+	isKonsultant := p.IsConsulServer()
+
+	if reissueDocker {
+		if err := p.GenerateDockerCerts(p.Galaksy.DockerConfigPath()); err != nil {
+			return err
+		}
+	}
+	if reissueConsul {
+		if err := p.GenerateConsulCerts(p.Galaksy.ConsulConfPath()); err != nil {
+			return err
+		}
+	}
+
+	if err := p.UploadDockerCerts(); err != nil {
+		return err
+	}
+	consulRemotePath := "/etc/docker"
+	if isKonsultant {
+		consulRemotePath = "/opt/consul-ssl"
+	}
+	if err := p.UploadConsulCerts(consulRemotePath); err != nil {
+		return err
+	}
+
+	if err := p.StopDocker(); err != nil {
+		return err
+	}
+	if err := p.StartDocker(); err != nil {
+		return err
+	}
+	return p.afterDockerRestart()
+}
+
+// afterDockerRestart waits for Docker, applies the published ports again and restarts the Consul server of a konsultant
+func (p *Partikle) afterDockerRestart() error {
+	if err := p.WaitDocker(); err != nil {
+		return err
+	}
+	if err := p.reapplyPorts(); err != nil {
+		return err
+	}
+
+	// A konsultant being created has no Consul server yet
+	if p.IsConsulServer() {
+		exists, err := p.hasContainer("mikro-consul")
+		if err != nil || !exists {
+			return err
+		}
+		if err := p.RestartContainer("mikro-consul"); err != nil {
+			return err
+		}
+		if _, err := p.ConnectToConsul(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasContainer tells if the container exists on the partikle, running or not
+func (p *Partikle) hasContainer(name string) (bool, error) {
+	out, errOut, err := p.Driver.SSHCommand("docker ps -a -q -f name=^/" + name + "$")
+	if err != nil {
+		return false, fmt.Errorf("Cannot list the containers of %s : %s %s", p.Name(), err, errOut)
+	}
+	return strings.TrimSpace(out) != "", nil
+}
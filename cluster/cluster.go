@@ -9,18 +9,19 @@ import (
 	"mikrodock-cli/drivers"
 	"mikrodock-cli/logger"
 	"mikrodock-cli/utils"
+	"mikrodock-cli/utils/audit"
+	"mikrodock-cli/utils/certs"
 	consulhelpers "mikrodock-cli/utils/consul-helpers"
+	"mikrodock-cli/utils/mssh"
+	"mikrodock-cli/utils/secrets"
 	"os"
 	"os/user"
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
-	"context"
-
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/network"
 	homedir "github.com/mitchellh/go-homedir"
 	"golang.org/x/crypto/ssh"
 
@@ -32,15 +33,70 @@ type ClusterDriver struct {
 	Config     map[string]string
 }
 
+// String hides the config, it holds the access token
+func (d ClusterDriver) String() string {
+	return "{DriverName:" + d.DriverName + " Config:[redacted]}"
+}
+
+func (d ClusterDriver) GoString() string {
+	return "cluster.ClusterDriver" + d.String()
+}
+
 type Cluster struct {
 	Name          string
 	DeployDir     string
 	Driver        ClusterDriver
 	DriverFactory drivers.InitDriver
 
+	// JumpHosts are crossed to reach every partikle, unless the partikle overrides them
+	JumpHosts []mssh.JumpHost
+
+	// KonsultantCount is the number of Consul servers created at init, 1, 3 or 5
+	KonsultantCount int
+
+	// SSHKeyType and EncryptSSHKey are only used to generate the cluster key at init
+	SSHKeyType    mssh.KeyType
+	EncryptSSHKey bool
+
+	// Audit records every remote action done by the partikle drivers
+	Audit *audit.Log
+
+	// Secrets stores the access token and the other credentials of the cluster
+	Secrets secrets.Provider
+
+	// CertIssuer and ConsulCertIssuer are the Vault PKI issuing the Docker and Consul certificates.
+	// The local CAs are used when they are empty
+	CertIssuer       string
+	ConsulCertIssuer string
+
+	// ExternalCA and ExternalConsulCA are existing CAs used at init instead of generating new ones
+	ExternalCA       *certs.FileCAGenerator
+	ExternalConsulCA *certs.FileCAGenerator
+
 	Partikles []*Partikle
 }
 
+// PartikleByName returns the partikle called name, nil when the cluster has none
+func (c *Cluster) PartikleByName(name string) *Partikle {
+	for _, p := range c.Partikles {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Klerks returns the workers of the cluster, every partikle but the konsultants and the konduktor
+func (c *Cluster) Klerks() []*Partikle {
+	var klerks []*Partikle
+	for _, p := range c.Partikles {
+		if p != nil && !p.IsConsulServer() && p.Name() != "konduktor" {
+			klerks = append(klerks, p)
+		}
+	}
+	return klerks
+}
+
 func LoadCluster(clusterName string) (*Cluster, error) {
 
 	var c *Cluster
@@ -69,7 +125,29 @@ func LoadCluster(clusterName string) (*Cluster, error) {
 		scanner.Scan()
 		driverName := scanner.Text()
 		scanner.Scan()
-		token := scanner.Text()
+		secretRef := scanner.Text()
+		scanner.Scan()
+		jumpHosts, err := mssh.ParseJumpHosts(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		scanner.Scan()
+		certIssuer := scanner.Text()
+		scanner.Scan()
+		consulCertIssuer := scanner.Text()
+
+		provider, err := secrets.Open(secretRef, clusterName)
+		if err == secrets.ErrUnknownProvider {
+			logger.Warn("Cluster.Load", "The access token of "+clusterName+" is stored in clear, run credentials migrate")
+			provider, err = &secrets.PlainProvider{Token: secretRef}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		token, err := provider.Get(secrets.AccessToken)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read access token : %s", err)
+		}
 
 		config := make(map[string]string)
 		config["access-token"] = token
@@ -79,11 +157,15 @@ func LoadCluster(clusterName string) (*Cluster, error) {
 				Config:     config,
 				DriverName: driverName,
 			},
-			Name: clusterName,
+			Name:             clusterName,
+			JumpHosts:        jumpHosts,
+			CertIssuer:       certIssuer,
+			ConsulCertIssuer: consulCertIssuer,
+			Secrets:          provider,
 		}
 
 		initDriver, _ := drivers.NewDriver(c.Driver.DriverName, c.Driver.Config)
-		c.DriverFactory = initDriver
+		c.setDriverFactory(initDriver)
 
 		partiklesDir, _ := ioutil.ReadDir(path.Join(c.DeployDir, "partikles"))
 		c.Partikles = make([]*Partikle, len(partiklesDir), len(partiklesDir))
@@ -99,6 +181,10 @@ func LoadCluster(clusterName string) (*Cluster, error) {
 
 func (c *Cluster) Init() {
 
+	if err := c.Secrets.Set(secrets.AccessToken, c.Driver.Config["access-token"]); err != nil {
+		logger.Fatal("ClusterInit", "Cannot store access token : "+err.Error())
+	}
+
 	generateMinimalFiles(c)
 
 	logger.Info("ClusterInit", "Creating Konsultant Machine")
@@ -108,61 +194,59 @@ func (c *Cluster) Init() {
 		logger.Fatal("ClusterInit", err.Error())
 	}
 
-	c.DriverFactory = initDriver
+	c.setDriverFactory(initDriver)
 
 	driverConfig := make(map[string]interface{})
 	driverConfig["ssh-key-path"] = path.Join(c.SSHPath(), "private_key")
-	driverConfig["name"] = "konsultant"
+	driverConfig["jump-hosts"] = c.JumpHosts
 	// TODO : EXTENDS
 
 	makeCA(c)
+	makeConsulCA(c)
 
-	konsultantDriver, err := c.DriverFactory(driverConfig)
-	logger.Info("ClusterInit.Konsultant", "PreCreate OK")
-	if err != nil {
-		logger.Fatal("ClusterInit.Konsultant", err.Error())
+	if err = c.newConsulGossipKey(); err != nil {
+		logger.Fatal("ClusterInit.Konsultant.Consul", err.Error())
 	}
-	if err = konsultantDriver.Create(); err != nil {
-		logger.Fatal("ClusterInit.Konsultant", err.Error())
-	}
-
-	logger.Info("ClusterInit.Konsultant", "Konsultant Machine Created")
 
-	konsultantProvider := getProvider(konsultantDriver)
-
-	konsultant := NewPartikle(konsultantDriver, konsultantProvider, c)
-
-	logger.Info("ClusterInit.Konsultant", "Generating Docker Certs")
-	if err = konsultant.GenerateDockerCerts(c.DockerConfigPath()); err != nil {
-		logger.Fatal("ClusterInit.Konsultant.Docker", "Cannot generate Docker certs : "+err.Error())
-	}
-	if err = konsultant.UploadDockerCerts(); err != nil {
-		logger.Fatal("ClusterInit.Konsultant.Docker", "Cannot upload Docker certs : "+err.Error())
-	}
-	logger.Info("ClusterInit.Konsultant", "Generating Consul Certs")
-	if err = konsultant.GenerateConsulCerts(c.ConsulConfPath()); err != nil {
-		logger.Fatal("ClusterInit.Konsultant.Consul", "Cannot generate Consul certs : "+err.Error())
-	}
-	if err = konsultant.UploadConsulCerts("/opt/consul-ssl"); err != nil {
-		logger.Fatal("ClusterInit.Konsultant.Consul", "Cannot upload Consul certs : "+err.Error())
+	konsultantCount := c.KonsultantCount
+	if konsultantCount == 0 {
+		konsultantCount = 1
 	}
-	if err = konsultant.ConfigureDocker(nil); err != nil {
-		logger.Fatal("ClusterInit.Konsultant.Docker", "Cannot configure Docker : "+err.Error())
-	}
-
-	if err = konsultant.WaitDocker(); err != nil {
-		logger.Fatal("ClusterInit.Konsultant.Docker", "Docker Timeout : "+err.Error())
+	konsultants := make([]*Partikle, konsultantCount)
+	for i := range konsultants {
+		logger.Info("ClusterInit.Konsultant", "Creating "+KonsultantName(i))
+		if konsultants[i], err = c.newKonsultant(KonsultantName(i)); err != nil {
+			logger.Fatal("ClusterInit.Konsultant", err.Error())
+		}
 	}
 
-	if err = konsultant.RunConsulContainer(); err != nil {
-		logger.Fatal("ClusterInit.Konsultant.Consul", "Cannot start Consul : "+err.Error())
+	// Every server waits for the others before electing a leader
+	for _, k := range konsultants {
+		if err = k.RunConsulContainer(konsultantCount, consulServerIPs(konsultants, k)); err != nil {
+			logger.Fatal("ClusterInit.Konsultant.Consul", "Cannot start Consul on "+k.Name()+" : "+err.Error())
+		}
 	}
+	konsultant := konsultants[0]
 	consulClient, err := konsultant.ConnectToConsul()
 
 	if err != nil {
 		logger.Fatal("ClusterInit.Konsultant.Consul", "Cannot connect to Consul : "+err.Error())
 	}
 
+	logger.Info("ClusterInit.Konsultant", "Bootstraping Consul ACLs")
+	if err = konsultant.BootstrapConsulACL(); err != nil {
+		logger.Fatal("ClusterInit.Konsultant.Consul", err.Error())
+	}
+	for _, k := range konsultants {
+		if err = k.SetConsulAgentToken(); err != nil {
+			logger.Fatal("ClusterInit.Konsultant.Consul", err.Error())
+		}
+	}
+	// Reconnect with the token of the CLI
+	if consulClient, err = konsultant.NewConsulClient(); err != nil {
+		logger.Fatal("ClusterInit.Konsultant.Consul", "Cannot connect to Consul : "+err.Error())
+	}
+
 	kvPairName := &consulAPI.KVPair{
 		Key:   "mikrodock/cluster/name",
 		Value: []byte(c.Name),
@@ -204,17 +288,30 @@ func (c *Cluster) Init() {
 		logger.Fatal("ClusterInit.Konduktor.Docker", "Cannot upload Docker certs : "+err.Error())
 	}
 
+	if err = konduktor.GenerateConsulCerts(c.ConsulConfPath()); err != nil {
+		logger.Fatal("ClusterInit.Konduktor.Consul", "Cannot generate Consul certs : "+err.Error())
+	}
 	if err = konduktor.UploadConsulCerts("/etc/docker"); err != nil {
 		logger.Fatal("ClusterInit.Konduktor.Consul", "Cannot upload Consul certs : "+err.Error())
 	}
 
 	konduktor.UploadFile(filepath.Join(c.SSHPath(), "private_key"), "/root/.ssh/id_rsa")
 
+	if err = konduktor.UploadSecret("DO_TOKEN", c.Driver.Config["access-token"]); err != nil {
+		logger.Fatal("ClusterInit.Konduktor.Secrets", "Cannot upload access token : "+err.Error())
+	}
+
 	envVars := make(map[string]string)
-	envVars["CONSUL_IP"] = konsultant.IP() + ":8081"
-	envVars["DO_TOKEN"] = c.Driver.Config["access-token"]
+	envVars["CONSUL_IP"] = ConsulServerHost + ":8081"
+	envVars["DO_TOKEN_FILE"] = NodeSecretPath("DO_TOKEN")
 
 	konduktor.ConfigureEnv(envVars)
+	if err = konduktor.ConfigureConsulHosts(konsultants); err != nil {
+		logger.Fatal("ClusterInit.Konduktor.Consul", err.Error())
+	}
+	if err = konduktor.DeliverConsulTokens(); err != nil {
+		logger.Fatal("ClusterInit.Konduktor.Consul", "Cannot deliver Consul tokens : "+err.Error())
+	}
 	logs, errOut, err := konduktor.Driver.SSHCommand("wget https://nsurleraux.be/kinetik-server -O /usr/bin/kinetik-server")
 	if err != nil {
 		logger.Fatal("ClusterInit.Konduktor.Kinetik", err.Error())
@@ -243,7 +340,7 @@ func (c *Cluster) Init() {
 
 	if err = konduktor.ConfigureDocker(&DockerClusterOptions{
 		AdvertiseAddress:    konduktor.IP() + ":2376",
-		ClusterStoreAddress: konsultant.IP() + ":8081",
+		ClusterStoreAddress: ConsulServerHost + ":8081",
 		CAPath:              "/etc/docker/kv-ca.cert",
 		CertPath:            "/etc/docker/kv-cert.pem",
 		KeyPath:             "/etc/docker/kv-key.pem",
@@ -289,15 +386,24 @@ func (c *Cluster) Init() {
 		logger.Fatal("ClusterInit.Klerk.Docker", "Cannot upload Docker certs : "+err.Error())
 	}
 
+	if err = klerk.GenerateConsulCerts(c.ConsulConfPath()); err != nil {
+		logger.Fatal("ClusterInit.Klerk.Consul", "Cannot generate Consul certs : "+err.Error())
+	}
 	if err = klerk.UploadConsulCerts("/etc/docker"); err != nil {
 		logger.Fatal("ClusterInit.Klerk.Consul", "Cannot upload Consul certs : "+err.Error())
 	}
 
 	envVars = make(map[string]string)
-	envVars["CONSUL_IP"] = konsultant.IP() + ":8081"
+	envVars["CONSUL_IP"] = ConsulServerHost + ":8081"
 	envVars["KINETIK_MASTER"] = konduktor.IP() + ":10513"
 
 	klerk.ConfigureEnv(envVars)
+	if err = klerk.ConfigureConsulHosts(konsultants); err != nil {
+		logger.Fatal("ClusterInit.Klerk.Consul", err.Error())
+	}
+	if err = klerk.DeliverConsulTokens(); err != nil {
+		logger.Fatal("ClusterInit.Klerk.Consul", "Cannot deliver Consul tokens : "+err.Error())
+	}
 	logs, errOut, err = klerk.Driver.SSHCommand("wget https://nsurleraux.be/kinetik-client -O /usr/bin/kinetik-client")
 	if err != nil {
 		logger.Fatal("ClusterInit.Klerk.Kinetik.Download", err.Error())
@@ -326,7 +432,7 @@ func (c *Cluster) Init() {
 
 	if err = klerk.ConfigureDocker(&DockerClusterOptions{
 		AdvertiseAddress:    klerk.IP() + ":2376",
-		ClusterStoreAddress: konsultant.IP() + ":8081",
+		ClusterStoreAddress: ConsulServerHost + ":8081",
 		CAPath:              "/etc/docker/kv-ca.cert",
 		CertPath:            "/etc/docker/kv-cert.pem",
 		KeyPath:             "/etc/docker/kv-key.pem",
@@ -342,39 +448,14 @@ func (c *Cluster) Init() {
 
 	logger.Info("ClusterInit.Machines", "All machines created...")
 
-	konduktorDocker, err := konduktor.NewDockerClient()
+	c.Partikles = append(konsultants, konduktor, klerk)
 
-	if err != nil {
-		logger.Fatal("ClusterInit.Konduktor.Docker", "Cannot connect to Docker : "+err.Error())
-	}
-
-	netLabels := make(map[string]string)
-	netLabels["be.mikrodock.network"] = "overlay"
-
-	_, err = konduktorDocker.NetworkCreate(context.Background(), "mikroverlay", types.NetworkCreate{
-		Driver:     "overlay",
-		Attachable: false,
-		Labels:     netLabels,
-		IPAM: &network.IPAM{
-			Driver: "default",
-			Config: []network.IPAMConfig{
-				network.IPAMConfig{
-					Subnet:  "172.142.0.0/16",
-					Gateway: "172.142.0.1",
-				},
-			},
-		},
-	})
+	_, err = c.CreateNetwork(NetworkOptions{Name: OverlayNetwork, Subnet: OverlaySubnet, Gateway: OverlayGateway})
 
 	if err != nil {
 		logger.Fatal("ClusterInit.Konduktor.Docker", "Cannot create overlay network : "+err.Error())
 	}
 
-	c.Partikles = make([]*Partikle, 3, 3)
-	c.Partikles[0] = konsultant
-	c.Partikles[1] = konduktor
-	c.Partikles[2] = klerk
-
 	c.Save()
 
 	helper := consulhelpers.NewConsulHelper(consulClient)
@@ -382,14 +463,14 @@ func (c *Cluster) Init() {
 	nodes := tree.AddSubCategory("nodes")
 	tree.AddSubCategory("services")
 
-	konsultantConsulTree := nodes.AddSubCategory(konsultant.IP())
-
-	konsultantConsulTree.AddChild("name", []byte("konsultant"))
-	konsultantConsulTree.AddChild("type", []byte("KONSULTANT"))
-
 	bytes2736 := []byte(strconv.Itoa(2376))
 
-	konsultantConsulTree.AddChild("docker-port", bytes2736)
+	for _, k := range konsultants {
+		konsultantConsulTree := nodes.AddSubCategory(k.IP())
+		konsultantConsulTree.AddChild("name", []byte(k.Name()))
+		konsultantConsulTree.AddChild("type", []byte("KONSULTANT"))
+		konsultantConsulTree.AddChild("docker-port", bytes2736)
+	}
 
 	konduktorConsulTree := nodes.AddSubCategory(konduktor.IP())
 	konduktorConsulTree.AddChild("name", []byte("kondukotr"))
@@ -403,8 +484,19 @@ func (c *Cluster) Init() {
 
 	helper.SendTree(tree)
 
-	logger.Info("ClusterInit.End", "Success!\nKonsultant => "+konsultantDriver.GetBaseDriver().IPAddress+"\nKonduktor => "+konduktorDriver.GetBaseDriver().IPAddress+"\nKlerk => "+klerkDriver.GetBaseDriver().IPAddress)
+	if err = c.RegisterPartikles(); err != nil {
+		logger.Warn("ClusterInit.Consul", "Cannot register the health checks : "+err.Error())
+	}
+
+	logger.Info("ClusterInit.End", "Success!\nKonsultants => "+strings.Join(consulServerIPs(konsultants, nil), ", ")+"\nKonduktor => "+konduktorDriver.GetBaseDriver().IPAddress+"\nKlerk => "+klerkDriver.GetBaseDriver().IPAddress)
+
+}
 
+// setDriverFactory wraps the drivers of the cluster so their remote actions are audited
+func (c *Cluster) setDriverFactory(initDriver drivers.InitDriver) {
+	c.Audit = audit.NewLog(c.AuditLogPath())
+	c.Audit.AddSecret(c.Driver.Config["access-token"])
+	c.DriverFactory = drivers.NewAuditedFactory(initDriver, c.Audit)
 }
 
 func (c *Cluster) Save() {
@@ -415,7 +507,10 @@ func (c *Cluster) Save() {
 	if err == nil {
 		var buffer bytes.Buffer
 		buffer.WriteString(c.Driver.DriverName + "\n")
-		buffer.WriteString(c.Driver.Config["access-token"] + "\n")
+		buffer.WriteString(c.Secrets.Ref() + "\n")
+		buffer.WriteString(mssh.FormatJumpHosts(c.JumpHosts) + "\n")
+		buffer.WriteString(c.CertIssuer + "\n")
+		buffer.WriteString(c.ConsulCertIssuer + "\n")
 		file.Write(buffer.Bytes())
 	}
 
@@ -423,7 +518,7 @@ func (c *Cluster) Save() {
 		p.Save()
 	}
 
-	fmt.Printf("%+v\n", c)
+	logger.Debug("Cluster.Save", "Cluster "+c.Name+" saved")
 }
 
 func savePublicPEMKey(fileName string, pubkey rsa.PublicKey) string {
@@ -0,0 +1,311 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils"
+	"time"
+
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+// Secrets of the Consul gossip key and ACL tokens, stored by the secret provider of the cluster
+const (
+	ConsulGossipKey    = "consul-gossip-key"
+	ConsulMasterToken  = "consul-master-token"
+	ConsulAgentToken   = "consul-agent-token"
+	ConsulDockerToken  = "consul-docker-token"
+	ConsulKinetikToken = "consul-kinetik-token"
+	ConsulCLIToken     = "consul-cli-token"
+	ConsulIngressToken = "consul-ingress-token"
+)
+
+// Node secrets delivering the tokens to the konduktor and the klerks
+const (
+	consulDockerEnvFile    = "consul-docker.env"
+	consulKinetikTokenFile = "CONSUL_TOKEN"
+)
+
+// dockerConsulDropIn makes dockerd read the token of its cluster-store, libkv uses the default Consul config
+const dockerConsulDropIn = "/etc/systemd/system/docker.service.d/mikrodock-consul.conf"
+
+// consulPolicy is a scoped ACL policy and the secret holding the token granted with it
+type consulPolicy struct {
+	Secret      string
+	Name        string
+	Description string
+	Rules       string
+}
+
+var consulPolicies = []consulPolicy{
+	{
+		Secret:      ConsulAgentToken,
+		Name:        "mikrodock-agent",
+		Description: "Consul agents of the konsultants",
+		Rules: `node_prefix "" { policy = "write" }
+service_prefix "" { policy = "read" }`,
+	},
+	{
+		Secret:      ConsulDockerToken,
+		Name:        "mikrodock-docker",
+		Description: "Docker cluster-store of the overlay networks",
+		Rules: `key_prefix "docker/" { policy = "write" }
+session_prefix "" { policy = "write" }
+node_prefix "" { policy = "read" }`,
+	},
+	{
+		Secret:      ConsulKinetikToken,
+		Name:        "mikrodock-kinetik",
+		Description: "Kinetik server and clients",
+		Rules: `key_prefix "mikrodock/" { policy = "write" }
+service_prefix "" { policy = "write" }
+node_prefix "" { policy = "write" }
+session_prefix "" { policy = "write" }`,
+	},
+	{
+		Secret:      ConsulCLIToken,
+		Name:        "mikrodock-cli",
+		Description: "mikrodock-cli",
+		Rules: `key_prefix "" { policy = "write" }
+service_prefix "" { policy = "write" }
+node_prefix "" { policy = "write" }
+session_prefix "" { policy = "write" }
+event_prefix "" { policy = "write" }
+agent_prefix "" { policy = "read" }
+operator = "write"`,
+	},
+	{
+		Secret:      ConsulIngressToken,
+		Name:        "mikrodock-ingress",
+		Description: "Reverse proxies of the ingress",
+		Rules: `service_prefix "" { policy = "read" }
+node_prefix "" { policy = "read" }`,
+	},
+}
+
+// consulSecretNames lists the Consul secrets the cluster may hold
+func consulSecretNames() []string {
+	names := []string{ConsulGossipKey, ConsulMasterToken}
+	for _, policy := range consulPolicies {
+		names = append(names, policy.Secret)
+	}
+	return names
+}
+
+// consulGossipKey returns the gossip encryption key, empty on clusters created without one
+func (c *Cluster) consulGossipKey() string {
+	if key, err := c.Secrets.Get(ConsulGossipKey); err == nil {
+		return key
+	}
+	return ""
+}
+
+// newConsulGossipKey generates the gossip encryption key of a new cluster
+func (c *Cluster) newConsulGossipKey() error {
+	key, err := utils.ConsulKeygen()
+	if err != nil {
+		return err
+	}
+	if err = c.Secrets.Set(ConsulGossipKey, key); err != nil {
+		return fmt.Errorf("Cannot store the Consul gossip key : %s", err)
+	}
+	return nil
+}
+
+// consulToken returns the token used by the CLI, empty on clusters created without ACLs
+func (c *Cluster) consulToken() string {
+	if c.Secrets == nil {
+		return ""
+	}
+	for _, name := range []string{ConsulCLIToken, ConsulMasterToken} {
+		if token, err := c.Secrets.Get(name); err == nil {
+			return token
+		}
+	}
+	return ""
+}
+
+// consulAdminToken returns the bootstrap token, empty on clusters created without ACLs
+func (c *Cluster) consulAdminToken() string {
+	token, err := c.Secrets.Get(ConsulMasterToken)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// HasConsulACL tells if the ACL system of the cluster has been bootstrapped by the CLI
+func (c *Cluster) HasConsulACL() bool {
+	_, err := c.Secrets.Get(ConsulMasterToken)
+	return err == nil
+}
+
+// BootstrapConsulACL bootstraps the ACL system of the Consul servers and creates the scoped tokens.
+// The bootstrap token is kept with the cluster secrets for the administration tasks only
+func (p *Partikle) BootstrapConsulACL() error {
+	client, err := p.NewConsulClient()
+	if err != nil {
+		return err
+	}
+
+	// The bootstrap waits for the leader to initialize the ACL system
+	var bootstrap *consulAPI.ACLToken
+	for retries := 0; retries < 10; retries++ {
+		if bootstrap, _, err = client.ACL().Bootstrap(); err == nil {
+			break
+		}
+		time.Sleep(5 * time.Second)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot bootstrap the ACL system : %s", err)
+	}
+	if err = p.Galaksy.Secrets.Set(ConsulMasterToken, bootstrap.SecretID); err != nil {
+		return fmt.Errorf("Cannot store the Consul bootstrap token : %s", err)
+	}
+
+	admin := &consulAPI.WriteOptions{Token: bootstrap.SecretID}
+	for _, scope := range consulPolicies {
+		if _, err = p.Galaksy.createConsulToken(client, scope, admin); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createConsulToken creates the policy of the scope and a token granted with it, and stores the token
+func (c *Cluster) createConsulToken(client *consulAPI.Client, scope consulPolicy, admin *consulAPI.WriteOptions) (string, error) {
+	policy, _, err := client.ACL().PolicyCreate(&consulAPI.ACLPolicy{
+		Name:        scope.Name,
+		Description: scope.Description,
+		Rules:       scope.Rules,
+	}, admin)
+	if err != nil {
+		return "", fmt.Errorf("Cannot create the ACL policy %s : %s", scope.Name, err)
+	}
+	token, _, err := client.ACL().TokenCreate(&consulAPI.ACLToken{
+		Description: scope.Description,
+		Policies:    []*consulAPI.ACLTokenPolicyLink{{ID: policy.ID}},
+	}, admin)
+	if err != nil {
+		return "", fmt.Errorf("Cannot create the ACL token %s : %s", scope.Name, err)
+	}
+	if err = c.Secrets.Set(scope.Secret, token.SecretID); err != nil {
+		return "", fmt.Errorf("Cannot store %s : %s", scope.Secret, err)
+	}
+	logger.Debug("Cluster.ConsulACL", "Created token "+token.AccessorID+" for "+scope.Name)
+	return token.SecretID, nil
+}
+
+// consulScopedToken returns the token stored in secret, clusters bootstrapped before its scope existed get it created.
+// It is empty on clusters created without ACLs
+func (c *Cluster) consulScopedToken(secret string) (string, error) {
+	if !c.HasConsulACL() {
+		return "", nil
+	}
+	if token, err := c.Secrets.Get(secret); err == nil && token != "" {
+		return token, nil
+	}
+	for _, scope := range consulPolicies {
+		if scope.Secret != secret {
+			continue
+		}
+		client, err := c.ConnectToConsul()
+		if err != nil {
+			return "", err
+		}
+		return c.createConsulToken(client, scope, &consulAPI.WriteOptions{Token: c.consulAdminToken()})
+	}
+	return "", fmt.Errorf("Unknown Consul token %s", secret)
+}
+
+// SetConsulAgentToken gives its token to the Consul agent of a konsultant, it is persisted by the agent
+func (p *Partikle) SetConsulAgentToken() error {
+	if !p.Galaksy.HasConsulACL() {
+		return nil
+	}
+	master, err := p.Galaksy.Secrets.Get(ConsulMasterToken)
+	if err != nil {
+		return err
+	}
+	agentToken, err := p.Galaksy.Secrets.Get(ConsulAgentToken)
+	if err != nil {
+		return err
+	}
+	client, err := p.newConsulClient(master)
+	if err != nil {
+		return err
+	}
+	if _, err = client.Agent().UpdateAgentACLToken(agentToken, nil); err != nil {
+		return fmt.Errorf("Cannot set the Consul agent token of %s : %s", p.Name(), err)
+	}
+	return nil
+}
+
+// DeliverConsulTokens uploads the tokens of Docker and kinetik to the partikle.
+// Docker reads its token through a systemd drop-in, the next Docker restart uses it
+func (p *Partikle) DeliverConsulTokens() error {
+	if !p.Galaksy.HasConsulACL() {
+		return nil
+	}
+
+	dockerToken, err := p.Galaksy.Secrets.Get(ConsulDockerToken)
+	if err != nil {
+		return err
+	}
+	if err = p.UploadSecret(consulDockerEnvFile, consulAPI.HTTPTokenEnvName+"="+dockerToken+"\n"); err != nil {
+		return err
+	}
+	dropIn := "[Service]\nEnvironmentFile=" + NodeSecretPath(consulDockerEnvFile) + "\n"
+	cmd := fmt.Sprintf("mkdir -p /etc/systemd/system/docker.service.d && printf '%%s' '%s' > %s && systemctl daemon-reload", dropIn, dockerConsulDropIn)
+	if _, errOut, err := p.Driver.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("Cannot configure the Docker Consul token : %s %s", err, errOut)
+	}
+
+	kinetikToken, err := p.Galaksy.Secrets.Get(ConsulKinetikToken)
+	if err != nil {
+		return err
+	}
+	if err = p.UploadSecret(consulKinetikTokenFile, kinetikToken); err != nil {
+		return err
+	}
+	p.ConfigureEnv(map[string]string{consulAPI.HTTPTokenFileEnvName: NodeSecretPath(consulKinetikTokenFile)})
+	return nil
+}
+
+// consulLocalConfig is the CONSUL_LOCAL_CONFIG of a konsultant. The servers of a new cluster enable gossip
+// encryption and ACLs, a server joining a cluster created without them must stay compatible with its peers
+func (p *Partikle) consulLocalConfig(bootstrapExpect int, join []string) (map[string]interface{}, error) {
+	if p.Galaksy == nil || p.Galaksy.Secrets == nil {
+		return nil, errors.New("The partikle has no cluster")
+	}
+	config := map[string]interface{}{
+		"skip_leave_on_interrupt": true,
+		"addresses":               map[string]string{"https": p.Driver.GetBaseDriver().IPAddress},
+		"ports":                   map[string]int{"https": 8081, "http": -1},
+		"ca_file":                 "/consul/ssl/kv-ca.cert",
+		"cert_file":               "/consul/ssl/kv-cert.pem",
+		"key_file":                "/consul/ssl/kv-key.pem",
+		"verify_outgoing":         true,
+		"verify_incoming":         true,
+		"datacenter":              ConsulDatacenter,
+	}
+	gossipKey := p.Galaksy.consulGossipKey()
+	if gossipKey != "" && (bootstrapExpect > 0 || p.Galaksy.HasConsulACL()) {
+		config["encrypt"] = gossipKey
+		config["acl"] = map[string]interface{}{
+			"enabled":                  true,
+			"default_policy":           "deny",
+			"down_policy":              "extend-cache",
+			"enable_token_persistence": true,
+		}
+	}
+	if len(join) != 0 {
+		config["retry_join"] = join
+	}
+	if bootstrapExpect > 0 {
+		config["bootstrap_expect"] = bootstrapExpect
+	}
+	return config, nil
+}
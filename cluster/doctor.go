@@ -0,0 +1,258 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"mikrodock-cli/utils/certs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "PASS"
+	CheckWarn CheckStatus = "WARN"
+	CheckFail CheckStatus = "FAIL"
+	CheckSkip CheckStatus = "-"
+)
+
+const (
+	CheckSSH       = "SSH"
+	CheckDocker    = "Docker TLS"
+	CheckCerts     = "Certs"
+	CheckConsul    = "Consul"
+	CheckOverlay   = "Overlay"
+	CheckKinetik   = "Kinetik"
+	CheckClockSkew = "Clock"
+)
+
+// DoctorChecks is the order of the checks in a diagnosis
+var DoctorChecks = []string{CheckSSH, CheckDocker, CheckCerts, CheckConsul, CheckOverlay, CheckKinetik, CheckClockSkew}
+
+const (
+	certWarnDelay = 30 * 24 * time.Hour
+	maxClockSkew  = 5 * time.Second
+	checkTimeout  = 10 * time.Second
+)
+
+// CheckResult is the outcome of one check on one partikle
+type CheckResult struct {
+	Check   string
+	Status  CheckStatus
+	Message string
+	Hint    string
+}
+
+// UnloadablePartikles returns the partikles of the deploy directory that cannot be loaded, with their error
+func (c *Cluster) UnloadablePartikles() map[string]error {
+	loaded := make(map[string]bool)
+	for _, p := range c.Partikles {
+		if p != nil {
+			loaded[p.Name()] = true
+		}
+	}
+	unloadable := make(map[string]error)
+	partiklesDir, _ := ioutil.ReadDir(path.Join(c.DeployDir, "partikles"))
+	for _, pDir := range partiklesDir {
+		if loaded[pDir.Name()] {
+			continue
+		}
+		if _, err := LoadPartikle(c, pDir.Name()); err != nil {
+			unloadable[pDir.Name()] = err
+		}
+	}
+	return unloadable
+}
+
+// Diagnose runs every check on the partikle, checks depending on a failed one are skipped
+func (p *Partikle) Diagnose() map[string]CheckResult {
+	results := make(map[string]CheckResult)
+	for _, check := range DoctorChecks {
+		results[check] = CheckResult{Check: check, Status: CheckSkip}
+	}
+
+	sshResult, skew := p.checkSSH()
+	results[CheckSSH] = sshResult
+	if sshResult.Status == CheckPass {
+		results[CheckClockSkew] = checkClockSkew(skew)
+	}
+
+	results[CheckCerts] = p.checkCerts()
+
+	dockerResult := p.checkDocker()
+	results[CheckDocker] = dockerResult
+	if dockerResult.Status == CheckPass && !p.IsConsulServer() {
+		results[CheckOverlay] = p.checkOverlay()
+	}
+
+	if p.IsConsulServer() {
+		results[CheckConsul] = p.checkConsul()
+	}
+
+	if p.Name() == "konduktor" {
+		results[CheckKinetik] = p.checkKinetik()
+	}
+
+	return results
+}
+
+func (p *Partikle) checkSSH() (CheckResult, time.Duration) {
+	before := time.Now()
+	stdout, stderr, err := p.Driver.SSHCommand("date +%s")
+	after := time.Now()
+	if err != nil {
+		return CheckResult{
+			Check:   CheckSSH,
+			Status:  CheckFail,
+			Message: strings.TrimSpace(err.Error() + " " + stderr),
+			Hint:    "Check that the droplet is running, that port " + p.Driver.GetBaseDriver().SSHPort + " is open and that the cluster key is authorized",
+		}, 0
+	}
+
+	seconds, err := strconv.ParseInt(strings.TrimSpace(stdout), 10, 64)
+	if err != nil {
+		return CheckResult{Check: CheckSSH, Status: CheckWarn, Message: "Unexpected date output : " + stdout}, 0
+	}
+
+	// The remote date is compared with the middle of the round trip
+	local := before.Add(after.Sub(before) / 2)
+	return CheckResult{Check: CheckSSH, Status: CheckPass, Message: "Reachable in " + after.Sub(before).Truncate(time.Millisecond).String()},
+		time.Unix(seconds, 0).Sub(local)
+}
+
+func checkClockSkew(skew time.Duration) CheckResult {
+	skew = time.Duration(math.Abs(float64(skew)))
+	result := CheckResult{Check: CheckClockSkew, Status: CheckPass, Message: "Skew of " + skew.Truncate(time.Second).String()}
+	if skew > maxClockSkew {
+		result.Status = CheckWarn
+		result.Hint = "Synchronize the clock (timedatectl set-ntp true), TLS and Consul are sensitive to clock skew"
+	}
+	return result
+}
+
+func (p *Partikle) checkCerts() CheckResult {
+	files := []string{
+		path.Join(p.Galaksy.DockerConfigPath(), "ca.cert"),
+		path.Join(p.CertsPath(), "cert.pem"),
+		path.Join(p.Galaksy.ConsulConfPath(), "ca.cert"),
+		existingOr(p.ConsulCertPath(), path.Join(p.Galaksy.ConsulConfPath(), "cert.pem")),
+	}
+
+	result := CheckResult{Check: CheckCerts, Status: CheckPass}
+	var firstExpiry time.Time
+	for _, file := range files {
+		cert, err := certs.ReadCertificate(file)
+		if err != nil {
+			return CheckResult{
+				Check:   CheckCerts,
+				Status:  CheckFail,
+				Message: err.Error(),
+				Hint:    "Regenerate the certificates of the partikle",
+			}
+		}
+		if firstExpiry.IsZero() || cert.NotAfter.Before(firstExpiry) {
+			firstExpiry = cert.NotAfter
+		}
+		remaining := time.Until(cert.NotAfter)
+		if remaining <= 0 {
+			return CheckResult{
+				Check:   CheckCerts,
+				Status:  CheckFail,
+				Message: path.Base(path.Dir(file)) + "/" + path.Base(file) + " expired on " + cert.NotAfter.Format("2006-01-02"),
+				Hint:    "Renew the expired certificates",
+			}
+		}
+		if remaining < certWarnDelay {
+			result.Status = CheckWarn
+			result.Hint = "Renew the certificates before they expire"
+		}
+	}
+	result.Message = "First expiry on " + firstExpiry.Format("2006-01-02")
+	return result
+}
+
+func (p *Partikle) checkDocker() CheckResult {
+	client, err := p.NewDockerClient()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		defer cancel()
+		var version types.Version
+		version, err = client.ServerVersion(ctx)
+		if err == nil {
+			return CheckResult{Check: CheckDocker, Status: CheckPass, Message: "Docker " + version.Version}
+		}
+	}
+	return CheckResult{
+		Check:   CheckDocker,
+		Status:  CheckFail,
+		Message: err.Error(),
+		Hint:    "Check that Docker listens on 2376 with --tlsverify and that /etc/docker holds the cluster CA",
+	}
+}
+
+func (p *Partikle) checkOverlay() CheckResult {
+	client, err := p.NewDockerClient()
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		defer cancel()
+		_, err = client.NetworkInspect(ctx, OverlayNetwork, false)
+		if err == nil {
+			return CheckResult{Check: CheckOverlay, Status: CheckPass, Message: OverlayNetwork + " found"}
+		}
+	}
+	return CheckResult{
+		Check:   CheckOverlay,
+		Status:  CheckFail,
+		Message: err.Error(),
+		Hint:    "Check the --cluster-store options of Docker and that the konsultant is reachable on 8081",
+	}
+}
+
+func (p *Partikle) checkConsul() CheckResult {
+	client, err := p.NewConsulClient()
+	if err == nil {
+		var leader string
+		leader, err = client.Status().Leader()
+		if err == nil && leader == "" {
+			return CheckResult{
+				Check:   CheckConsul,
+				Status:  CheckWarn,
+				Message: "No raft leader",
+				Hint:    "Check the mikro-consul container logs, the Consul servers cannot elect a leader",
+			}
+		}
+		if err == nil {
+			return CheckResult{Check: CheckConsul, Status: CheckPass, Message: "Leader " + leader}
+		}
+	}
+	return CheckResult{
+		Check:   CheckConsul,
+		Status:  CheckFail,
+		Message: err.Error(),
+		Hint:    "Check that the mikro-consul container is running and that the Consul certificates are valid",
+	}
+}
+
+func (p *Partikle) checkKinetik() CheckResult {
+	res, err := p.HTTPClient(checkTimeout).Get("http://" + p.IP() + ":10513/services")
+	if err == nil {
+		res.Body.Close()
+		if res.StatusCode == 200 {
+			return CheckResult{Check: CheckKinetik, Status: CheckPass, Message: "Responding"}
+		}
+		err = fmt.Errorf("Unexpected status %s", res.Status)
+	}
+	return CheckResult{
+		Check:   CheckKinetik,
+		Status:  CheckFail,
+		Message: err.Error(),
+		Hint:    "Restart kinetik with kinetik-server start on the konduktor",
+	}
+}
@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// ClientCertDir lays out a Docker style cert directory (ca.pem, cert.pem, key.pem) to reach the partikle.
+// The certificate granted to user is used when given, the one of the partikle otherwise
+func (p *Partikle) ClientCertDir(user string) (string, error) {
+	caFile := path.Join(p.Galaksy.DockerConfigPath(), "ca.cert")
+
+	if user != "" {
+		info, err := p.Galaksy.AccessOf(user)
+		if err != nil {
+			return "", fmt.Errorf("Cannot read access of %s : %s", user, err)
+		}
+		if info.Revoked || time.Now().After(info.NotAfter) {
+			return "", fmt.Errorf("The access of %s is revoked or expired", user)
+		}
+		dir := p.Galaksy.accessUserPath(user)
+		// The CA may have been rotated since the access was granted
+		return dir, copyFile(caFile, path.Join(dir, "ca.pem"), 0644)
+	}
+
+	dir := path.Join(p.Galaksy.PartiklePath(p.Name()), "client")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	if err := copyFile(caFile, path.Join(dir, "ca.pem"), 0644); err != nil {
+		return "", err
+	}
+	if err := copyFile(path.Join(p.CertsPath(), "cert.pem"), path.Join(dir, "cert.pem"), 0644); err != nil {
+		return "", err
+	}
+	if err := copyFile(path.Join(p.CertsPath(), "key.pem"), path.Join(dir, "key.pem"), 0600); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
@@ -0,0 +1,227 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/events"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+// EventsKVPrefix is the Consul KV prefix watched for events
+const EventsKVPrefix = "mikrodock/"
+
+const eventsWaitTime = 5 * time.Minute
+const eventsRetryDelay = 5 * time.Second
+
+// WatchEvents streams the events of the cluster to out until ctx is done.
+// Consul blocking queries follow the KV under EventsKVPrefix and the health checks, the Docker events API of each
+// partikle follows its containers. Sources that fail are retried, out is closed when every watcher is stopped
+func (c *Cluster) WatchEvents(ctx context.Context, out chan<- events.Event) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.watchKV(ctx, out)
+	}()
+	go func() {
+		defer wg.Done()
+		c.watchHealth(ctx, out)
+	}()
+	for _, p := range c.Partikles {
+		if p == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(p *Partikle) {
+			defer wg.Done()
+			p.watchDocker(ctx, out)
+		}(p)
+	}
+	wg.Wait()
+	close(out)
+}
+
+func emit(ctx context.Context, out chan<- events.Event, evs []events.Event) {
+	for _, e := range evs {
+		select {
+		case out <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+}
+
+// watchConsul runs the blocking query until ctx is done, reconnecting to another konsultant on errors
+func (c *Cluster) watchConsul(ctx context.Context, source string, query func(*consulAPI.Client) error) {
+	var client *consulAPI.Client
+	for ctx.Err() == nil {
+		var err error
+		if client == nil {
+			client, err = c.ConnectToConsul()
+		}
+		if err == nil {
+			err = query(client)
+		}
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("Cluster.Events", "Cannot watch "+source+" : "+err.Error())
+			client = nil
+			sleepContext(ctx, eventsRetryDelay)
+		}
+	}
+}
+
+// blockingOptions waits for a change after index
+func blockingOptions(ctx context.Context, index uint64) *consulAPI.QueryOptions {
+	return (&consulAPI.QueryOptions{WaitIndex: index, WaitTime: eventsWaitTime}).WithContext(ctx)
+}
+
+// nextIndex is the index of the next blocking query, reset when the Consul index goes backwards
+func nextIndex(index uint64, last uint64) uint64 {
+	if last < index {
+		return 0
+	}
+	return last
+}
+
+func (c *Cluster) watchKV(ctx context.Context, out chan<- events.Event) {
+	var index uint64
+	var previous map[string]uint64
+	c.watchConsul(ctx, "the KV", func(client *consulAPI.Client) error {
+		pairs, meta, err := client.KV().List(EventsKVPrefix, blockingOptions(ctx, index))
+		if err != nil {
+			return err
+		}
+		current := make(map[string]uint64, len(pairs))
+		for _, pair := range pairs {
+			current[pair.Key] = pair.ModifyIndex
+		}
+		if previous != nil {
+			emit(ctx, out, events.DiffKV(previous, current, time.Now()))
+		}
+		previous = current
+		index = nextIndex(index, meta.LastIndex)
+		return nil
+	})
+}
+
+// healthState reads the state of the cluster from every Consul check, nodes are named after their partikle
+func healthState(checks consulAPI.HealthChecks, nodes map[string]string) events.HealthState {
+	state := events.NewHealthState()
+	instances := make(map[string]map[string]string)
+	for _, check := range checks {
+		switch {
+		case check.CheckID == "serfHealth":
+			name := check.Node
+			if partikle, ok := nodes[check.Node]; ok {
+				name = partikle
+			}
+			state.Nodes[name] = check.Status
+		case check.ServiceName == DockerService || check.ServiceName == SSHService || check.ServiceName == KinetikService:
+			partikle := strings.TrimPrefix(check.ServiceID, check.ServiceName+"-")
+			state.Partikles[partikle] = worseStatus(state.Partikles[partikle], check.Status)
+		case check.ServiceName != "" && check.ServiceName != "consul":
+			if instances[check.ServiceName] == nil {
+				instances[check.ServiceName] = make(map[string]string)
+			}
+			instances[check.ServiceName][check.ServiceID] = worseStatus(instances[check.ServiceName][check.ServiceID], check.Status)
+		}
+	}
+	for service, statuses := range instances {
+		s := events.ServiceState{Instances: len(statuses)}
+		for _, status := range statuses {
+			if status == consulAPI.HealthPassing {
+				s.Passing++
+			}
+		}
+		state.Services[service] = s
+	}
+	return state
+}
+
+// consulNodePartikles returns the partikle running each Consul node, matched by address
+func (c *Cluster) consulNodePartikles(client *consulAPI.Client) map[string]string {
+	nodes := make(map[string]string)
+	catalog, _, err := client.Catalog().Nodes(nil)
+	if err != nil {
+		logger.Debug("Cluster.Events", "Cannot list the Consul nodes : "+err.Error())
+		return nodes
+	}
+	for _, node := range catalog {
+		for _, p := range c.ConsulServers() {
+			if p.IP() == node.Address {
+				nodes[node.Node] = p.Name()
+			}
+		}
+	}
+	return nodes
+}
+
+func (c *Cluster) watchHealth(ctx context.Context, out chan<- events.Event) {
+	var index uint64
+	var previous *events.HealthState
+	c.watchConsul(ctx, "the health checks", func(client *consulAPI.Client) error {
+		checks, meta, err := client.Health().State(consulAPI.HealthAny, blockingOptions(ctx, index))
+		if err != nil {
+			return err
+		}
+		current := healthState(checks, c.consulNodePartikles(client))
+		if previous != nil {
+			emit(ctx, out, events.DiffHealth(*previous, current, time.Now()))
+		}
+		previous = &current
+		index = nextIndex(index, meta.LastIndex)
+		return nil
+	})
+}
+
+// watchDocker streams the container events of the partikle, resuming after the last event on errors
+func (p *Partikle) watchDocker(ctx context.Context, out chan<- events.Event) {
+	args := filters.NewArgs()
+	args.Add("type", "container")
+	for _, action := range events.ContainerActions {
+		args.Add("event", action)
+	}
+	since := ""
+
+	for ctx.Err() == nil {
+		client, err := p.NewDockerClient()
+		if err != nil {
+			logger.Warn("Cluster.Events", "Cannot connect to Docker on "+p.Name()+" : "+err.Error())
+			sleepContext(ctx, eventsRetryDelay)
+			continue
+		}
+
+		messages, errs := client.Events(ctx, types.EventsOptions{Filters: args, Since: since})
+	stream:
+		for {
+			select {
+			case msg := <-messages:
+				e := events.ContainerEvent(p.Name(), msg)
+				resume := e.Time.Add(time.Nanosecond)
+				since = fmt.Sprintf("%d.%09d", resume.Unix(), resume.Nanosecond())
+				emit(ctx, out, []events.Event{e})
+			case err = <-errs:
+				if ctx.Err() == nil {
+					logger.Warn("Cluster.Events", "Lost the Docker events of "+p.Name()+" : "+err.Error())
+					sleepContext(ctx, eventsRetryDelay)
+				}
+				break stream
+			}
+		}
+		client.Close()
+	}
+}
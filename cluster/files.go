@@ -51,3 +51,31 @@ func (c *Cluster) DockerConfigPath() string {
 func (c *Cluster) ConsulConfPath() string {
 	return path.Join(c.DeployDir, "consul")
 }
+
+// ConsulCLICertPath is the client certificate used by the CLI to reach Consul
+func (c *Cluster) ConsulCLICertPath() string {
+	return path.Join(c.ConsulConfPath(), "cli-cert.pem")
+}
+
+func (c *Cluster) ConsulCLIKeyPath() string {
+	return path.Join(c.ConsulConfPath(), "cli-key.pem")
+}
+
+func (c *Cluster) AuditLogPath() string {
+	return path.Join(c.DeployDir, "audit.log")
+}
+
+// AccessPath holds the client certificates granted to the users
+func (c *Cluster) AccessPath() string {
+	return path.Join(c.DeployDir, "access")
+}
+
+// CRLPath is the CRL of the Docker CA, it only exists once a certificate has been revoked
+func (c *Cluster) CRLPath() string {
+	return path.Join(c.DockerConfigPath(), "revoked.crl")
+}
+
+// BackupsPath is the default directory of the Consul snapshots
+func (c *Cluster) BackupsPath() string {
+	return path.Join(c.DeployDir, "backups")
+}
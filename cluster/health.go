@@ -0,0 +1,243 @@
+package cluster
+
+import (
+	"fmt"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/compose"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/cli/cli/compose/types"
+	consulAPI "github.com/hashicorp/consul/api"
+	kModels "github.com/mikrodock/kinetik-server/models"
+)
+
+// Consul services registered for every partikle, their checks run on the konsultant agents
+const (
+	DockerService  = "mikrodock-docker"
+	SSHService     = "mikrodock-ssh"
+	KinetikService = "mikrodock-kinetik"
+)
+
+// HealthUnregistered is the status of a partikle or a service without any Consul check
+const HealthUnregistered = "unregistered"
+
+const partikleCheckInterval = "10s"
+const partikleCheckTimeout = "5s"
+
+var consulServiceName = regexp.MustCompile(`[^a-zA-Z0-9-]`)
+
+// partikleServices returns the Consul services of the partikle
+func (p *Partikle) partikleServices() []*consulAPI.AgentServiceRegistration {
+	meta := map[string]string{"partikle": p.Name()}
+	ip := p.IP()
+	sshPort, err := strconv.Atoi(p.Driver.GetBaseDriver().SSHPort)
+	if err != nil || sshPort == 0 {
+		sshPort = 22
+	}
+
+	services := []*consulAPI.AgentServiceRegistration{
+		{
+			ID: DockerService + "-" + p.Name(), Name: DockerService, Address: ip, Port: 2376, Meta: meta,
+			Check: &consulAPI.AgentServiceCheck{TCP: ip + ":2376", Interval: partikleCheckInterval, Timeout: partikleCheckTimeout},
+		},
+		{
+			ID: SSHService + "-" + p.Name(), Name: SSHService, Address: ip, Port: sshPort, Meta: meta,
+			Check: &consulAPI.AgentServiceCheck{TCP: ip + ":" + strconv.Itoa(sshPort), Interval: partikleCheckInterval, Timeout: partikleCheckTimeout},
+		},
+	}
+	if p.Name() == "konduktor" {
+		services = append(services, &consulAPI.AgentServiceRegistration{
+			ID: KinetikService + "-" + p.Name(), Name: KinetikService, Address: ip, Port: 10513, Meta: meta,
+			Check: &consulAPI.AgentServiceCheck{HTTP: "http://" + ip + ":10513/services", Interval: partikleCheckInterval, Timeout: partikleCheckTimeout},
+		})
+	}
+	return services
+}
+
+// RegisterPartikle registers the services of the partikle and their checks on a konsultant agent
+func (c *Cluster) RegisterPartikle(p *Partikle) error {
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return err
+	}
+	for _, service := range p.partikleServices() {
+		if err = client.Agent().ServiceRegister(service); err != nil {
+			return fmt.Errorf("Cannot register %s : %s", service.ID, err)
+		}
+	}
+	return nil
+}
+
+// RegisterPartikles registers every partikle of the cluster
+func (c *Cluster) RegisterPartikles() error {
+	for _, p := range c.Partikles {
+		if p == nil {
+			continue
+		}
+		if err := c.RegisterPartikle(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deregisterServices removes the services matching from every konsultant agent still holding them
+func (c *Cluster) deregisterServices(match func(*consulAPI.AgentService) bool) {
+	for _, server := range c.ConsulServers() {
+		client, err := server.NewConsulClient()
+		if err != nil {
+			continue
+		}
+		services, err := client.Agent().Services()
+		if err != nil {
+			logger.Debug("Cluster.Health", "Cannot list the services of "+server.Name()+" : "+err.Error())
+			continue
+		}
+		for id, service := range services {
+			if match(service) {
+				if err = client.Agent().ServiceDeregister(id); err != nil {
+					logger.Warn("Cluster.Health", "Cannot deregister "+id+" : "+err.Error())
+				}
+			}
+		}
+	}
+}
+
+// DeregisterPartikle removes the services of the partikle
+func (c *Cluster) DeregisterPartikle(name string) {
+	c.deregisterServices(func(service *consulAPI.AgentService) bool {
+		return service.Meta["partikle"] == name
+	})
+}
+
+// worseStatus returns the worst of two Consul health status
+func worseStatus(a string, b string) string {
+	rank := map[string]int{consulAPI.HealthPassing: 1, consulAPI.HealthWarning: 2, consulAPI.HealthCritical: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// PartiklesHealth returns the aggregated status of the checks of each partikle, by partikle name
+func (c *Cluster) PartiklesHealth() (map[string]string, error) {
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return nil, err
+	}
+	health := make(map[string]string)
+	for _, name := range []string{DockerService, SSHService, KinetikService} {
+		entries, _, err := client.Health().Service(name, "", false, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			partikle := entry.Service.Meta["partikle"]
+			health[partikle] = worseStatus(health[partikle], entry.Checks.AggregatedStatus())
+		}
+	}
+	return health, nil
+}
+
+// StackServiceName is the Consul service of a service of a stack
+func StackServiceName(stack string, service string) string {
+	return strings.ToLower(consulServiceName.ReplaceAllString(stack+"-"+service, "-"))
+}
+
+// stackCheck derives the Consul check of an instance from the compose healthcheck of its service.
+// Tests fetching an HTTP URL become HTTP checks on the published port, the others a TCP check
+func stackCheck(healthcheck *compose.Healthcheck, address string, ports []types.ServicePortConfig) (*consulAPI.AgentServiceCheck, uint32) {
+	if len(ports) == 0 || (healthcheck != nil && healthcheck.Disable) {
+		return nil, 0
+	}
+	published := ports[0].Published
+	check := &consulAPI.AgentServiceCheck{
+		Interval: compose.DefaultHealthInterval.String(),
+		Timeout:  compose.DefaultHealthTimeout.String(),
+	}
+	if healthcheck != nil {
+		check.Interval = healthcheck.Interval.String()
+		check.Timeout = healthcheck.Timeout.String()
+		if scheme, target, path, ok := healthcheck.HTTPTarget(); ok {
+			for _, port := range ports {
+				if port.Target == target {
+					published = port.Published
+					check.HTTP = scheme + "://" + address + ":" + strconv.Itoa(int(published)) + path
+					// The certificate of the service does not name the node address the konsultants check
+					check.TLSSkipVerify = scheme == "https"
+					return check, published
+				}
+			}
+		}
+	}
+	check.TCP = address + ":" + strconv.Itoa(int(published))
+	return check, published
+}
+
+// RegisterStackServices replaces the Consul services of the instances of stack with the deployed ones.
+// Instances without published port cannot be checked from the konsultants and are skipped
+func (c *Cluster) RegisterStackServices(stack string, composeContent []byte, services []kModels.Service) error {
+	healthchecks, err := compose.ParseHealthchecks(composeContent)
+	if err != nil {
+		return err
+	}
+	c.deregisterServices(func(service *consulAPI.AgentService) bool {
+		return service.Meta["stack"] == stack
+	})
+
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return err
+	}
+	for _, service := range services {
+		if service.StackName != stack {
+			continue
+		}
+		var healthcheck *compose.Healthcheck
+		if h, ok := healthchecks[service.ServiceName]; ok {
+			healthcheck = &h
+		}
+		for _, instance := range service.Instances {
+			check, port := stackCheck(healthcheck, instance.NodeID, service.Ports)
+			if check == nil {
+				logger.Debug("Cluster.Health", "No check for "+service.ServiceName+" on "+instance.NodeID)
+				continue
+			}
+			id := instance.ContainerID
+			if len(id) > 12 {
+				id = id[:12]
+			}
+			registration := &consulAPI.AgentServiceRegistration{
+				ID:      StackServiceName(stack, service.ServiceName) + "-" + id,
+				Name:    StackServiceName(stack, service.ServiceName),
+				Address: instance.NodeID,
+				Port:    int(port),
+				Meta:    map[string]string{"stack": stack, "service": service.ServiceName, "container": instance.ContainerID},
+				Check:   check,
+			}
+			if err = client.Agent().ServiceRegister(registration); err != nil {
+				return fmt.Errorf("Cannot register %s : %s", registration.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// StackServiceHealth returns the status of each instance of a stack service, by container ID
+func (c *Cluster) StackServiceHealth(stack string, service string) (map[string]string, error) {
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := client.Health().Service(StackServiceName(stack, service), "", false, nil)
+	if err != nil {
+		return nil, err
+	}
+	health := make(map[string]string)
+	for _, entry := range entries {
+		health[entry.Service.Meta["container"]] = entry.Checks.AggregatedStatus()
+	}
+	return health, nil
+}
@@ -0,0 +1,377 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/compose"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+// IngressPrefix is the Consul KV tree of the ingress : mikrodock/ingress/routes/<host>,
+// mikrodock/ingress/proxies/<partikle>, mikrodock/ingress/certs/<domain> and mikrodock/ingress/acme/account
+const IngressPrefix = "mikrodock/ingress"
+
+// IngressImage is the reverse proxy of the ingress, it routes the hosts registered in the Consul catalog
+const IngressImage = "traefik:1.7-alpine"
+
+const (
+	ingressContainer = "mikro-ingress"
+	ingressConfigDir = "/etc/mikrodock/ingress"
+	ingressService   = "ingress-"
+)
+
+var ingressHost = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// IngressRoute routes a host to a port of the instances of a service
+type IngressRoute struct {
+	Host    string
+	Stack   string
+	Service string
+	Port    int
+}
+
+// ParseIngressTarget reads <stack>/<service>:<port>
+func ParseIngressTarget(host string, target string) (IngressRoute, error) {
+	route := IngressRoute{Host: strings.ToLower(host)}
+	if !ingressHost.MatchString(route.Host) {
+		return route, fmt.Errorf("Invalid host %s", host)
+	}
+	i := strings.LastIndex(target, ":")
+	j := strings.Index(target, "/")
+	if i == -1 || j <= 0 || j > i-2 {
+		return route, fmt.Errorf("Invalid target %s, expected <stack>/<service>:<port>", target)
+	}
+	route.Stack, route.Service = target[:j], target[j+1:i]
+	port, err := strconv.Atoi(target[i+1:])
+	if err != nil || port < 1 || port > 65535 {
+		return route, fmt.Errorf("Invalid port in %s", target)
+	}
+	route.Port = port
+	return route, nil
+}
+
+func (r IngressRoute) String() string {
+	return r.Host + " -> " + r.Stack + "/" + r.Service + ":" + strconv.Itoa(r.Port)
+}
+
+// consulService is the Consul service of the route, its tags are the Traefik frontend of the host
+func (r IngressRoute) consulService() string {
+	return ingressService + strings.ToLower(consulServiceName.ReplaceAllString(strings.Replace(r.Host, "*", "wildcard", 1), "-"))
+}
+
+func (r IngressRoute) consulTags() []string {
+	rule := "Host:" + r.Host
+	if strings.HasPrefix(r.Host, "*.") {
+		rule = "HostRegexp:{subdomain:[a-z0-9-]+}." + strings.TrimPrefix(r.Host, "*.")
+	}
+	return []string{"traefik.enable=true", "traefik.frontend.rule=" + rule, "traefik.frontend.passHostHeader=true"}
+}
+
+// traefikConfig reads the Consul catalog of the cluster through consul.mikrodock.local with the Consul certificates
+// of the node, only the services tagged traefik.enable=true are routed. The certificates of the hosts are
+// in the watched dynamic directory
+const traefikConfig = `defaultEntryPoints = ["http", "https"]
+
+[entryPoints]
+  [entryPoints.http]
+  address = ":80"
+  [entryPoints.https]
+  address = ":443"
+    [entryPoints.https.tls]
+
+[file]
+directory = "/etc/traefik/dynamic"
+watch = true
+
+[consulCatalog]
+endpoint = "` + ConsulServerHost + `:8081"
+exposedByDefault = false
+prefix = "traefik"
+  [consulCatalog.tls]
+  ca = "/etc/traefik/kv-ca.cert"
+  cert = "/etc/traefik/kv-cert.pem"
+  key = "/etc/traefik/kv-key.pem"
+`
+
+// DeployIngress runs the reverse proxy and the ACME responder on the partikles, attached to the overlay and
+// publishing ports 80 and 443. A proxy already running is replaced
+func (c *Cluster) DeployIngress(partikles []*Partikle, image string) error {
+	token, err := c.consulScopedToken(ConsulIngressToken)
+	if err != nil {
+		return err
+	}
+	var extraHosts []string
+	for _, server := range c.ConsulServers() {
+		extraHosts = append(extraHosts, ConsulServerHost+":"+server.IP())
+	}
+
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return err
+	}
+	tree := helper.NewTree(IngressPrefix)
+	proxies := tree.AddSubCategory("proxies")
+	for _, p := range partikles {
+		if err = p.runIngress(image, token, extraHosts); err != nil {
+			return err
+		}
+		if err = c.runACMEResponder(p); err != nil {
+			return err
+		}
+		proxies.AddChild(p.Name(), []byte(image))
+		logger.Info("Cluster.Ingress", "Ingress running on "+p.Name())
+	}
+	if err = helper.SendTree(tree); err != nil {
+		return fmt.Errorf("Cannot record the ingress proxies : %s", err)
+	}
+	return c.syncIngressCerts(partikles)
+}
+
+func (p *Partikle) runIngress(image string, token string, extraHosts []string) error {
+	cmd := fmt.Sprintf("mkdir -p %s/dynamic %s/certs %s && touch %s/dynamic/certs.toml && install -m 0600 /etc/docker/kv-ca.cert /etc/docker/kv-cert.pem /etc/docker/kv-key.pem %s",
+		ingressConfigDir, ingressConfigDir, ingressChallengeDir, ingressConfigDir, ingressConfigDir)
+	if _, errOut, err := p.Driver.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("Cannot copy the Consul certificates on %s : %s %s", p.Name(), err, errOut)
+	}
+	if err := p.Driver.Copy(int64(len(traefikConfig)), 0644, "traefik.toml", strings.NewReader(traefikConfig), ingressConfigDir, nil); err != nil {
+		return fmt.Errorf("Cannot upload the ingress configuration to %s : %s", p.Name(), err)
+	}
+
+	var env []string
+	if token != "" {
+		env = append(env, consulAPI.HTTPTokenEnvName+"="+token)
+	}
+	httpPort, httpsPort := nat.Port("80/tcp"), nat.Port("443/tcp")
+	err := p.replaceContainer(ingressContainer, &container.Config{
+		Image:        image,
+		Cmd:          []string{"--configFile=/etc/traefik/traefik.toml"},
+		Env:          env,
+		ExposedPorts: nat.PortSet{httpPort: struct{}{}, httpsPort: struct{}{}},
+		Labels:       map[string]string{NetworkLabel: "ingress"},
+	}, &container.HostConfig{
+		Binds: []string{ingressConfigDir + ":/etc/traefik:ro"},
+		PortBindings: nat.PortMap{
+			httpPort:  []nat.PortBinding{{HostPort: "80"}},
+			httpsPort: []nat.PortBinding{{HostPort: "443"}},
+		},
+		ExtraHosts:    extraHosts,
+		NetworkMode:   container.NetworkMode(OverlayNetwork),
+		RestartPolicy: container.RestartPolicy{Name: "always"},
+	})
+	if err != nil {
+		return fmt.Errorf("Cannot run the ingress of %s : %s", p.Name(), err)
+	}
+	return nil
+}
+
+// replaceContainer pulls the image of the container and runs it in place of the container of the same name
+func (p *Partikle) replaceContainer(name string, config *container.Config, hostConfig *container.HostConfig) error {
+	client, err := p.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	reader, err := client.ImagePull(ctx, config.Image, types.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("Cannot pull %s : %s", config.Image, err)
+	}
+	ioutil.ReadAll(reader)
+	reader.Close()
+
+	if err = client.ContainerRemove(ctx, name, types.ContainerRemoveOptions{Force: true}); err != nil && !strings.Contains(err.Error(), "No such container") {
+		return fmt.Errorf("Cannot remove the previous %s : %s", name, err)
+	}
+	body, err := client.ContainerCreate(ctx, config, hostConfig, &network.NetworkingConfig{}, name)
+	if err != nil {
+		return fmt.Errorf("Cannot create %s : %s", name, err)
+	}
+	if err = client.ContainerStart(ctx, body.ID, types.ContainerStartOptions{}); err != nil {
+		return fmt.Errorf("Cannot start %s : %s", name, err)
+	}
+	return nil
+}
+
+// IngressProxies returns the partikles running the reverse proxy
+func (c *Cluster) IngressProxies() ([]string, error) {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := helper.Keys(IngressPrefix + "/proxies/")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list the ingress proxies : %s", err)
+	}
+	proxies := make([]string, 0, len(keys))
+	for _, key := range keys {
+		proxies = append(proxies, strings.TrimPrefix(key, IngressPrefix+"/proxies/"))
+	}
+	sort.Strings(proxies)
+	return proxies, nil
+}
+
+// IngressRoutes returns the routes of the ingress, by host
+func (c *Cluster) IngressRoutes() ([]IngressRoute, error) {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := helper.Keys(IngressPrefix + "/routes/")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list the ingress routes : %s", err)
+	}
+	routes := make([]IngressRoute, 0, len(keys))
+	for _, key := range keys {
+		content, err := helper.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		var route IngressRoute
+		if err = json.Unmarshal(content, &route); err != nil {
+			return nil, fmt.Errorf("Cannot read %s : %s", key, err)
+		}
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Host < routes[j].Host
+	})
+	return routes, nil
+}
+
+// AddIngressRoute records the route and registers the instances of its service, replacing the route of the host
+func (c *Cluster) AddIngressRoute(route IngressRoute) error {
+	if err := c.saveIngressRoute(route); err != nil {
+		return err
+	}
+	return c.registerIngressRoute(route)
+}
+
+func (c *Cluster) saveIngressRoute(route IngressRoute) error {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(route)
+	if err != nil {
+		return err
+	}
+	tree := helper.NewTree(IngressPrefix)
+	tree.AddSubCategory("routes").AddChild(route.Host, content)
+	if err = helper.SendTree(tree); err != nil {
+		return fmt.Errorf("Cannot record the route of %s : %s", route.Host, err)
+	}
+	return nil
+}
+
+// registerIngressRoute registers the addresses of the instances of the service on the overlay, the proxies
+// reach them through it
+func (c *Cluster) registerIngressRoute(route IngressRoute) error {
+	srv, err := c.KinetikService(route.Stack, route.Service)
+	if err != nil {
+		return err
+	}
+	c.deregisterServices(func(service *consulAPI.AgentService) bool {
+		return service.Meta["ingress"] == route.Host
+	})
+
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return err
+	}
+	for _, instance := range srv.Instances {
+		p := c.PartikleByIP(instance.NodeID)
+		if p == nil {
+			return fmt.Errorf("Cannot find the partikle of %s", instance.NodeID)
+		}
+		ip, err := p.containerIP(instance.ContainerID, OverlayNetwork)
+		if err != nil {
+			return err
+		}
+		id := instance.ContainerID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		registration := &consulAPI.AgentServiceRegistration{
+			ID:      route.consulService() + "-" + id,
+			Name:    route.consulService(),
+			Address: ip,
+			Port:    route.Port,
+			Tags:    route.consulTags(),
+			Meta:    map[string]string{"ingress": route.Host, "stack": route.Stack, "service": route.Service, "container": instance.ContainerID},
+		}
+		if err = client.Agent().ServiceRegister(registration); err != nil {
+			return fmt.Errorf("Cannot register %s : %s", registration.ID, err)
+		}
+	}
+	return nil
+}
+
+// RemoveIngressRoute stops routing the host
+func (c *Cluster) RemoveIngressRoute(host string) error {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return err
+	}
+	key := IngressPrefix + "/routes/" + strings.ToLower(host)
+	content, err := helper.Get(key)
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		return fmt.Errorf("No route for %s", host)
+	}
+	c.deregisterServices(func(service *consulAPI.AgentService) bool {
+		return service.Meta["ingress"] == strings.ToLower(host)
+	})
+	return helper.Delete(key, false)
+}
+
+// RefreshIngress registers again the instances of the routes to the services of the stack, after they changed
+func (c *Cluster) RefreshIngress(stack string) error {
+	routes, err := c.IngressRoutes()
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		if route.Stack != stack {
+			continue
+		}
+		if err = c.registerIngressRoute(route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeployIngressLabels records the routes of the services of a compose file labelled with compose.IngressHostLabel,
+// then registers the instances of every route of the stack
+func (c *Cluster) DeployIngressLabels(stack string, composeContent []byte) error {
+	ingresses, err := compose.ParseIngresses(composeContent)
+	if err != nil {
+		return err
+	}
+	for service, ingress := range ingresses {
+		route, err := ParseIngressTarget(ingress.Host, stack+"/"+service+":"+strconv.Itoa(ingress.Port))
+		if err != nil {
+			return err
+		}
+		if err = c.saveIngressRoute(route); err != nil {
+			return err
+		}
+		logger.Info("Cluster.Ingress", route.String())
+	}
+	return c.RefreshIngress(stack)
+}
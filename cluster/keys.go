@@ -0,0 +1,222 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/mssh"
+	"net"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RotateSSHKey replaces the cluster SSH key on every partikle and at the driver provider.
+// The old key stays valid until the new one has been checked on every partikle
+func (c *Cluster) RotateSSHKey(keyType mssh.KeyType) error {
+	if len(c.Partikles) == 0 {
+		return errors.New("The cluster has no partikle")
+	}
+	for _, p := range c.Partikles {
+		if p == nil {
+			return errors.New("A partikle of the cluster cannot be loaded")
+		}
+	}
+
+	privateKeyPath := path.Join(c.SSHPath(), "private_key")
+	newKeyPath := privateKeyPath + ".new"
+
+	oldKey, err := mssh.LoadPrivateKey(privateKeyPath)
+	if err != nil {
+		return fmt.Errorf("Cannot load current key : %s", err)
+	}
+
+	// An encrypted cluster key stays encrypted with the same passphrase
+	encrypted, err := mssh.IsEncryptedKey(privateKeyPath)
+	if err != nil {
+		return fmt.Errorf("Cannot read current key : %s", err)
+	}
+	var passphrase []byte
+	if encrypted {
+		if passphrase, err = mssh.ReadPassphrase(privateKeyPath); err != nil {
+			return fmt.Errorf("Cannot read the passphrase of the current key : %s", err)
+		}
+	}
+
+	logger.Info("Keys.Rotate", "Generating new SSH key")
+	if err = mssh.CreateKey(newKeyPath, keyType, passphrase); err != nil {
+		return fmt.Errorf("Cannot generate new key : %s", err)
+	}
+	if encrypted {
+		mssh.RememberPassphrase(newKeyPath, passphrase)
+	}
+	newKey, err := mssh.LoadPrivateKey(newKeyPath)
+	if err != nil {
+		os.Remove(newKeyPath)
+		return fmt.Errorf("Cannot load new key : %s", err)
+	}
+
+	oldAuthorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(oldKey.PublicKey())))
+	newAuthorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(newKey.PublicKey())))
+
+	// Until the provider has the new key, a failure removes it from the partikles and the hops and restores the old one
+	newBlob := strings.Fields(newAuthorized)[1]
+	hops := clusterKeyHops(c.Partikles)
+	var authorized, uploaded []*Partikle
+	var authorizedHops []clusterKeyHop
+	rollback := func() {
+		for _, p := range uploaded {
+			if err := p.UploadFile(privateKeyPath, "/root/.ssh/id_rsa"); err != nil {
+				logger.Warn("Keys.Rotate", "Cannot restore the old key of "+p.Name()+" : "+err.Error())
+			}
+		}
+		for _, p := range authorized {
+			if err := removeAuthorizedKey(p, newBlob); err != nil {
+				logger.Warn("Keys.Rotate", "Cannot remove the new key from "+p.Name()+" : "+err.Error())
+			}
+		}
+		for _, hop := range authorizedHops {
+			if err := hop.run(privateKeyPath, removeAuthorizedKeyCmd(newBlob)); err != nil {
+				logger.Warn("Keys.Rotate", "Cannot remove the new key from the jump host "+hop.String()+" : "+err.Error())
+			}
+		}
+		os.Remove(newKeyPath)
+	}
+
+	// The jump hosts without a key of their own authenticate with the cluster key, the partikles are reached through them
+	for _, hop := range hops {
+		logger.Info("Keys.Rotate", "Authorizing new key on the jump host "+hop.String())
+		if err = hop.run(privateKeyPath, authorizeKeyCmd(newAuthorized)); err != nil {
+			rollback()
+			return fmt.Errorf("Cannot authorize new key on the jump host %s : %s", hop.String(), err)
+		}
+		authorizedHops = append(authorizedHops, hop)
+	}
+
+	for _, p := range c.Partikles {
+		logger.Info("Keys.Rotate", "Authorizing new key on "+p.Name())
+		if _, errOut, err := p.Driver.SSHCommand(authorizeKeyCmd(newAuthorized)); err != nil {
+			rollback()
+			return fmt.Errorf("Cannot authorize new key on %s : %s %s", p.Name(), err, errOut)
+		}
+		authorized = append(authorized, p)
+
+		// The whole path is checked with the new key only, as it will be used once the rotation is done
+		base := p.Driver.GetBaseDriver()
+		if err = mssh.CheckAuth(base.JumpHosts, base.IPAddress+":"+base.SSHPort, base.SSHUser, newKeyPath, newKeyPath); err != nil {
+			rollback()
+			return fmt.Errorf("Cannot login on %s with new key : %s", p.Name(), err)
+		}
+	}
+
+	for _, p := range c.Partikles {
+		if p.IsMaster || p.Name() == "konduktor" {
+			logger.Info("Keys.Rotate", "Uploading new key to "+p.Name())
+			uploaded = append(uploaded, p)
+			if err = p.UploadFile(newKeyPath, "/root/.ssh/id_rsa"); err != nil {
+				rollback()
+				return fmt.Errorf("Cannot upload new key to %s : %s", p.Name(), err)
+			}
+		}
+	}
+
+	logger.Info("Keys.Rotate", "Replacing key at the driver provider")
+	if err = c.Partikles[0].Driver.ReplaceSSHKey(newKeyPath); err != nil {
+		rollback()
+		return fmt.Errorf("Cannot replace provider key : %s", err)
+	}
+
+	if err = os.Rename(newKeyPath, privateKeyPath); err != nil {
+		return fmt.Errorf("Cannot replace local key, the new one is %s : %s", newKeyPath, err)
+	}
+	if encrypted {
+		mssh.RememberPassphrase(privateKeyPath, passphrase)
+	}
+	for _, p := range c.Partikles {
+		p.Driver.GetBaseDriver().SSHKeyPath = privateKeyPath
+		if err = p.Save(); err != nil {
+			return fmt.Errorf("Cannot save %s : %s", p.Name(), err)
+		}
+	}
+
+	// The blob is enough to match the key whatever its comment
+	oldBlob := strings.Fields(oldAuthorized)[1]
+	for _, p := range c.Partikles {
+		logger.Info("Keys.Rotate", "Removing old key from "+p.Name())
+		if err = removeAuthorizedKey(p, oldBlob); err != nil {
+			return fmt.Errorf("The new key is in use but the old one is still authorized on %s : %s", p.Name(), err)
+		}
+	}
+	for _, hop := range hops {
+		logger.Info("Keys.Rotate", "Removing old key from the jump host "+hop.String())
+		if err = hop.run(privateKeyPath, removeAuthorizedKeyCmd(oldBlob)); err != nil {
+			return fmt.Errorf("The new key is in use but the old one is still authorized on the jump host %s : %s", hop.String(), err)
+		}
+	}
+
+	logger.Info("Keys.Rotate", "SSH key rotated")
+	return nil
+}
+
+// authorizeKeyCmd appends the authorized_keys line unless it is already there
+func authorizeKeyCmd(authorized string) string {
+	return fmt.Sprintf("mkdir -p ~/.ssh && grep -q -F '%s' ~/.ssh/authorized_keys || echo '%s' >> ~/.ssh/authorized_keys", authorized, authorized)
+}
+
+// removeAuthorizedKeyCmd removes the lines of authorized_keys holding the key blob
+func removeAuthorizedKeyCmd(blob string) string {
+	return fmt.Sprintf("grep -v -F '%s' ~/.ssh/authorized_keys > ~/.ssh/authorized_keys.tmp; mv ~/.ssh/authorized_keys.tmp ~/.ssh/authorized_keys && chmod 600 ~/.ssh/authorized_keys", blob)
+}
+
+// removeAuthorizedKey removes the lines of authorized_keys holding the key blob
+func removeAuthorizedKey(p *Partikle, blob string) error {
+	if _, errOut, err := p.Driver.SSHCommand(removeAuthorizedKeyCmd(blob)); err != nil {
+		return fmt.Errorf("%s %s", err, errOut)
+	}
+	return nil
+}
+
+// clusterKeyHop is a jump host authenticating with the cluster key, reached through the hops before it
+type clusterKeyHop struct {
+	before []mssh.JumpHost
+	addr   string
+	user   string
+}
+
+func (h clusterKeyHop) String() string {
+	return h.user + "@" + h.addr
+}
+
+// run runs cmd on the jump host, every hop without a key of its own authenticating with keyFile
+func (h clusterKeyHop) run(keyFile string, cmd string) error {
+	out, err := mssh.RunJump(h.before, h.addr, h.user, keyFile, keyFile, cmd)
+	if err != nil {
+		return fmt.Errorf("%s %s", err, strings.TrimSpace(out))
+	}
+	return nil
+}
+
+// clusterKeyHops lists once the jump hosts of the partikles without a key of their own
+func clusterKeyHops(partikles []*Partikle) []clusterKeyHop {
+	seen := make(map[string]bool)
+	hops := make([]clusterKeyHop, 0)
+	for _, p := range partikles {
+		base := p.Driver.GetBaseDriver()
+		for i, hop := range base.JumpHosts {
+			if hop.KeyPath != "" {
+				continue
+			}
+			h := clusterKeyHop{before: base.JumpHosts[:i], addr: net.JoinHostPort(hop.Address, hop.Port), user: hop.User}
+			if h.user == "" {
+				h.user = base.SSHUser
+			}
+			if !seen[h.String()] {
+				seen[h.String()] = true
+				hops = append(hops, h)
+			}
+		}
+	}
+	return hops
+}
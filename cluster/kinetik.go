@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	kModels "github.com/mikrodock/kinetik-server/models"
+)
+
+// KinetikPort is the port of the kinetik API on the konduktor
+const KinetikPort = "10513"
+
+// KinetikServices returns the services deployed by kinetik
+func (c *Cluster) KinetikServices() ([]kModels.Service, error) {
+	konduktor := c.PartikleByName("konduktor")
+	if konduktor == nil {
+		return nil, errors.New("The cluster has no konduktor")
+	}
+	res, err := konduktor.HTTPClient(30 * time.Second).Get("http://" + konduktor.IP() + ":" + KinetikPort + "/services")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list the services : %s", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("Cannot list the services : kinetik answered %s", res.Status)
+	}
+	services := make([]kModels.Service, 0)
+	if err = json.NewDecoder(res.Body).Decode(&services); err != nil {
+		return nil, fmt.Errorf("Cannot read the services : %s", err)
+	}
+	return services, nil
+}
+
+// KinetikService returns a service of a stack deployed by kinetik
+func (c *Cluster) KinetikService(stack string, service string) (*kModels.Service, error) {
+	services, err := c.KinetikServices()
+	if err != nil {
+		return nil, err
+	}
+	for i := range services {
+		if services[i].StackName == stack && services[i].ServiceName == service {
+			return &services[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Cannot find the service %s of the stack %s", service, stack)
+}
+
+// PartikleByIP returns the partikle of the address, nil when there is none
+func (c *Cluster) PartikleByIP(ip string) *Partikle {
+	for _, p := range c.Partikles {
+		if p != nil && p.IP() == ip {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"mikrodock-cli/logger"
+	consulhelpers "mikrodock-cli/utils/consul-helpers"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+// ConsulServerHost resolves to every konsultant in the /etc/hosts of the partikles.
+// Docker and kinetik reach Consul through it and fail over between its addresses
+const ConsulServerHost = "consul.mikrodock.local"
+
+// consulRaftPort is the server RPC port used in the raft peer set
+const consulRaftPort = "8300"
+
+// ValidKonsultantCount tells if n Consul servers keep a quorum, an even count adds no fault tolerance
+func ValidKonsultantCount(n int) bool {
+	return n == 1 || n == 3 || n == 5
+}
+
+// KonsultantName returns the name of the i-th konsultant, the first one keeps the name of single konsultant clusters
+func KonsultantName(i int) string {
+	if i == 0 {
+		return "konsultant"
+	}
+	return "konsultant-" + strconv.Itoa(i+1)
+}
+
+// ConsulServers returns the konsultants of the cluster, sorted by name
+func (c *Cluster) ConsulServers() []*Partikle {
+	servers := make([]*Partikle, 0)
+	for _, p := range c.Partikles {
+		if p != nil && p.IsConsulServer() {
+			servers = append(servers, p)
+		}
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name() < servers[j].Name()
+	})
+	return servers
+}
+
+// consulServerIPs returns the IPs of servers, except the one of skip
+func consulServerIPs(servers []*Partikle, skip *Partikle) []string {
+	ips := make([]string, 0, len(servers))
+	for _, p := range servers {
+		if p != skip {
+			ips = append(ips, p.IP())
+		}
+	}
+	return ips
+}
+
+// ConnectToConsul returns a client of the first konsultant having a raft leader, the others are tried in turn
+func (c *Cluster) ConnectToConsul() (*consulAPI.Client, error) {
+	return connectToConsul(c.ConsulServers())
+}
+
+// ConsulHelper returns a KV helper connected by ConnectToConsul
+func (c *Cluster) ConsulHelper() (*consulhelpers.ConsulHelper, error) {
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return nil, err
+	}
+	return consulhelpers.NewConsulHelper(client), nil
+}
+
+func connectToConsul(servers []*Partikle) (*consulAPI.Client, error) {
+	lastErr := errors.New("The cluster has no konsultant")
+	for _, p := range servers {
+		client, err := p.NewConsulClient()
+		if err == nil {
+			var leader string
+			leader, err = client.Status().Leader()
+			if err == nil && leader != "" {
+				return client, nil
+			}
+			if err == nil {
+				err = errors.New("No raft leader")
+			}
+		}
+		logger.Warn("Cluster.Consul", "Konsultant "+p.Name()+" unavailable : "+err.Error())
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// newKonsultant creates the machine of a konsultant and starts its Docker, Consul is not started yet
+func (c *Cluster) newKonsultant(name string) (*Partikle, error) {
+	driverConfig := make(map[string]interface{})
+	driverConfig["ssh-key-path"] = path.Join(c.SSHPath(), "private_key")
+	driverConfig["name"] = name
+	driverConfig["jump-hosts"] = c.JumpHosts
+
+	driver, err := c.DriverFactory(driverConfig)
+	if err != nil {
+		return nil, err
+	}
+	if err = driver.Create(); err != nil {
+		return nil, err
+	}
+	logger.Info("Cluster.Konsultant", name+" Machine Created")
+
+	p := NewPartikle(driver, getProvider(driver), c)
+
+	if err = p.GenerateDockerCerts(c.DockerConfigPath()); err != nil {
+		return nil, fmt.Errorf("Cannot generate Docker certs : %s", err)
+	}
+	if err = p.UploadDockerCerts(); err != nil {
+		return nil, fmt.Errorf("Cannot upload Docker certs : %s", err)
+	}
+	if err = p.GenerateConsulCerts(c.ConsulConfPath()); err != nil {
+		return nil, fmt.Errorf("Cannot generate Consul certs : %s", err)
+	}
+	if err = p.UploadConsulCerts("/opt/consul-ssl"); err != nil {
+		return nil, fmt.Errorf("Cannot upload Consul certs : %s", err)
+	}
+	if err = p.ConfigureDocker(nil); err != nil {
+		return nil, fmt.Errorf("Cannot configure Docker : %s", err)
+	}
+	if err = p.WaitDocker(); err != nil {
+		return nil, fmt.Errorf("Docker Timeout : %s", err)
+	}
+	return p, nil
+}
+
+// ConfigureConsulHosts points ConsulServerHost at every server in the /etc/hosts of the partikle
+func (p *Partikle) ConfigureConsulHosts(servers []*Partikle) error {
+	cmd := "sed -i '/ " + ConsulServerHost + "$/d' /etc/hosts"
+	for _, ip := range consulServerIPs(servers, nil) {
+		cmd += " && echo '" + ip + " " + ConsulServerHost + "' >> /etc/hosts"
+	}
+	if _, errOut, err := p.Driver.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("Cannot update /etc/hosts of %s : %s %s", p.Name(), err, errOut)
+	}
+	return nil
+}
+
+// updateConsulHosts spreads the current server set to every partikle
+func (c *Cluster) updateConsulHosts() {
+	servers := c.ConsulServers()
+	for _, p := range c.Partikles {
+		if p == nil {
+			continue
+		}
+		if err := p.ConfigureConsulHosts(servers); err != nil {
+			logger.Warn("Cluster.Konsultant", err.Error())
+			continue
+		}
+		if p.IsConsulServer() {
+			continue
+		}
+		// Partikles created before the HA konsultants use the address of the first one
+		if _, _, err := p.Driver.SSHCommand("grep -q " + ConsulServerHost + " /etc/default/docker"); err != nil {
+			logger.Warn("Cluster.Konsultant", "Docker of "+p.Name()+" uses a single konsultant as cluster-store, it does not fail over")
+		}
+	}
+}
+
+// waitRaftPeer waits until the server at ip is a voter of the raft peer set, or has left it when present is false
+func waitRaftPeer(client *consulAPI.Client, ip string, present bool) error {
+	address := ip + ":" + consulRaftPort
+	for retries := 0; retries < 12; retries++ {
+		config, err := client.Operator().RaftGetConfiguration(nil)
+		if err == nil {
+			found := false
+			for _, server := range config.Servers {
+				if server.Address == address && (server.Voter || !present) {
+					found = true
+				}
+			}
+			if found == present {
+				return nil
+			}
+		}
+		time.Sleep(5 * time.Second)
+	}
+	if present {
+		return fmt.Errorf("%s did not become a raft voter (Timeout after 60 seconds)", address)
+	}
+	return fmt.Errorf("%s is still a raft peer (Timeout after 60 seconds)", address)
+}
+
+// AddKonsultant creates a new Consul server and waits for it to join the raft peer set
+func (c *Cluster) AddKonsultant() (*Partikle, error) {
+	servers := c.ConsulServers()
+	client, err := c.ConnectToConsul()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot connect to Consul : %s", err)
+	}
+
+	name := ""
+	for i := 0; name == ""; i++ {
+		if c.PartikleByName(KonsultantName(i)) == nil {
+			name = KonsultantName(i)
+		}
+	}
+
+	p, err := c.newKonsultant(name)
+	if err != nil {
+		return nil, err
+	}
+	c.Partikles = append(c.Partikles, p)
+	c.Save()
+
+	if err = p.RunConsulContainer(0, consulServerIPs(servers, nil)); err != nil {
+		return p, fmt.Errorf("Cannot start Consul : %s", err)
+	}
+	if err = waitRaftPeer(client, p.IP(), true); err != nil {
+		return p, err
+	}
+	if err = p.SetConsulAgentToken(); err != nil {
+		return p, err
+	}
+
+	helper := consulhelpers.NewConsulHelper(client)
+	tree := helper.NewTree("mikrodock")
+	node := tree.AddSubCategory("nodes").AddSubCategory(p.IP())
+	node.AddChild("name", []byte(p.Name()))
+	node.AddChild("type", []byte("KONSULTANT"))
+	node.AddChild("docker-port", []byte(strconv.Itoa(2376)))
+	if err = helper.SendTree(tree); err != nil {
+		logger.Warn("Cluster.Konsultant", "Cannot register "+p.Name()+" : "+err.Error())
+	}
+
+	if err = c.RegisterPartikle(p); err != nil {
+		logger.Warn("Cluster.Konsultant", "Cannot register the health checks of "+p.Name()+" : "+err.Error())
+	}
+
+	c.updateConsulHosts()
+	if !ValidKonsultantCount(len(servers) + 1) {
+		logger.Warn("Cluster.Konsultant", strconv.Itoa(len(servers)+1)+" konsultants tolerate no more failures than "+strconv.Itoa(len(servers)))
+	}
+	return p, nil
+}
+
+// RemoveKonsultant makes a Consul server leave the raft peer set, then destroys its machine.
+// Every other server must be a voter so the cluster keeps its quorum
+func (c *Cluster) RemoveKonsultant(name string) error {
+	target := c.PartikleByName(name)
+	if target == nil || !target.IsConsulServer() {
+		return fmt.Errorf("%s is not a konsultant of %s", name, c.Name)
+	}
+	servers := c.ConsulServers()
+	if len(servers) == 1 {
+		return errors.New("Cannot remove the last konsultant")
+	}
+	remaining := make([]*Partikle, 0, len(servers)-1)
+	for _, p := range servers {
+		if p != target {
+			remaining = append(remaining, p)
+		}
+	}
+
+	client, err := connectToConsul(remaining)
+	if err != nil {
+		return fmt.Errorf("Cannot connect to the other konsultants : %s", err)
+	}
+	config, err := client.Operator().RaftGetConfiguration(nil)
+	if err != nil {
+		return fmt.Errorf("Cannot read the raft peer set : %s", err)
+	}
+	for _, p := range remaining {
+		voter := false
+		for _, server := range config.Servers {
+			if server.Address == p.IP()+":"+consulRaftPort && server.Voter {
+				voter = true
+			}
+		}
+		if !voter {
+			return fmt.Errorf("%s is not a raft voter, removing %s could lose the quorum", p.Name(), name)
+		}
+	}
+
+	c.DeregisterPartikle(name)
+
+	// A graceful leave lets the leader update the peer set itself
+	targetClient, err := target.newConsulClient(c.consulAdminToken())
+	if err == nil {
+		err = targetClient.Agent().Leave()
+	}
+	if err == nil {
+		err = waitRaftPeer(client, target.IP(), false)
+	}
+	if err != nil {
+		logger.Warn("Cluster.Konsultant", name+" did not leave ("+err.Error()+"), removing its raft peer")
+		if err = client.Operator().RaftRemovePeerByAddress(target.IP()+":"+consulRaftPort, nil); err != nil {
+			return fmt.Errorf("Cannot remove the raft peer of %s : %s", name, err)
+		}
+	}
+
+	helper := consulhelpers.NewConsulHelper(client)
+	if err = helper.Delete("mikrodock/nodes/"+target.IP()+"/", true); err != nil {
+		logger.Warn("Cluster.Konsultant", "Cannot unregister "+name+" : "+err.Error())
+	}
+
+	if err = target.Driver.Destroy(); err != nil {
+		logger.Warn("Cluster.Konsultant", "Cannot destroy "+name+" : "+err.Error())
+	}
+	for i, p := range c.Partikles {
+		if p == target {
+			c.Partikles = append(c.Partikles[:i], c.Partikles[i+1:]...)
+			break
+		}
+	}
+	if err = os.RemoveAll(c.PartiklePath(name)); err != nil {
+		return err
+	}
+	c.Save()
+
+	c.updateConsulHosts()
+	return nil
+}
@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/subnets"
+	"net"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// The overlay network created with the cluster
+const (
+	OverlayNetwork = "mikroverlay"
+	OverlaySubnet  = "172.142.0.0/16"
+	OverlayGateway = "172.142.0.1"
+)
+
+// Labels of the networks managed by mikrodock
+const (
+	NetworkLabel      = "be.mikrodock.network"
+	NetworkStackLabel = "be.mikrodock.stack"
+)
+
+// NetworkOptions is an overlay network to create, the gateway defaults to the first address of the subnet
+type NetworkOptions struct {
+	Name       string
+	Subnet     string
+	Gateway    string
+	Internal   bool
+	Attachable bool
+	Encrypted  bool
+	Labels     map[string]string
+}
+
+func (c *Cluster) konduktorDocker() (*client.Client, error) {
+	konduktor := c.PartikleByName("konduktor")
+	if konduktor == nil {
+		return nil, errors.New("Cannot find the konduktor")
+	}
+	return konduktor.NewDockerClient()
+}
+
+// usedSubnets returns the subnets of the networks of Docker and of the hosts of the partikles, by owner
+func (c *Cluster) usedSubnets(docker *client.Client) (map[string][]*net.IPNet, error) {
+	used := make(map[string][]*net.IPNet)
+	networks, err := docker.NetworkList(context.Background(), types.NetworkListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list the networks : %s", err)
+	}
+	for _, n := range networks {
+		for _, config := range n.IPAM.Config {
+			if _, subnet, err := net.ParseCIDR(config.Subnet); err == nil {
+				used["network "+n.Name] = append(used["network "+n.Name], subnet)
+			}
+		}
+	}
+	for _, p := range c.Partikles {
+		if p == nil {
+			continue
+		}
+		stdout, _, err := p.Driver.SSHCommand("ip -o addr show")
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the addresses of %s : %s", p.Name(), err)
+		}
+		used["host "+p.Name()] = subnets.ParseHostSubnets(stdout)
+	}
+	return used, nil
+}
+
+// CreateNetwork creates an overlay network from the konduktor, its subnet must not overlap with the existing
+// networks nor the subnets of the hosts
+func (c *Cluster) CreateNetwork(opts NetworkOptions) (string, error) {
+	subnet, err := subnets.Parse(opts.Subnet)
+	if err != nil {
+		return "", err
+	}
+	gateway := opts.Gateway
+	if gateway == "" {
+		gateway = subnets.DefaultGateway(subnet)
+	}
+	if err = subnets.ValidateGateway(subnet, gateway); err != nil {
+		return "", err
+	}
+
+	docker, err := c.konduktorDocker()
+	if err != nil {
+		return "", err
+	}
+	used, err := c.usedSubnets(docker)
+	if err != nil {
+		return "", err
+	}
+	if owner, other, ok := subnets.FindOverlap(subnet, used); ok {
+		return "", fmt.Errorf("Cannot create %s : %s overlaps with %s of the %s", opts.Name, subnet.String(), other.String(), owner)
+	}
+
+	labels := map[string]string{NetworkLabel: "overlay"}
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	options := make(map[string]string)
+	if opts.Encrypted {
+		options["encrypted"] = ""
+	}
+
+	logger.Debug("Cluster.Network", "Creating "+opts.Name+" on "+subnet.String()+" through "+gateway)
+	resp, err := docker.NetworkCreate(context.Background(), opts.Name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "overlay",
+		Internal:       opts.Internal,
+		Attachable:     opts.Attachable,
+		Options:        options,
+		Labels:         labels,
+		IPAM: &network.IPAM{
+			Driver: "default",
+			Config: []network.IPAMConfig{
+				{Subnet: subnet.String(), Gateway: gateway},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("Cannot create %s : %s", opts.Name, err)
+	}
+	if resp.Warning != "" {
+		logger.Warn("Cluster.Network", resp.Warning)
+	}
+	return resp.ID, nil
+}
+
+// Networks returns the networks managed by mikrodock
+func (c *Cluster) Networks() ([]types.NetworkResource, error) {
+	docker, err := c.konduktorDocker()
+	if err != nil {
+		return nil, err
+	}
+	args := filters.NewArgs()
+	args.Add("label", NetworkLabel)
+	return docker.NetworkList(context.Background(), types.NetworkListOptions{Filters: args})
+}
+
+// InspectNetwork returns a network managed by mikrodock
+func (c *Cluster) InspectNetwork(name string) (types.NetworkResource, error) {
+	docker, err := c.konduktorDocker()
+	if err != nil {
+		return types.NetworkResource{}, err
+	}
+	resource, err := docker.NetworkInspect(context.Background(), name, false)
+	if err != nil {
+		return resource, err
+	}
+	if _, ok := resource.Labels[NetworkLabel]; !ok {
+		return resource, fmt.Errorf("%s is not a mikrodock network", name)
+	}
+	return resource, nil
+}
+
+// RemoveNetwork removes a network created with CreateNetwork, the cluster overlay cannot be removed
+func (c *Cluster) RemoveNetwork(name string) error {
+	resource, err := c.InspectNetwork(name)
+	if err != nil {
+		return err
+	}
+	if resource.Name == OverlayNetwork {
+		return fmt.Errorf("%s is the overlay of the cluster and cannot be removed", OverlayNetwork)
+	}
+	docker, err := c.konduktorDocker()
+	if err != nil {
+		return err
+	}
+	return docker.NetworkRemove(context.Background(), resource.ID)
+}
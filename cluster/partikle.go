@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -11,9 +12,13 @@ import (
 	"mikrodock-cli/logger"
 	"mikrodock-cli/provision"
 	"mikrodock-cli/utils/certs"
+	"mikrodock-cli/utils/mssh"
+	"net"
+	"net/http"
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -23,6 +28,9 @@ import (
 	consulAPI "github.com/hashicorp/consul/api"
 )
 
+// ConsulDatacenter is the Consul datacenter of every cluster
+const ConsulDatacenter = "dc1"
+
 type DockerClusterOptions struct {
 	ClusterStoreAddress string
 	AdvertiseAddress    string
@@ -57,6 +65,9 @@ type Partikle struct {
 	Provider provision.Provider
 	Galaksy  *Cluster
 	IsMaster bool
+
+	// JumpHosts overrides the jump hosts of the cluster for this partikle only
+	JumpHosts []mssh.JumpHost
 }
 
 func newEmptyPartikle() *Partikle {
@@ -87,31 +98,54 @@ func (p *Partikle) Create() error {
 	return p.Driver.Create()
 }
 
+// GenerateConsulCerts issues the Consul certificate of the partikle from the Consul CA of caDir.
+// The Consul server gets a server certificate, the other partikles a client certificate for Docker
 func (p *Partikle) GenerateConsulCerts(caDir string) error {
-	certGen := certs.NewX509CertGenerator()
-
-	err := certGen.GenerateCACert(path.Join(caDir, "ca.cert"), path.Join(caDir, "ca.key"), "Mikrodock-Consul-CA", 2048)
-
-	if err != nil {
+	if err := os.MkdirAll(p.CertsPath(), 0775); err != nil {
 		return err
 	}
 
 	opts := &certs.CertOpts{
-		CAFile:       path.Join(p.Galaksy.ConsulConfPath(), "ca.cert"),
-		CAKeyFile:    path.Join(p.Galaksy.ConsulConfPath(), "ca.key"),
-		CertFile:     path.Join(p.Galaksy.ConsulConfPath(), "cert.pem"),
-		KeyFile:      path.Join(p.Galaksy.ConsulConfPath(), "key.pem"),
+		CAFile:       path.Join(caDir, "ca.cert"),
+		CAKeyFile:    path.Join(caDir, "ca.key"),
+		CertFile:     p.ConsulCertPath(),
+		KeyFile:      p.ConsulKeyPath(),
 		KeyBits:      2048,
-		MainHost:     "consul.mikrodock.local",
-		AliasIPs:     []string{p.Driver.GetBaseDriver().IPAddress, "127.0.0.1"},
+		MainHost:     p.Name() + ".mikrodock.local",
+		AliasIPs:     []string{},
 		AliasHosts:   []string{},
-		MasterMode:   true,
+		MasterMode:   false,
 		Organization: "Mikrodock-Consul",
 	}
 
+	if p.IsConsulServer() {
+		opts.MainHost = "consul.mikrodock.local"
+		opts.AliasHosts = []string{"consul.mikrodock.local", "server." + ConsulDatacenter + ".consul", "localhost"}
+		opts.AliasIPs = []string{p.Driver.GetBaseDriver().IPAddress, "127.0.0.1"}
+		// Servers also authenticate to each other with this certificate
+		opts.MasterMode = true
+	}
+
+	certGen, err := p.Galaksy.certGenerator(ConsulCA)
+	if err != nil {
+		return err
+	}
 	return certGen.GenerateCert(opts)
 }
 
+// IsConsulServer tells if the partikle runs a Consul server
+func (p *Partikle) IsConsulServer() bool {
+	return p.Name() == "konsultant" || strings.HasPrefix(p.Name(), "konsultant-")
+}
+
+func (p *Partikle) ConsulCertPath() string {
+	return path.Join(p.CertsPath(), "consul-cert.pem")
+}
+
+func (p *Partikle) ConsulKeyPath() string {
+	return path.Join(p.CertsPath(), "consul-key.pem")
+}
+
 func (p *Partikle) GenerateDockerCerts(caDir string) error {
 	if _, err := os.Stat(p.CertsPath()); os.IsNotExist(err) {
 		if err := os.MkdirAll(p.CertsPath(), 0775); err != nil {
@@ -132,11 +166,12 @@ func (p *Partikle) GenerateDockerCerts(caDir string) error {
 		Organization: "Mikrodock",
 	}
 
-	certGen := certs.NewX509CertGenerator()
-
-	err := certGen.GenerateCert(opts)
+	certGen, err := p.Galaksy.certGenerator(DockerCA)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return certGen.GenerateCert(opts)
 }
 
 func (p *Partikle) UploadConsulCerts(remotePath string) error {
@@ -151,12 +186,16 @@ func (p *Partikle) UploadConsulCerts(remotePath string) error {
 		return err
 	}
 
-	err = p.UploadFile(path.Join(p.Galaksy.ConsulConfPath(), "cert.pem"), path.Join(remotePath, "kv-cert.pem"))
+	// Clusters created before per partikle Consul certificates share the cluster one
+	certFile := existingOr(p.ConsulCertPath(), path.Join(p.Galaksy.ConsulConfPath(), "cert.pem"))
+	keyFile := existingOr(p.ConsulKeyPath(), path.Join(p.Galaksy.ConsulConfPath(), "key.pem"))
+
+	err = p.UploadFile(certFile, path.Join(remotePath, "kv-cert.pem"))
 	if err != nil {
 		return err
 	}
 
-	err = p.UploadFile(path.Join(p.Galaksy.ConsulConfPath(), "key.pem"), path.Join(remotePath, "kv-key.pem"))
+	err = p.UploadFile(keyFile, path.Join(remotePath, "kv-key.pem"))
 
 	return err
 }
@@ -177,11 +216,28 @@ func (p *Partikle) UploadDockerCerts() error {
 		return err
 	}
 
-	return p.UploadFile(path.Join(p.CertsPath(), "key.pem"), "/etc/docker/key.pem")
+	if err = p.UploadFile(path.Join(p.CertsPath(), "key.pem"), "/etc/docker/key.pem"); err != nil {
+		return err
+	}
 
+	// The TLS guard refuses every connection without a CRL matching the CA
+	if p.Galaksy.HasCRL() {
+		return p.UploadFile(p.Galaksy.CRLPath(), "/etc/docker/crl.pem")
+	}
+	return nil
 }
 
-func (p *Partikle) RunConsulContainer() error {
+// RunConsulContainer starts the Consul server of a konsultant. The servers of a new cluster wait for
+// bootstrapExpect servers before electing a leader, a server added later uses 0 and joins the others
+func (p *Partikle) RunConsulContainer(bootstrapExpect int, join []string) error {
+	localConfig, err := p.consulLocalConfig(bootstrapExpect, join)
+	if err != nil {
+		return err
+	}
+	localJSON, err := json.Marshal(localConfig)
+	if err != nil {
+		return err
+	}
 
 	vols := make(map[string]struct{})
 	vols["/consul/data"] = struct{}{}
@@ -190,8 +246,8 @@ func (p *Partikle) RunConsulContainer() error {
 	return p.RunContainer("izanagi1995/consul-ssl", "mikro-consul", vols, &container.Config{
 		Hostname: "mikro-consul",
 		Image:    "izanagi1995/consul-ssl",
-		Env:      []string{"CONSUL_LOCAL_CONFIG={\"skip_leave_on_interrupt\": true, \"addresses\": {\"https\": \"" + p.Driver.GetBaseDriver().IPAddress + "\"}, \"ports\" : {\"https\" : 8081, \"http\": -1}, \"ca_file\": \"/consul/ssl/kv-ca.cert\", \"cert_file\": \"/consul/ssl/kv-cert.pem\", \"key_file\": \"/consul/ssl/kv-key.pem\", \"verify_outgoing\": true, \"verify_incoming\": true}"},
-		Cmd:      []string{"consul", "agent", "-server", "-data-dir=/consul/data", "-bind=" + p.Driver.GetBaseDriver().IPAddress, "-client=" + p.Driver.GetBaseDriver().IPAddress, "-config-dir=/consul/config", "-bootstrap"},
+		Env:      []string{"CONSUL_LOCAL_CONFIG=" + string(localJSON)},
+		Cmd:      []string{"consul", "agent", "-server", "-data-dir=/consul/data", "-bind=" + p.Driver.GetBaseDriver().IPAddress, "-client=" + p.Driver.GetBaseDriver().IPAddress, "-config-dir=/consul/config"},
 		Volumes:  vols,
 	}, &container.HostConfig{
 		Binds:       []string{"/opt/consul:/consul/data", "/opt/consul-ssl/:/consul/ssl"},
@@ -220,31 +276,29 @@ func (p *Partikle) RunContainer(imageName string, containerName string, volumes
 	return dockerClient.ContainerStart(context.Background(), body.ID, types.ContainerStartOptions{})
 }
 
-func (p *Partikle) ConnectToConsul() (*consulAPI.Client, error) {
-	consulConfig := consulAPI.DefaultConfig()
-	consulConfig.Address = p.Driver.GetBaseDriver().IPAddress + ":8081"
-	consulConfig.Scheme = "https"
-
-	consulConfig.TLSConfig = consulAPI.TLSConfig{
-		Address:            p.Driver.GetBaseDriver().IPAddress + ":8081",
-		CAFile:             path.Join(p.Galaksy.ConsulConfPath(), "ca.cert"),
-		CertFile:           path.Join(p.Galaksy.ConsulConfPath(), "cert.pem"),
-		KeyFile:            path.Join(p.Galaksy.ConsulConfPath(), "key.pem"),
-		InsecureSkipVerify: true,
+func (p *Partikle) RestartContainer(containerName string) error {
+	dockerClient, err := p.NewDockerClient()
+	if err != nil {
+		return err
 	}
 
-	client, err := consulAPI.NewClient(consulConfig)
+	timeout := 30 * time.Second
+	return dockerClient.ContainerRestart(context.Background(), containerName, &timeout)
+}
+
+func (p *Partikle) ConnectToConsul() (*consulAPI.Client, error) {
+	client, err := p.NewConsulClient()
 	if err != nil {
 		return nil, err
 	}
 
-	// Trick to check if the connection is ready
-	_, _, err = client.KV().List("", nil)
+	// Consul is ready once a leader is elected, the status endpoints need no ACL token
+	leader, err := client.Status().Leader()
 	retries := 0
 
-	for err != nil && retries < 10 {
+	for (err != nil || leader == "") && retries < 10 {
 		time.Sleep(5 * time.Second)
-		_, _, err = client.KV().List("", nil)
+		leader, err = client.Status().Leader()
 		retries++
 	}
 
@@ -255,6 +309,35 @@ func (p *Partikle) ConnectToConsul() (*consulAPI.Client, error) {
 	return client, nil
 }
 
+// NewConsulClient returns a Consul client without waiting for Consul to be ready
+func (p *Partikle) NewConsulClient() (*consulAPI.Client, error) {
+	return p.newConsulClient(p.Galaksy.consulToken())
+}
+
+func (p *Partikle) newConsulClient(token string) (*consulAPI.Client, error) {
+	consulConfig := consulAPI.DefaultConfig()
+	consulConfig.Address = p.Driver.GetBaseDriver().IPAddress + ":8081"
+	consulConfig.Scheme = "https"
+	consulConfig.Token = token
+
+	consulConfig.TLSConfig = consulAPI.TLSConfig{
+		Address:            p.Driver.GetBaseDriver().IPAddress + ":8081",
+		CAFile:             path.Join(p.Galaksy.ConsulConfPath(), "ca.cert"),
+		CertFile:           existingOr(p.Galaksy.ConsulCLICertPath(), path.Join(p.Galaksy.ConsulConfPath(), "cert.pem")),
+		KeyFile:            existingOr(p.Galaksy.ConsulCLIKeyPath(), path.Join(p.Galaksy.ConsulConfPath(), "key.pem")),
+		InsecureSkipVerify: false,
+	}
+
+	if len(p.Driver.GetBaseDriver().JumpHosts) != 0 {
+		// The pooled transport of DefaultConfig dials with DialContext, which takes precedence over Dial
+		consulConfig.Transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return p.Driver.Dial(network, address)
+		}
+	}
+
+	return consulAPI.NewClient(consulConfig)
+}
+
 func (p *Partikle) UploadFile(source string, destination string) error {
 	return p.Driver.CopyFile(source, destination)
 }
@@ -263,6 +346,29 @@ func (p *Partikle) DetectDocker() (bool, error) {
 	return p.Provider.DetectDocker()
 }
 
+// NodeSecretsDir holds the secrets delivered to the partikles, readable by root only
+const NodeSecretsDir = "/etc/mikrodock/secrets"
+
+func NodeSecretPath(name string) string {
+	return path.Join(NodeSecretsDir, name)
+}
+
+// UploadSecret writes value to NodeSecretPath(name) with 0600 permissions, without passing it on a command line
+func (p *Partikle) UploadSecret(name string, value string) error {
+	if _, errOut, err := p.Driver.SSHCommand("install -d -m 0700 -o root -g root " + NodeSecretsDir); err != nil {
+		return fmt.Errorf("Cannot create %s : %s %s", NodeSecretsDir, err, errOut)
+	}
+	if err := p.Driver.Copy(int64(len(value)), 0600, name, strings.NewReader(value), NodeSecretsDir, nil); err != nil {
+		return err
+	}
+	// scp keeps the mode of an existing file
+	_, errOut, err := p.Driver.SSHCommand("chown root:root " + NodeSecretPath(name) + " && chmod 0600 " + NodeSecretPath(name))
+	if err != nil {
+		return fmt.Errorf("Cannot protect %s : %s %s", NodeSecretPath(name), err, errOut)
+	}
+	return nil
+}
+
 func (p *Partikle) ConfigureEnv(envs map[string]string) {
 	for key, value := range envs {
 		p.Driver.SSHCommand(fmt.Sprintf("echo 'export %s=%s' >> ~/.env", key, value))
@@ -282,29 +388,50 @@ func (p *Partikle) StartDocker() error {
 	return p.Provider.StartDocker()
 }
 
+// ConfigureDocker writes the Docker options of the partikle and restarts Docker. Once a certificate has been
+// revoked, Docker keeps listening on localhost and the TLS guard is started if it does not run yet
 func (p *Partikle) ConfigureDocker(d *DockerClusterOptions) error {
 
 	if err := p.StopDocker(); err != nil {
 		return err
 	}
 
+	tlsGuard := p.Galaksy.HasCRL()
 	if d != nil {
-		if err := p.Provider.ConfigureDocker(d.String()); err != nil {
+		if err := p.Provider.ConfigureDocker(d.String(), tlsGuard); err != nil {
 			return err
 		}
 	} else {
-		if err := p.Provider.ConfigureDocker(""); err != nil {
+		if err := p.Provider.ConfigureDocker("", tlsGuard); err != nil {
 			return err
 		}
 	}
 
-	return p.StartDocker()
+	if err := p.StartDocker(); err != nil {
+		return err
+	}
+	if tlsGuard {
+		return p.UploadCRL()
+	}
+	return nil
 }
 
 func (p *Partikle) NewDockerClient() (*client.Client, error) {
 	return p.Provider.GetBaseProvider().ConnectDocker(p.CertsPath(), p.Galaksy.DockerConfigPath())
 }
 
+// HTTPClient returns a plain HTTP client reaching the partikle, through SSH when jump hosts are used
+func (p *Partikle) HTTPClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{}
+	if len(p.Driver.GetBaseDriver().JumpHosts) != 0 {
+		transport.Dial = p.Driver.Dial
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+}
+
 func (p *Partikle) Mkdir(path string) error {
 	return p.Provider.CreateDirectory(path)
 }
@@ -345,6 +472,7 @@ func (p *Partikle) Save() error {
 		buffer.WriteString(p.Driver.GetBaseDriver().SSHUser + "\n")
 		buffer.WriteString(strconv.Itoa(p.Driver.GetBaseDriver().MachineID) + "\n")
 		buffer.WriteString(strconv.FormatBool(p.IsMaster) + "\n")
+		buffer.WriteString(mssh.FormatJumpHosts(p.JumpHosts) + "\n")
 
 		file.Write(buffer.Bytes())
 	}
@@ -380,18 +508,38 @@ func LoadPartikle(Gal *Cluster, Name string) (*Partikle, error) {
 		base.SSHUser = scanner.Text()
 		scanner.Scan()
 		base.MachineID, _ = strconv.Atoi(scanner.Text())
+		scanner.Scan()
+		isMaster, _ := strconv.ParseBool(scanner.Text())
+		scanner.Scan()
+		jumpHosts, err := mssh.ParseJumpHosts(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+
+		base.JumpHosts = Gal.JumpHosts
+		if len(jumpHosts) != 0 {
+			base.JumpHosts = jumpHosts
+		}
 
 		driver.SetBaseDriver(base)
 
-		fmt.Printf("%#v\n", driver)
+		logger.Debug("Partikle.Load", "Loaded "+base.MachineName+" ("+base.IPAddress+")")
 
 		provider := getProvider(driver)
 
 		part = NewPartikle(driver, provider, Gal)
-		scanner.Scan()
-		part.IsMaster, _ = strconv.ParseBool(scanner.Text())
+		part.IsMaster = isMaster
+		part.JumpHosts = jumpHosts
 
 	}
 
 	return part, err
 }
+
+// existingOr returns file if it exists, fallback otherwise
+func existingOr(file string, fallback string) string {
+	if _, err := os.Stat(file); err == nil {
+		return file
+	}
+	return fallback
+}
@@ -0,0 +1,359 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mikrodock-cli/iptables"
+	"mikrodock-cli/logger"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortsPrefix is the Consul KV tree of the published ports : mikrodock/ports/<published>-<protocol>
+const PortsPrefix = "mikrodock/ports"
+
+// The script and the unit applying the published ports whenever Docker starts, Docker recreating its nat chains
+const (
+	portsScriptDir  = "/etc/mikrodock"
+	portsScriptName = "ports.sh"
+	portsUnit       = "mikrodock-ports.service"
+)
+
+const portsUnitContent = `[Unit]
+Description=Ports published by mikrodock
+After=docker.service
+Requires=docker.service
+PartOf=docker.service
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/bin/sh ` + portsScriptDir + "/" + portsScriptName + `
+
+[Install]
+WantedBy=docker.service
+`
+
+// PortBinding is a container of a published port and its address on docker_gwbridge
+type PortBinding struct {
+	Partikle    string
+	ContainerID string
+	ContainerIP string
+}
+
+// PublishedPort is a port of the hosts published on a port of the instances of a service
+type PublishedPort struct {
+	Stack    string
+	Service  string
+	Mapping  iptables.PortMapping
+	Bindings []PortBinding
+}
+
+func portKey(m iptables.PortMapping) string {
+	return PortsPrefix + "/" + strconv.Itoa(m.Published) + "-" + m.Protocol
+}
+
+// dockerGwbridge is the bridge of the containers of the overlays, Docker NATs the published ports to it
+const dockerGwbridge = "docker_gwbridge"
+
+// containerIP returns the address of a container on a network
+func (p *Partikle) containerIP(containerID string, networkName string) (string, error) {
+	client, err := p.NewDockerClient()
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	container, err := client.ContainerInspect(context.Background(), containerID)
+	if err != nil {
+		return "", fmt.Errorf("Cannot inspect %s on %s : %s", containerID, p.Name(), err)
+	}
+	if container.NetworkSettings != nil {
+		if endpoint, ok := container.NetworkSettings.Networks[networkName]; ok && endpoint.IPAddress != "" {
+			return endpoint.IPAddress, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not attached to %s on %s", containerID, networkName, p.Name())
+}
+
+// PublishedPorts returns every published port, by published port
+func (c *Cluster) PublishedPorts() ([]PublishedPort, error) {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := helper.Keys(PortsPrefix + "/")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list the published ports : %s", err)
+	}
+	ports := make([]PublishedPort, 0, len(keys))
+	for _, key := range keys {
+		content, err := helper.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		var port PublishedPort
+		if err = json.Unmarshal(content, &port); err != nil {
+			return nil, fmt.Errorf("Cannot read %s : %s", key, err)
+		}
+		ports = append(ports, port)
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Mapping.Published < ports[j].Mapping.Published ||
+			ports[i].Mapping.Published == ports[j].Mapping.Published && ports[i].Mapping.Protocol < ports[j].Mapping.Protocol
+	})
+	return ports, nil
+}
+
+func (c *Cluster) publishedPort(m iptables.PortMapping) (*PublishedPort, error) {
+	ports, err := c.PublishedPorts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ports {
+		if ports[i].Mapping.Published == m.Published && ports[i].Mapping.Protocol == m.Protocol {
+			return &ports[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *Cluster) savePublishedPort(port PublishedPort) error {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(port)
+	if err != nil {
+		return err
+	}
+	tree := helper.NewTree(PortsPrefix)
+	tree.AddChild(strings.TrimPrefix(portKey(port.Mapping), PortsPrefix+"/"), content)
+	if err = helper.SendTree(tree); err != nil {
+		return fmt.Errorf("Cannot record %s : %s", port.Mapping, err)
+	}
+	return nil
+}
+
+func hasBinding(bindings []PortBinding, binding PortBinding) bool {
+	for _, b := range bindings {
+		if b == binding {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAddress(bindings []PortBinding, binding PortBinding) bool {
+	for _, b := range bindings {
+		if b.Partikle == binding.Partikle && b.ContainerIP == binding.ContainerIP {
+			return true
+		}
+	}
+	return false
+}
+
+// unlinkBinding removes the rules of a binding, for its recorded address and the current one of its container
+func (c *Cluster) unlinkBinding(binding PortBinding, m iptables.PortMapping) error {
+	p := c.PartikleByName(binding.Partikle)
+	if p == nil {
+		logger.Warn("Cluster.Ports", "Cannot find "+binding.Partikle+", its rules are kept")
+		return nil
+	}
+	rules := m.Rules(binding.ContainerIP)
+	if ip, err := p.containerIP(binding.ContainerID, dockerGwbridge); err == nil && ip != binding.ContainerIP {
+		rules = append(rules, m.Rules(ip)...)
+	}
+	if err := iptables.Delete(p.Driver, rules); err != nil {
+		return fmt.Errorf("Cannot unpublish %s on %s : %s", m, p.Name(), err)
+	}
+	return nil
+}
+
+// PublishPort publishes the port on the partikles running the instances of the service and records it,
+// publishing it again updates the rules to the current instances
+func (c *Cluster) PublishPort(stack string, service string, m iptables.PortMapping) (*PublishedPort, error) {
+	existing, err := c.publishedPort(m)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && (existing.Stack != stack || existing.Service != service) {
+		return nil, fmt.Errorf("%d/%s is already published for %s/%s", m.Published, m.Protocol, existing.Stack, existing.Service)
+	}
+	if existing != nil && existing.Mapping.Target != m.Target {
+		return nil, fmt.Errorf("%d/%s is published on %d, unpublish it first", m.Published, m.Protocol, existing.Mapping.Target)
+	}
+	srv, err := c.KinetikService(stack, service)
+	if err != nil {
+		return nil, err
+	}
+	if len(srv.Instances) == 0 {
+		return nil, fmt.Errorf("The service %s of the stack %s has no instance", service, stack)
+	}
+
+	published := PublishedPort{Stack: stack, Service: service, Mapping: m}
+	// The bindings already recorded keep their rules
+	rollback := func() {
+		for _, binding := range published.Bindings {
+			if existing != nil && hasBinding(existing.Bindings, binding) {
+				continue
+			}
+			if err := c.unlinkBinding(binding, m); err != nil {
+				logger.Warn("Cluster.Ports", err.Error())
+			}
+		}
+	}
+	for _, instance := range srv.Instances {
+		p := c.PartikleByIP(instance.NodeID)
+		if p == nil {
+			rollback()
+			return nil, fmt.Errorf("Cannot find the partikle of %s", instance.NodeID)
+		}
+		ip, err := p.containerIP(instance.ContainerID, dockerGwbridge)
+		if err == nil {
+			err = iptables.Apply(p.Driver, m.Rules(ip))
+		}
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("Cannot publish %s on %s : %s", m, p.Name(), err)
+		}
+		published.Bindings = append(published.Bindings, PortBinding{Partikle: p.Name(), ContainerID: instance.ContainerID, ContainerIP: ip})
+	}
+
+	// Instances gone since the last publication
+	affected := make(map[string]bool)
+	if existing != nil {
+		for _, old := range existing.Bindings {
+			affected[old.Partikle] = true
+			if hasBinding(published.Bindings, old) {
+				continue
+			}
+			// Only the recorded address, the current one of the container may be published again
+			if p := c.PartikleByName(old.Partikle); p != nil && !hasAddress(published.Bindings, old) {
+				if err := iptables.Delete(p.Driver, m.Rules(old.ContainerIP)); err != nil {
+					logger.Warn("Cluster.Ports", "Cannot remove the rules of "+old.ContainerID+" : "+err.Error())
+				}
+			}
+		}
+	}
+
+	if err = c.savePublishedPort(published); err != nil {
+		rollback()
+		return nil, err
+	}
+	for _, binding := range published.Bindings {
+		affected[binding.Partikle] = true
+	}
+	return &published, c.installPortsScripts(affected)
+}
+
+// UnpublishPort removes the rules of a published port and its record
+func (c *Cluster) UnpublishPort(stack string, service string, m iptables.PortMapping) error {
+	existing, err := c.publishedPort(m)
+	if err != nil {
+		return err
+	}
+	if existing == nil || existing.Stack != stack || existing.Service != service {
+		return fmt.Errorf("%d/%s is not published for %s/%s", m.Published, m.Protocol, stack, service)
+	}
+	if existing.Mapping.Target != m.Target {
+		return fmt.Errorf("%d/%s is published on %d, not %d", m.Published, m.Protocol, existing.Mapping.Target, m.Target)
+	}
+
+	affected := make(map[string]bool)
+	for _, binding := range existing.Bindings {
+		if err = c.unlinkBinding(binding, existing.Mapping); err != nil {
+			return err
+		}
+		affected[binding.Partikle] = true
+	}
+
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return err
+	}
+	if err = helper.Delete(portKey(m), false); err != nil {
+		return fmt.Errorf("Cannot remove the record of %s : %s", m, err)
+	}
+	return c.installPortsScripts(affected)
+}
+
+// PortApplied tells how many bindings of the port have all their rules on their partikle
+func (c *Cluster) PortApplied(port PublishedPort) int {
+	applied := 0
+	for _, binding := range port.Bindings {
+		p := c.PartikleByName(binding.Partikle)
+		if p == nil {
+			continue
+		}
+		present := true
+		for _, rule := range port.Mapping.Rules(binding.ContainerIP) {
+			ok, err := iptables.Check(p.Driver, rule)
+			if err != nil {
+				logger.Debug("Cluster.Ports", err.Error())
+			}
+			present = present && ok
+		}
+		if present {
+			applied++
+		}
+	}
+	return applied
+}
+
+// installPortsScripts writes the boot script of the partikles from the recorded ports
+func (c *Cluster) installPortsScripts(partikles map[string]bool) error {
+	ports, err := c.PublishedPorts()
+	if err != nil {
+		return err
+	}
+	for name := range partikles {
+		p := c.PartikleByName(name)
+		if p == nil {
+			continue
+		}
+		var containerPorts []iptables.ContainerPort
+		for _, port := range ports {
+			for _, binding := range port.Bindings {
+				if binding.Partikle == name {
+					containerPorts = append(containerPorts, iptables.ContainerPort{ContainerID: binding.ContainerID, Mapping: port.Mapping})
+				}
+			}
+		}
+		if err = p.installPortsScript(containerPorts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Partikle) installPortsScript(ports []iptables.ContainerPort) error {
+	script, err := iptables.BootScript(ports)
+	if err != nil {
+		return err
+	}
+	if _, errOut, err := p.Driver.SSHCommand("mkdir -p " + portsScriptDir); err != nil {
+		return fmt.Errorf("Cannot create %s on %s : %s %s", portsScriptDir, p.Name(), err, errOut)
+	}
+	if err = p.Driver.Copy(int64(len(script)), 0700, portsScriptName, strings.NewReader(script), portsScriptDir, nil); err != nil {
+		return fmt.Errorf("Cannot upload the ports script to %s : %s", p.Name(), err)
+	}
+	if err = p.Driver.Copy(int64(len(portsUnitContent)), 0644, portsUnit, strings.NewReader(portsUnitContent), "/etc/systemd/system", nil); err != nil {
+		return fmt.Errorf("Cannot upload %s to %s : %s", portsUnit, p.Name(), err)
+	}
+	// reenable drops the multi-user.target link of the units installed before
+	if _, errOut, err := p.Driver.SSHCommand("systemctl daemon-reload && systemctl reenable " + portsUnit); err != nil {
+		return fmt.Errorf("Cannot enable %s on %s : %s %s", portsUnit, p.Name(), err, errOut)
+	}
+	return nil
+}
+
+// reapplyPorts runs the ports script again, the rules of the published ports are lost when Docker restarts
+func (p *Partikle) reapplyPorts() error {
+	script := portsScriptDir + "/" + portsScriptName
+	if _, errOut, err := p.Driver.SSHCommand("[ ! -f " + script + " ] || /bin/sh " + script); err != nil {
+		return fmt.Errorf("Cannot apply the published ports on %s : %s %s", p.Name(), err, errOut)
+	}
+	return nil
+}
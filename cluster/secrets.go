@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"mikrodock-cli/utils/secrets"
+)
+
+// secretNames lists the secrets stored by the provider of the cluster
+func (c *Cluster) secretNames() []string {
+	names := []string{secrets.AccessToken}
+	// The other secrets only exist once the feature using them has been set up
+	for _, name := range append(consulSecretNames(), StackSecretsKey) {
+		if _, err := c.Secrets.Get(name); err == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// MigrateSecrets moves every secret of the cluster to another provider, then saves the cluster with it
+func (c *Cluster) MigrateSecrets(to secrets.Provider) error {
+	from := c.Secrets
+	if err := secrets.Migrate(from, to, c.secretNames()); err != nil {
+		return err
+	}
+	c.Secrets = to
+	c.Save()
+
+	if from.Ref() != to.Ref() {
+		for _, name := range c.secretNames() {
+			from.Delete(name)
+		}
+	}
+	return nil
+}
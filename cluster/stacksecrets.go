@@ -0,0 +1,243 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/compose"
+	consulhelpers "mikrodock-cli/utils/consul-helpers"
+	"mikrodock-cli/utils/secrets"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// StackSecretsPrefix is the Consul KV tree holding the sealed secrets and configs of the stacks :
+// mikrodock/secrets/<stack>/<secrets|configs>/<name>
+const StackSecretsPrefix = "mikrodock/secrets"
+
+// StackSecretsKey is the secret of the provider sealing the values stored under StackSecretsPrefix and the ingress keys
+const StackSecretsKey = "stack-secrets-key"
+
+// StackSecretsKeyFile is the node secret of the konduktor holding StackSecretsKey, kinetik unseals the values with it
+const StackSecretsKeyFile = "STACK_SECRETS_KEY"
+
+var stackSecretName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// StackSecret references a sealed secret or config of a stack in Consul KV
+type StackSecret struct {
+	Stack string
+	Kind  string
+	Name  string
+	Key   string
+}
+
+func newStackSecret(stack string, kind string, name string) (StackSecret, error) {
+	if kind != compose.SecretKind && kind != compose.ConfigKind {
+		return StackSecret{}, fmt.Errorf("Unknown kind %s, expected %s or %s", kind, compose.SecretKind, compose.ConfigKind)
+	}
+	for _, part := range []string{stack, name} {
+		if !stackSecretName.MatchString(part) {
+			return StackSecret{}, fmt.Errorf("Invalid name %s : only letters, digits, '.', '-' and '_' are allowed", part)
+		}
+	}
+	return StackSecret{Stack: stack, Kind: kind, Name: name, Key: path.Join(StackSecretsPrefix, stack, kind, name)}, nil
+}
+
+// sealKey returns the key sealing the stack secrets, it is generated on first use and delivered to the konduktor
+func (c *Cluster) sealKey(helper *consulhelpers.ConsulHelper) (string, error) {
+	key, err := c.Secrets.Get(StackSecretsKey)
+	if err == nil && key != "" {
+		return key, nil
+	}
+	// Never replace the key of values already sealed, the ingress keys included
+	for _, prefix := range []string{StackSecretsPrefix + "/", IngressPrefix + "/certs/", IngressPrefix + "/acme/"} {
+		existing, kerr := helper.Keys(prefix)
+		if kerr != nil {
+			return "", fmt.Errorf("Cannot list the sealed values of %s : %s", prefix, kerr)
+		}
+		if len(existing) != 0 && err != nil {
+			return "", fmt.Errorf("Cannot read the stack secrets key : %s", err)
+		}
+		if len(existing) != 0 {
+			return "", errors.New("The stack secrets key is empty while values are sealed with it")
+		}
+	}
+
+	logger.Info("Cluster.StackSecrets", "Generating the stack secrets key")
+	if key, err = secrets.GenerateSealKey(); err != nil {
+		return "", err
+	}
+	if err = c.Secrets.Set(StackSecretsKey, key); err != nil {
+		return "", fmt.Errorf("Cannot store the stack secrets key : %s", err)
+	}
+	return key, c.deliverSealKey(key)
+}
+
+func (c *Cluster) deliverSealKey(key string) error {
+	konduktor := c.PartikleByName("konduktor")
+	if konduktor == nil {
+		return errors.New("The cluster has no konduktor")
+	}
+	if err := konduktor.UploadSecret(StackSecretsKeyFile, key); err != nil {
+		return fmt.Errorf("Cannot deliver the stack secrets key : %s", err)
+	}
+	return nil
+}
+
+// StoreStackSecret seals value and stores it as the secret or config name of stack
+func (c *Cluster) StoreStackSecret(stack string, kind string, name string, value []byte) (*StackSecret, error) {
+	secret, err := newStackSecret(stack, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	key, err := c.sealKey(helper)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := secrets.Seal(key, value)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot seal %s : %s", name, err)
+	}
+
+	tree := helper.NewTree(StackSecretsPrefix)
+	tree.AddSubCategory(stack).AddSubCategory(kind).AddChild(name, sealed)
+	if err = helper.SendTree(tree); err != nil {
+		return nil, fmt.Errorf("Cannot store %s : %s", name, err)
+	}
+	return &secret, nil
+}
+
+// DeployStackSecrets stores the secrets and the configs of a compose file before its deployment.
+// Local files and environment variables are sealed and sent, external ones must already exist
+func (c *Cluster) DeployStackSecrets(stack string, composeFile string) ([]StackSecret, error) {
+	refs, err := compose.ReadFileRefs(composeFile)
+	if err != nil {
+		return nil, err
+	}
+	deployed := make([]StackSecret, 0, len(refs))
+	if len(refs) == 0 {
+		return deployed, nil
+	}
+
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	key, err := c.sealKey(helper)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := helper.NewTree(StackSecretsPrefix)
+	var stackNode *consulhelpers.KVNode
+	kinds := make(map[string]*consulhelpers.KVNode)
+	for _, ref := range refs {
+		if ref.External {
+			secret, err := newStackSecret(stack, ref.Kind, ref.ExternalName)
+			if err != nil {
+				return nil, err
+			}
+			value, err := helper.Get(secret.Key)
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				return nil, fmt.Errorf("External %s %s not found, create it with secret create", ref.Kind, ref.ExternalName)
+			}
+			// Kinetik mounts it under the name used by the compose file
+			secret.Name = ref.Name
+			deployed = append(deployed, secret)
+			continue
+		}
+
+		secret, err := newStackSecret(stack, ref.Kind, ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := ref.Read()
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read %s %s : %s", ref.Kind, ref.Name, err)
+		}
+		sealed, err := secrets.Seal(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot seal %s : %s", ref.Name, err)
+		}
+		if stackNode == nil {
+			stackNode = tree.AddSubCategory(stack)
+		}
+		if kinds[ref.Kind] == nil {
+			kinds[ref.Kind] = stackNode.AddSubCategory(ref.Kind)
+		}
+		kinds[ref.Kind].AddChild(ref.Name, sealed)
+		deployed = append(deployed, secret)
+	}
+
+	if stackNode != nil {
+		if err = helper.SendTree(tree); err != nil {
+			return nil, fmt.Errorf("Cannot store the secrets of %s : %s", stack, err)
+		}
+	}
+	// The konduktor may have been replaced since the key was generated
+	if err = c.deliverSealKey(key); err != nil {
+		return nil, err
+	}
+	return deployed, nil
+}
+
+// StackSecrets lists the secrets and configs stored for stack, or for every stack when stack is empty
+func (c *Cluster) StackSecrets(stack string) ([]StackSecret, error) {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return nil, err
+	}
+	prefix := StackSecretsPrefix + "/"
+	if stack != "" {
+		prefix += stack + "/"
+	}
+	keys, err := helper.Keys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]StackSecret, 0, len(keys))
+	for _, key := range keys {
+		parts := strings.Split(strings.TrimPrefix(key, StackSecretsPrefix+"/"), "/")
+		if len(parts) != 3 || parts[2] == "" {
+			continue
+		}
+		list = append(list, StackSecret{Stack: parts[0], Kind: parts[1], Name: parts[2], Key: key})
+	}
+	return list, nil
+}
+
+// RemoveStackSecret deletes a secret or config of stack, or every one of them when name is empty
+func (c *Cluster) RemoveStackSecret(stack string, kind string, name string) error {
+	helper, err := c.ConsulHelper()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		if !stackSecretName.MatchString(stack) {
+			return fmt.Errorf("Invalid stack name %s", stack)
+		}
+		return helper.Delete(path.Join(StackSecretsPrefix, stack)+"/", true)
+	}
+
+	secret, err := newStackSecret(stack, kind, name)
+	if err != nil {
+		return err
+	}
+	value, err := helper.Get(secret.Key)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return fmt.Errorf("%s %s not found in stack %s", kind, name, stack)
+	}
+	return helper.Delete(secret.Key, false)
+}
@@ -20,7 +20,16 @@ func generateMinimalFiles(c *Cluster) {
 
 	logger.Info("ClusterInit", "Generating SSH keys")
 	privateKeyPath := path.Join(c.SSHPath(), "private_key")
-	if err := mssh.CreatePrivateKey(privateKeyPath); err != nil {
+	var passphrase []byte
+	if c.EncryptSSHKey {
+		var err error
+		passphrase, err = mssh.ReadPassphrase(privateKeyPath)
+		if err != nil {
+			logger.Fatal("ClusterInit", err.Error())
+		}
+		logger.Warn("ClusterInit", "The konduktor will receive the encrypted key and needs the passphrase to use it")
+	}
+	if err := mssh.CreateKey(privateKeyPath, c.SSHKeyType, passphrase); err != nil {
 		logger.Fatal("ClusterInit", err.Error())
 	}
 }
@@ -98,15 +107,36 @@ func makeConsulCerts(c *Cluster, driver drivers.Driver) {
 }
 
 func makeCA(c *Cluster) {
-	certGen := certs.NewX509CertGenerator()
+	certGen, err := c.caGenerator(DockerCA)
+	if err != nil {
+		logger.Fatal("ClusterInit.Security", err.Error())
+	}
 
-	err := certGen.GenerateCACert(path.Join(c.DockerConfigPath(), "ca.cert"), path.Join(c.DockerConfigPath(), "ca.key"), "Mikrodock-CA", 2048)
+	err = certGen.GenerateCACert(path.Join(c.DockerConfigPath(), "ca.cert"), path.Join(c.DockerConfigPath(), "ca.key"), "Mikrodock-CA", 2048)
 
 	if err != nil {
 		logger.Fatal("ClusterInit.Security", "Cannot generate CA Certs : "+err.Error())
 	}
 }
 
+// makeConsulCA creates the Consul CA, once for the whole cluster, and the client certificate of the CLI
+func makeConsulCA(c *Cluster) {
+	certGen, err := c.caGenerator(ConsulCA)
+	if err != nil {
+		logger.Fatal("ClusterInit.Security", err.Error())
+	}
+
+	err = certGen.GenerateCACert(path.Join(c.ConsulConfPath(), "ca.cert"), path.Join(c.ConsulConfPath(), "ca.key"), "Mikrodock-Consul-CA", 2048)
+
+	if err != nil {
+		logger.Fatal("ClusterInit.Security", "Cannot generate Consul CA Certs : "+err.Error())
+	}
+
+	if err = c.GenerateConsulCLICert(); err != nil {
+		logger.Fatal("ClusterInit.Security", "Cannot generate Consul CLI Certs : "+err.Error())
+	}
+}
+
 func makeCerts(c *Cluster, driver drivers.Driver) {
 
 	pDir := c.PartiklePath(driver.GetBaseDriver().MachineName)
@@ -198,7 +228,7 @@ func configureDocker(provider provision.Provider, extraConf string) {
 		logger.Fatal("ClusterInit.Konsultant.Docker", "Cannot stop docker : "+err.Error())
 	}
 
-	err = provider.ConfigureDocker(extraConf)
+	err = provider.ConfigureDocker(extraConf, false)
 	if err != nil {
 		logger.Fatal("ClusterInit.Konsultant.Docker", "Cannot configure docker : "+err.Error())
 	}
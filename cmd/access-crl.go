@@ -0,0 +1,59 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// accessCRLCmd represents the access crl command
+var accessCRLCmd = &cobra.Command{
+	Use:   "crl",
+	Short: "Publish the CRL again on every node",
+	Long: `Sign the CRL again and publish it on every node. A CRL from Vault expires quickly
+(72h by default) and must be published again before that, or every connection is refused.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		if !c.HasCRL() {
+			logger.Info("Access.CRL", "No certificate has been revoked, nothing to publish")
+			return
+		}
+		if err = c.PublishCRL(); err != nil {
+			logger.Fatal("Access.CRL", err.Error())
+		}
+		logger.Info("Access.CRL", "CRL published")
+	},
+}
+
+func init() {
+	accessCmd.AddCommand(accessCRLCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// accessCRLCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// accessCRLCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,76 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var accessTTL string
+var accessOutput string
+
+// accessGrantCmd represents the access grant command
+var accessGrantCmd = &cobra.Command{
+	Use:   "grant",
+	Short: "Issue a client certificate for a user and export it as a bundle",
+	Long: `Issue a client only certificate for a user from the Docker CA of the cluster.
+The bundle is a tar.gz holding ca.pem, cert.pem and key.pem : extract it in a directory
+and point DOCKER_CERT_PATH to it. Its nodes file lists the Docker endpoint of every node.`,
+	Args: cobra.ExactArgs(2), // cluster name, user
+	Run: func(cmd *cobra.Command, args []string) {
+		ttl, err := utils.ParseDuration(accessTTL)
+		if err != nil {
+			logger.Fatal("Access.Grant", "Invalid TTL : "+err.Error())
+		}
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		info, err := c.GrantAccess(args[1], ttl)
+		if err != nil {
+			logger.Fatal("Access.Grant", err.Error())
+		}
+
+		output := accessOutput
+		if output == "" {
+			output = args[1] + "-" + args[0] + ".tar.gz"
+		}
+		if err = c.ExportAccess(args[1], output); err != nil {
+			logger.Fatal("Access.Grant", "Cannot export bundle : "+err.Error())
+		}
+		logger.Info("Access.Grant", "Access granted to "+info.User+" until "+info.NotAfter.Format("2006-01-02")+", bundle written to "+output)
+	},
+}
+
+func init() {
+	accessCmd.AddCommand(accessGrantCmd)
+
+	accessGrantCmd.Flags().StringVar(&accessTTL, "ttl", "30d", "Validity of the certificate (e.g. 30d, 12h)")
+	accessGrantCmd.Flags().StringVarP(&accessOutput, "output", "o", "", "Bundle file (default <user>-<cluster>.tar.gz)")
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// accessGrantCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// accessGrantCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,75 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// accessListCmd represents the access list command
+var accessListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the users having been granted a client certificate",
+	Long:  ``,
+	Args:  cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		infos, err := c.Accesses()
+		if err != nil {
+			logger.Fatal("Access.List", err.Error())
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"User", "Serial", "Expires", "Status"})
+		table.AppendBulk(accessTable(infos))
+		table.Render()
+	},
+}
+
+func init() {
+	accessCmd.AddCommand(accessListCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// accessListCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// accessListCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+func accessTable(infos []cluster.AccessInfo) [][]string {
+	tContent := make([][]string, len(infos))
+	for i, info := range infos {
+		status := "active"
+		if info.Revoked {
+			status = "revoked"
+		} else if time.Now().After(info.NotAfter) {
+			status = "expired"
+		}
+		tContent[i] = []string{info.User, info.Serial, info.NotAfter.Format("2006-01-02"), status}
+	}
+	return tContent
+}
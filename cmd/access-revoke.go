@@ -0,0 +1,56 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// accessRevokeCmd represents the access revoke command
+var accessRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke the client certificate of a user on every node",
+	Long: `Revoke the client certificate of a user and publish the CRL on every node.
+The first revocation puts a TLS guard checking the CRL in front of the Docker daemon,
+which restarts Docker on every node.`,
+	Args: cobra.ExactArgs(2), // cluster name, user
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		if err = c.RevokeAccess(args[1]); err != nil {
+			logger.Fatal("Access.Revoke", err.Error())
+		}
+		logger.Info("Access.Revoke", "Access of "+args[1]+" revoked")
+	},
+}
+
+func init() {
+	accessCmd.AddCommand(accessRevokeCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// accessRevokeCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// accessRevokeCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,106 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/audit"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var auditNode string
+var auditSince time.Duration
+var auditFailed bool
+var auditJSON bool
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the remote commands executed on the nodes of a cluster",
+	Long:  `Every SSH command and file copy done on a node is appended to the audit log of the cluster.`,
+	Args:  cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		filter := audit.Filter{
+			Node:       auditNode,
+			OnlyFailed: auditFailed,
+		}
+		if auditSince != 0 {
+			filter.Since = time.Now().Add(-auditSince)
+		}
+
+		entries, err := c.Audit.Read(filter)
+		if err != nil {
+			logger.Fatal("Audit.Read", err.Error())
+		}
+
+		if auditJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, entry := range entries {
+				encoder.Encode(entry)
+			}
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Time", "User", "Node", "Action", "Command", "Status", "Duration"})
+		table.AppendBulk(auditTable(entries))
+		table.Render()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// auditCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	auditCmd.Flags().StringVar(&auditNode, "node", "", "Only show the entries of this node (name or IP)")
+	auditCmd.Flags().DurationVar(&auditSince, "since", 0, "Only show the entries newer than this duration (e.g. 2h)")
+	auditCmd.Flags().BoolVar(&auditFailed, "failed", false, "Only show the failed commands")
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false, "Print the entries as JSON lines")
+}
+
+func auditTable(entries []audit.Entry) [][]string {
+	tContent := make([][]string, len(entries))
+	for i, entry := range entries {
+		tLine := make([]string, 7)
+		tLine[0] = entry.Time.Format(time.RFC3339)
+		tLine[1] = entry.User
+		tLine[2] = entry.Node
+		tLine[3] = entry.Action
+		tLine[4] = entry.Command
+		tLine[5] = strconv.Itoa(entry.ExitStatus)
+		tLine[6] = (time.Duration(entry.DurationMs) * time.Millisecond).String()
+		tContent[i] = tLine
+	}
+	return tContent
+}
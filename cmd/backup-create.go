@@ -0,0 +1,119 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var backupKeep int
+var backupSchedule string
+
+// backupCreateCmd represents the backup create command
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Take a Consul snapshot of a cluster",
+	Long: `Take a Consul snapshot of a cluster, named <cluster>-<UTC timestamp>.snap.
+--keep removes the oldest snapshots of the directory.
+--schedule "<cron spec>" installs the same command in the crontab of the current user instead of running it,
+--schedule none removes it. Scheduled backups cannot prompt : use a keyring unlocked at login or the env secret provider.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		dir := backupDir
+		if dir == "" {
+			dir = c.BackupsPath()
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			logger.Fatal("Backup.Create", err.Error())
+		}
+
+		if backupSchedule != "" {
+			scheduleBackup(c, dir)
+			return
+		}
+
+		file, err := c.CreateBackup(dir)
+		if err != nil {
+			logger.Fatal("Backup.Create", err.Error())
+		}
+		logger.Info("Backup.Create", "Snapshot saved to "+file)
+
+		if backupKeep > 0 {
+			removed, err := c.PruneBackups(dir, backupKeep)
+			if err != nil {
+				logger.Fatal("Backup.Create", "Cannot remove old snapshots : "+err.Error())
+			}
+			for _, old := range removed {
+				logger.Info("Backup.Create", "Removed "+old)
+			}
+		}
+	},
+}
+
+func scheduleBackup(c *cluster.Cluster, dir string) {
+	tag := "mikrodock-backup-" + c.Name
+	if backupSchedule == "none" {
+		if err := utils.InstallCrontabEntry(tag, ""); err != nil {
+			logger.Fatal("Backup.Schedule", err.Error())
+		}
+		logger.Info("Backup.Schedule", "Scheduled backups of "+c.Name+" removed")
+		return
+	}
+	if !utils.ValidCronSpec(backupSchedule) {
+		logger.Fatal("Backup.Schedule", "Invalid cron spec "+backupSchedule+", expected 5 fields or a shortcut like @daily")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		logger.Fatal("Backup.Schedule", err.Error())
+	}
+	args := []string{exe, "backup", "create", c.Name, "--dir", dir}
+	if backupKeep > 0 {
+		args = append(args, "--keep", strconv.Itoa(backupKeep))
+	}
+	line := backupSchedule + " " + utils.CrontabCommand(args...) + " >> " + utils.CrontabQuote(path.Join(c.DeployDir, "backup.log")) + " 2>&1"
+
+	if err = utils.InstallCrontabEntry(tag, line); err != nil {
+		logger.Fatal("Backup.Schedule", err.Error())
+	}
+	logger.Info("Backup.Schedule", "Backups of "+c.Name+" scheduled : "+backupSchedule)
+}
+
+func init() {
+	backupCmd.AddCommand(backupCreateCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// backupCreateCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	backupCreateCmd.Flags().IntVar(&backupKeep, "keep", 0, "Number of snapshots to keep, 0 keeps them all")
+	backupCreateCmd.Flags().StringVar(&backupSchedule, "schedule", "", "Cron spec running this backup from the crontab, none to remove it")
+}
@@ -0,0 +1,91 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+)
+
+var backupRestoreYes bool
+
+// backupRestoreCmd represents the backup restore command
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a Consul snapshot of a cluster",
+	Long: `Replace the whole Consul state of a cluster with a snapshot.
+The snapshot is a file path, a file name of the backup directory, or latest.
+The restore is confirmed on the terminal, --yes skips the question.`,
+	Args: cobra.ExactArgs(2), // cluster name, snapshot
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		dir := backupDir
+		if dir == "" {
+			dir = c.BackupsPath()
+		}
+
+		file := args[1]
+		if file == "latest" {
+			backups, err := c.Backups(dir)
+			if err != nil {
+				logger.Fatal("Backup.Restore", err.Error())
+			}
+			if len(backups) == 0 {
+				logger.Fatal("Backup.Restore", "No snapshot of "+c.Name+" in "+dir)
+			}
+			file = backups[len(backups)-1].File
+		} else if _, err = os.Stat(file); os.IsNotExist(err) {
+			file = path.Join(dir, file)
+		}
+
+		if !backupRestoreYes {
+			confirmed, err := utils.Confirm("Replace the whole Consul state of " + c.Name + " with " + file + " ?")
+			if err != nil {
+				logger.Fatal("Backup.Restore", err.Error())
+			}
+			if !confirmed {
+				logger.Info("Backup.Restore", "Restore cancelled")
+				return
+			}
+		}
+
+		if err = c.RestoreBackup(file); err != nil {
+			logger.Fatal("Backup.Restore", err.Error())
+		}
+		logger.Info("Backup.Restore", "Consul state of "+c.Name+" restored from "+file)
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupRestoreCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// backupRestoreCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	backupRestoreCmd.Flags().BoolVar(&backupRestoreYes, "yes", false, "Restore without asking for confirmation")
+}
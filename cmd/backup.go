@@ -0,0 +1,45 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var backupDir string
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Save and restore the Consul state of a cluster",
+	Long: `Save and restore the Consul state of a cluster (nodes, services, stack secrets, overlay networks and ACLs)
+with Consul snapshots. They are stored in ~/.mikrodock/<cluster>/backups unless --dir is given.
+The CLI has no remote state backend : to keep the snapshots off the machine, point --dir to a mounted
+remote storage or copy them from there.`,
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	backupCmd.PersistentFlags().StringVar(&backupDir, "dir", "", "Directory of the snapshots")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// backupCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
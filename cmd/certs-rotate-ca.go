@@ -0,0 +1,63 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// certsRotateCACmd represents the certs rotate-ca command
+var certsRotateCACmd = &cobra.Command{
+	Use:   "rotate-ca",
+	Short: "Replace the Docker or the Consul CA of a cluster",
+	Long: `The rotation is done in phases, every node being restarted one at a time in each phase :
+  dual-trust : the nodes trust both the old and the new CA
+  reissue    : every certificate is reissued from the new CA
+  drop-old   : the nodes only trust the new CA
+Progress is checkpointed, running the command again resumes an interrupted rotation.
+The rotation is without downtime only with 3 konsultants or more : with fewer, Consul, the cluster store and kinetik
+are down while a konsultant restarts.`,
+	Args: cobra.ExactArgs(2), // cluster name - docker or consul
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		kind, err := cluster.ParseCAKind(args[1])
+		if err != nil {
+			logger.Fatal("CA.Rotate", err.Error())
+		}
+		if err = c.RotateCA(kind); err != nil {
+			logger.Fatal("CA.Rotate", err.Error()+" (run the command again to resume)")
+		}
+	},
+}
+
+func init() {
+	certsCmd.AddCommand(certsRotateCACmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// certsRotateCACmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// certsRotateCACmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
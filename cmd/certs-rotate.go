@@ -0,0 +1,56 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// certsRotateCmd represents the certs rotate command
+var certsRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Reissue the Docker and Consul certificates of a cluster",
+	Long: `The leaf certificates are reissued from the current CAs and uploaded.
+Nodes are restarted one at a time, the konsultants first.
+The rotation is without downtime only with 3 konsultants or more : with fewer, Consul, the cluster store and kinetik
+are down while a konsultant restarts.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		if err = c.RotateCerts(); err != nil {
+			logger.Fatal("Certs.Rotate", err.Error())
+		}
+	},
+}
+
+func init() {
+	certsCmd.AddCommand(certsRotateCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// certsRotateCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// certsRotateCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,84 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// certsStatusCmd represents the certs status command
+var certsStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the certificates of a cluster and their expiry",
+	Long:  ``,
+	Args:  cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		infos, err := c.Certificates()
+		if err != nil {
+			logger.Fatal("Certs.Status", err.Error())
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Owner", "File", "CA", "Subject", "Expires", "Days left"})
+		table.AppendBulk(certsTable(infos))
+		table.Render()
+
+		kind, phase, err := c.CARotationInProgress()
+		if err != nil {
+			logger.Warn("Certs.Status", err.Error())
+		} else if kind != "" {
+			logger.Warn("Certs.Status", "A rotation of the "+string(kind)+" CA is stopped at phase "+phase)
+		}
+	},
+}
+
+func init() {
+	certsCmd.AddCommand(certsStatusCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// certsStatusCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// certsStatusCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+func certsTable(infos []cluster.CertInfo) [][]string {
+	tContent := make([][]string, len(infos))
+	for i, info := range infos {
+		tLine := make([]string, 6)
+		tLine[0] = info.Owner
+		tLine[1] = info.Path
+		tLine[2] = strconv.FormatBool(info.IsCA)
+		tLine[3] = info.Subject
+		tLine[4] = info.NotAfter.Format("2006-01-02")
+		tLine[5] = strconv.Itoa(int(time.Until(info.NotAfter).Hours() / 24))
+		tContent[i] = tLine
+	}
+	return tContent
+}
@@ -0,0 +1,97 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var contextUser string
+
+// contextCreateCmd represents the context create command
+var contextCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Register a Docker context <cluster>-<node> for every node",
+	Long: `Register a Docker context named <cluster>-<node> for every node of the cluster,
+with the docker CLI which copies the certificates in its own store.
+Existing contexts are updated. Use them with docker --context or docker context use.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := exec.LookPath("docker"); err != nil {
+			logger.Fatal("Context.Create", "The docker CLI is needed : "+err.Error())
+		}
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		for _, p := range c.Partikles {
+			if p == nil {
+				logger.Warn("Context.Create", "A partikle of the cluster cannot be loaded")
+				continue
+			}
+			certDir, err := p.ClientCertDir(contextUser)
+			if err != nil {
+				logger.Fatal("Context.Create", "Cannot lay out cert directory of "+p.Name()+" : "+err.Error())
+			}
+			name := c.Name + "-" + p.Name()
+			if err = dockerContext(name, "Mikrodock "+c.Name+" "+p.Name(), p.Driver.GetDockerURL(), certDir); err != nil {
+				logger.Fatal("Context.Create", "Cannot register context "+name+" : "+err.Error())
+			}
+			logger.Info("Context.Create", "Context "+name+" registered")
+		}
+	},
+}
+
+func init() {
+	contextCmd.AddCommand(contextCreateCmd)
+
+	contextCreateCmd.Flags().StringVar(&contextUser, "as", "", "Use the certificate granted to this user (access grant) instead of the node ones")
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// contextCreateCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// contextCreateCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+// dockerContext creates the context with the docker CLI, or updates it when it exists
+func dockerContext(name string, description string, host string, certDir string) error {
+	endpoint := "host=" + host +
+		",ca=" + path.Join(certDir, "ca.pem") +
+		",cert=" + path.Join(certDir, "cert.pem") +
+		",key=" + path.Join(certDir, "key.pem")
+
+	action := "create"
+	if exec.Command("docker", "context", "inspect", name).Run() == nil {
+		action = "update"
+	}
+	out, err := exec.Command("docker", "context", action, name, "--description", description, "--docker", endpoint).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -98,6 +98,10 @@ func create(c *cluster.Cluster, p *cluster.Partikle, wg *sync.WaitGroup) {
 				logger.Fatal("Node.Create", "Node cannot be loaded from new config")
 			}
 
+			if err = newP.GenerateConsulCerts(c.ConsulConfPath()); err != nil {
+				logger.Fatal("Node.Create", "Cannot generate Consul certs : "+err.Error())
+			}
+
 			if err = newP.UploadConsulCerts("/etc/docker/"); err != nil {
 				logger.Fatal("Node.Create", "Cannot upload Consul certs : "+err.Error())
 			}
@@ -106,6 +110,14 @@ func create(c *cluster.Cluster, p *cluster.Partikle, wg *sync.WaitGroup) {
 				logger.Fatal("Node.Create", "Cannot upload Docker certs : "+err.Error())
 			}
 
+			if err = newP.ConfigureConsulHosts(c.ConsulServers()); err != nil {
+				logger.Fatal("Node.Create", err.Error())
+			}
+
+			if err = newP.DeliverConsulTokens(); err != nil {
+				logger.Fatal("Node.Create", "Cannot deliver Consul tokens : "+err.Error())
+			}
+
 			if err = newP.StartDocker(); err != nil {
 				logger.Fatal("Node.Create", "Cannot start Docker : "+err.Error())
 			}
@@ -113,6 +125,16 @@ func create(c *cluster.Cluster, p *cluster.Partikle, wg *sync.WaitGroup) {
 				logger.Fatal("Node.Create", "Docker cannot be detected : "+err.Error())
 			}
 
+			if c.HasCRL() {
+				if err = newP.UploadCRL(); err != nil {
+					logger.Fatal("Node.Create", "Cannot publish CRL : "+err.Error())
+				}
+			}
+
+			if err = c.RegisterPartikle(newP); err != nil {
+				logger.Warn("Node.Create", "Cannot register the health checks : "+err.Error())
+			}
+
 			logger.Info("Node.Create", "OK!")
 		}
 
@@ -0,0 +1,61 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/secrets"
+
+	"github.com/spf13/cobra"
+)
+
+// credentialsMigrateCmd represents the credentials migrate command
+var credentialsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move the credentials of a cluster to another secret provider",
+	Long: `Move the credentials of a cluster to another secret provider :
+keyring, env, age:<file>[:<identity>] or gpg:<file>:<recipient>.
+Clusters created before the secret providers keep their access token in clear in data.mk until migrated.`,
+	Args: cobra.ExactArgs(2), // cluster name, provider
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		to, err := secrets.Open(args[1], args[0])
+		if err != nil {
+			logger.Fatal("Credentials.Migrate", "Invalid secret provider "+args[1]+" : "+err.Error())
+		}
+		if err = c.MigrateSecrets(to); err != nil {
+			logger.Fatal("Credentials.Migrate", err.Error())
+		}
+		logger.Info("Credentials.Migrate", "Credentials of "+args[0]+" moved to "+to.Ref())
+	},
+}
+
+func init() {
+	credentialsCmd.AddCommand(credentialsMigrateCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// credentialsMigrateCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// credentialsMigrateCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
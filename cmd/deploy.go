@@ -17,12 +17,14 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"mikrodock-cli/cluster"
 	"mikrodock-cli/logger"
 	"net/http"
 	"path/filepath"
+	"strconv"
+
+	kModels "github.com/mikrodock/kinetik-server/models"
 
 	"github.com/spf13/cobra"
 )
@@ -41,7 +43,8 @@ var deployCmd = &cobra.Command{
 		if err != nil {
 			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
 		} else {
-			fmt.Printf("%#v\n", c)
+			// The cluster holds the access token, only its name and its partikles are shown
+			logger.Debug("Cluster.Load", "Loaded "+c.Name+" from "+c.DeployDir+" with "+strconv.Itoa(len(c.Partikles))+" partikles")
 		}
 		for _, p := range c.Partikles {
 			if p.Name() == "konduktor" {
@@ -49,12 +52,21 @@ var deployCmd = &cobra.Command{
 				if err != nil {
 					logger.Fatal("Kinetik.Service.Post", err.Error())
 				}
+				stackSecrets, err := c.DeployStackSecrets(args[1], absfile)
+				if err != nil {
+					logger.Fatal("Kinetik.Service.Secrets", "Cannot store the stack secrets : "+err.Error())
+				}
+				// Kinetik reads the sealed values from Consul KV and unseals them with the key file
 				structService := struct {
 					StackName            string
 					DockerComposeContent string
+					Secrets              []cluster.StackSecret
+					SecretsKeyFile       string
 				}{
 					args[1],
 					string(filecnt),
+					stackSecrets,
+					cluster.NodeSecretPath(cluster.StackSecretsKeyFile),
 				}
 				b, err := json.Marshal(structService)
 				if err != nil {
@@ -68,6 +80,10 @@ var deployCmd = &cobra.Command{
 				}
 				if res.StatusCode == 200 {
 					logger.Info("Kinetik.Service", "OK!")
+					registerStackHealth(c, ip, args[1], filecnt)
+					if err = c.DeployIngressLabels(args[1], filecnt); err != nil {
+						logger.Warn("Kinetik.Service.Ingress", "Cannot route the labelled services : "+err.Error())
+					}
 				} else {
 					body, _ := ioutil.ReadAll(res.Body)
 					logger.Fatal("Kinetik.Service", res.Status+" : "+string(body))
@@ -77,6 +93,24 @@ var deployCmd = &cobra.Command{
 	},
 }
 
+// registerStackHealth registers the deployed instances of the stack in Consul with the checks of their compose healthcheck
+func registerStackHealth(c *cluster.Cluster, konduktorIP string, stack string, composeContent []byte) {
+	res, err := http.Get("http://" + konduktorIP + ":10513/services")
+	if err != nil {
+		logger.Warn("Kinetik.Service.Health", "Cannot list the services : "+err.Error())
+		return
+	}
+	defer res.Body.Close()
+	services := make([]kModels.Service, 0)
+	if err = json.NewDecoder(res.Body).Decode(&services); err != nil {
+		logger.Warn("Kinetik.Service.Health", "Cannot list the services : "+err.Error())
+		return
+	}
+	if err = c.RegisterStackServices(stack, composeContent, services); err != nil {
+		logger.Warn("Kinetik.Service.Health", "Cannot register the health checks : "+err.Error())
+	}
+}
+
 func init() {
 	serviceCmd.AddCommand(deployCmd)
 
@@ -0,0 +1,120 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the connections to every node of a cluster",
+	Long: `Checks SSH, the Docker TLS endpoint, the certificates expiry, Consul,
+the overlay network, kinetik and the clock skew of every node.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		// The partikles failing to load are nil, they are reported apart
+		partikles := make([]*cluster.Partikle, 0, len(c.Partikles))
+		for _, p := range c.Partikles {
+			if p != nil {
+				partikles = append(partikles, p)
+			}
+		}
+		sort.Slice(partikles, func(i, j int) bool {
+			return partikles[i].Name() < partikles[j].Name()
+		})
+
+		diagnoses := make([]map[string]cluster.CheckResult, len(partikles))
+		var wg sync.WaitGroup
+		wg.Add(len(partikles))
+		for i, p := range partikles {
+			go func(i int, p *cluster.Partikle) {
+				defer wg.Done()
+				diagnoses[i] = p.Diagnose()
+			}(i, p)
+		}
+		wg.Wait()
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader(append([]string{"Node"}, cluster.DoctorChecks...))
+		hints := make([]string, 0)
+		failed := false
+		for i, p := range partikles {
+			tLine := []string{p.Name()}
+			for _, check := range cluster.DoctorChecks {
+				result := diagnoses[i][check]
+				tLine = append(tLine, string(result.Status))
+				if result.Status == cluster.CheckFail {
+					failed = true
+				}
+				if result.Status == cluster.CheckFail || result.Status == cluster.CheckWarn {
+					hints = append(hints, fmt.Sprintf("[%s] %s %s : %s\n    -> %s", result.Status, p.Name(), check, result.Message, result.Hint))
+				}
+			}
+			table.Append(tLine)
+		}
+
+		unloadable := c.UnloadablePartikles()
+		names := make([]string, 0, len(unloadable))
+		for name := range unloadable {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			tLine := []string{name}
+			for range cluster.DoctorChecks {
+				tLine = append(tLine, string(cluster.CheckSkip))
+			}
+			table.Append(tLine)
+			hints = append(hints, fmt.Sprintf("[%s] %s load : %s\n    -> %s", cluster.CheckFail, name, unloadable[name], "Check partikles/"+name+"/data.mk in "+c.DeployDir))
+			failed = true
+		}
+		table.Render()
+
+		for _, hint := range hints {
+			fmt.Println(hint)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// doctorCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// doctorCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,160 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var envShell string
+var envUser string
+var envUnset bool
+
+// envCmd represents the env command
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print the environment to use the docker CLI against a node",
+	Long: `Print the DOCKER_HOST, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH exports to use the docker CLI against a node :
+
+	eval $(mikrodock-cli env <cluster> <node>)                      # bash, zsh
+	mikrodock-cli env --shell fish <cluster> <node> | source        # fish
+	mikrodock-cli env --shell powershell <cluster> <node> | Invoke-Expression`,
+	Args: cobra.ExactArgs(2), // cluster name, node
+	Run: func(cmd *cobra.Command, args []string) {
+		shell := envShell
+		if shell == "" {
+			shell = detectShell()
+		}
+		if shell != "bash" && shell != "fish" && shell != "powershell" {
+			logger.Fatal("Env", "Unknown shell "+shell+" (bash, fish or powershell)")
+		}
+
+		names := []string{"DOCKER_HOST", "DOCKER_TLS_VERIFY", "DOCKER_CERT_PATH"}
+		if envUnset {
+			for _, name := range names {
+				fmt.Println(unsetLine(shell, name))
+			}
+			return
+		}
+
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		var partikle *cluster.Partikle
+		for _, p := range c.Partikles {
+			if p != nil && p.Name() == args[1] {
+				partikle = p
+			}
+		}
+		if partikle == nil {
+			logger.Fatal("Cluster.FindPartikle", "Cannot find partikle "+args[1])
+		}
+
+		certDir, err := partikle.ClientCertDir(envUser)
+		if err != nil {
+			logger.Fatal("Env", "Cannot lay out cert directory : "+err.Error())
+		}
+		if len(partikle.Driver.GetBaseDriver().JumpHosts) != 0 {
+			logger.Warn("Env", "The node is behind jump hosts, "+partikle.Driver.GetDockerURL()+" needs a tunnel to be reached")
+		}
+
+		values := []string{partikle.Driver.GetDockerURL(), "1", certDir}
+		for i, name := range names {
+			fmt.Println(exportLine(shell, name, values[i]))
+		}
+		fmt.Println("# Run this command to configure your shell:")
+		fmt.Println("# " + evalHint(shell, args[0], args[1]))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+
+	envCmd.Flags().StringVar(&envShell, "shell", "", "Shell to print the exports for : bash, fish or powershell (detected when empty)")
+	envCmd.Flags().StringVar(&envUser, "as", "", "Use the certificate granted to this user (access grant) instead of the node one")
+	envCmd.Flags().BoolVarP(&envUnset, "unset", "u", false, "Print the commands unsetting the variables")
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// envCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// envCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+func detectShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	if filepath.Base(os.Getenv("SHELL")) == "fish" {
+		return "fish"
+	}
+	return "bash"
+}
+
+func exportLine(shell string, name string, value string) string {
+	switch shell {
+	case "fish":
+		return fmt.Sprintf("set -gx %s %s;", name, quoteFish(value))
+	case "powershell":
+		return fmt.Sprintf("$Env:%s = '%s'", name, strings.Replace(value, "'", "''", -1))
+	default:
+		return fmt.Sprintf("export %s=%s", name, quoteShell(value))
+	}
+}
+
+func unsetLine(shell string, name string) string {
+	switch shell {
+	case "fish":
+		return "set -e " + name + ";"
+	case "powershell":
+		return "Remove-Item Env:\\" + name + " -ErrorAction SilentlyContinue"
+	default:
+		return "unset " + name
+	}
+}
+
+func evalHint(shell string, clusterName string, node string) string {
+	command := "mikrodock-cli env --shell " + shell + " " + clusterName + " " + node
+	switch shell {
+	case "fish":
+		return command + " | source"
+	case "powershell":
+		return "& " + command + " | Invoke-Expression"
+	default:
+		return "eval $(" + command + ")"
+	}
+}
+
+func quoteShell(value string) string {
+	return "'" + strings.Replace(value, "'", "'\\''", -1) + "'"
+}
+
+// quoteFish quotes a value for fish, which allows escaping in single quotes
+func quoteFish(value string) string {
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(value) + "'"
+}
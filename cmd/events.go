@@ -0,0 +1,98 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/events"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+)
+
+var eventsFilters []string
+var eventsJSON bool
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Stream the events of a cluster",
+	Long: `Stream the events of a cluster until interrupted : nodes joining and leaving, partikle and service health,
+services scaled, KV changes under mikrodock/ and the containers started or dying on every partikle.
+
+	mikrodock-cli events <cluster> --filter type=container --filter action=die
+	mikrodock-cli events <cluster> --json | jq .
+
+Filters are key=value with the keys source, type, action, partikle and subject. Values of a same key are alternatives,
+different keys must all match.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, err := events.ParseFilter(eventsFilters)
+		if err != nil {
+			logger.Fatal("Events", err.Error())
+		}
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			cancel()
+		}()
+
+		stream := make(chan events.Event)
+		go c.WatchEvents(ctx, stream)
+
+		encoder := json.NewEncoder(os.Stdout)
+		for e := range stream {
+			if !filter.Match(e) {
+				continue
+			}
+			if eventsJSON {
+				if err = encoder.Encode(e); err != nil {
+					logger.Fatal("Events", "Cannot encode the event : "+err.Error())
+				}
+			} else {
+				fmt.Println(e.String())
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+
+	eventsCmd.Flags().StringArrayVarP(&eventsFilters, "filter", "f", nil, "Only print the events matching key=value (source, type, action, partikle or subject)")
+	eventsCmd.Flags().BoolVar(&eventsJSON, "json", false, "Print one JSON event per line (NDJSON)")
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// eventsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// eventsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,63 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// ingressAddCmd represents the ingress add command
+var ingressAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Route a host to a service",
+	Long: `Route a host to a port of the instances of a service, <stack>/<service>:<port>.
+The route of a host already routed is replaced, *.example.com routes every subdomain.`,
+	Args: cobra.ExactArgs(3), // cluster name, host, stack/service:port
+	Run: func(cmd *cobra.Command, args []string) {
+		route, err := cluster.ParseIngressTarget(args[1], args[2])
+		if err != nil {
+			logger.Fatal("Ingress.Add", err.Error())
+		}
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		if err = c.AddIngressRoute(route); err != nil {
+			logger.Fatal("Ingress.Add", err.Error())
+		}
+		logger.Info("Ingress.Add", route.String())
+		if proxies, err := c.IngressProxies(); err == nil && len(proxies) == 0 {
+			logger.Warn("Ingress.Add", "No proxy runs the ingress yet, run ingress deploy "+args[0])
+		}
+	},
+}
+
+func init() {
+	ingressCmd.AddCommand(ingressAddCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ingressAddCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// ingressAddCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
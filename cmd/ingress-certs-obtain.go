@@ -0,0 +1,75 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/certs"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var acmeOpts cluster.ACMEOptions
+
+// ingressCertsObtainCmd represents the ingress certs obtain command
+var ingressCertsObtainCmd = &cobra.Command{
+	Use:   "obtain",
+	Short: "Obtain a certificate of hosts from an ACME server",
+	Long: `Obtain one certificate of the domains from an ACME server, Let's Encrypt by default, and reload the proxies with it.
+Obtaining it again replaces it.
+
+http-01 serves the challenges from the proxies : the domains must resolve to the klerks running them.
+dns-01 runs --dns-hook on this machine, <hook> present|cleanup _acme-challenge.<domain>. <value>, to manage the TXT
+records. It is needed by the wildcards, *.example.com.`,
+	Args: cobra.MinimumNArgs(2), // cluster name, domains
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		// The hook is recorded for the renewals, which may run from another directory
+		if acmeOpts.DNSHook != "" {
+			if acmeOpts.DNSHook, err = filepath.Abs(acmeOpts.DNSHook); err != nil {
+				logger.Fatal("Ingress.Certs.Obtain", err.Error())
+			}
+		}
+		ic, err := c.ObtainIngressCert(args[1:], acmeOpts)
+		if err != nil {
+			logger.Fatal("Ingress.Certs.Obtain", err.Error())
+		}
+		logger.Info("Ingress.Certs.Obtain", strings.Join(ic.Domains, ", ")+" valid until "+ic.NotAfter.Format("2006-01-02"))
+	},
+}
+
+func init() {
+	ingressCertsCmd.AddCommand(ingressCertsObtainCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ingressCertsObtainCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	ingressCertsObtainCmd.Flags().StringVar(&acmeOpts.Directory, "directory", certs.LetsEncryptDirectory, "Directory URL of the ACME server")
+	ingressCertsObtainCmd.Flags().StringVar(&acmeOpts.CAFile, "ca-cert", "", "CA of the TLS certificate of a private ACME server, like pebble.minica.pem")
+	ingressCertsObtainCmd.Flags().StringVar(&acmeOpts.Email, "email", "", "Contact of the ACME account")
+	ingressCertsObtainCmd.Flags().StringVar(&acmeOpts.Challenge, "challenge", certs.HTTP01, "Challenge proving the control of the domains, http-01 or dns-01")
+	ingressCertsObtainCmd.Flags().StringVar(&acmeOpts.DNSHook, "dns-hook", "", "Executable managing the TXT records of dns-01")
+}
@@ -0,0 +1,119 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var renewDays int
+var renewSchedule string
+var renewOpts cluster.ACMEOptions
+
+// ingressCertsRenewCmd represents the ingress certs renew command
+var ingressCertsRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew the certificates of the ingress close to their expiry",
+	Long: `Obtain again the certificates expiring within --days, from their ACME server and with their challenge,
+and reload the proxies with them.
+--schedule "<cron spec>" installs the same command in the crontab of the current user instead of running it,
+--schedule none removes it.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		if renewSchedule != "" {
+			scheduleRenew(c)
+			return
+		}
+
+		renewed, err := c.RenewIngressCerts(time.Duration(renewDays)*24*time.Hour, renewOpts)
+		for _, ic := range renewed {
+			logger.Info("Ingress.Certs.Renew", strings.Join(ic.Domains, ", ")+" valid until "+ic.NotAfter.Format("2006-01-02"))
+		}
+		if err != nil {
+			logger.Fatal("Ingress.Certs.Renew", err.Error())
+		}
+		if len(renewed) == 0 {
+			logger.Info("Ingress.Certs.Renew", "No certificate expires within "+strconv.Itoa(renewDays)+" days")
+		}
+	},
+}
+
+func scheduleRenew(c *cluster.Cluster) {
+	tag := "mikrodock-ingress-certs-" + c.Name
+	if renewSchedule == "none" {
+		if err := utils.InstallCrontabEntry(tag, ""); err != nil {
+			logger.Fatal("Ingress.Certs.Schedule", err.Error())
+		}
+		logger.Info("Ingress.Certs.Schedule", "Scheduled renewals of "+c.Name+" removed")
+		return
+	}
+	if !utils.ValidCronSpec(renewSchedule) {
+		logger.Fatal("Ingress.Certs.Schedule", "Invalid cron spec "+renewSchedule+", expected 5 fields or a shortcut like @daily")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		logger.Fatal("Ingress.Certs.Schedule", err.Error())
+	}
+	args := []string{exe, "ingress", "certs", "renew", c.Name, "--days", strconv.Itoa(renewDays)}
+	// The crontab does not run from the current directory
+	for _, flag := range [][2]string{{"--ca-cert", renewOpts.CAFile}, {"--dns-hook", renewOpts.DNSHook}} {
+		if flag[1] != "" {
+			file, err := filepath.Abs(flag[1])
+			if err != nil {
+				logger.Fatal("Ingress.Certs.Schedule", err.Error())
+			}
+			args = append(args, flag[0], file)
+		}
+	}
+	line := renewSchedule + " " + utils.CrontabCommand(args...) + " >> " + utils.CrontabQuote(path.Join(c.DeployDir, "ingress-certs.log")) + " 2>&1"
+
+	if err = utils.InstallCrontabEntry(tag, line); err != nil {
+		logger.Fatal("Ingress.Certs.Schedule", err.Error())
+	}
+	logger.Info("Ingress.Certs.Schedule", "Renewals of "+c.Name+" scheduled : "+renewSchedule)
+}
+
+func init() {
+	ingressCertsCmd.AddCommand(ingressCertsRenewCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ingressCertsRenewCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	ingressCertsRenewCmd.Flags().IntVar(&renewDays, "days", 30, "Renew the certificates expiring within this number of days")
+	ingressCertsRenewCmd.Flags().StringVar(&renewSchedule, "schedule", "", "Cron spec running this renewal from the crontab, none to remove it")
+	ingressCertsRenewCmd.Flags().StringVar(&renewOpts.CAFile, "ca-cert", "", "CA of the TLS certificate of a private ACME server, like pebble.minica.pem")
+	ingressCertsRenewCmd.Flags().StringVar(&renewOpts.Email, "email", "", "Contact of the ACME account, when it is registered again")
+	ingressCertsRenewCmd.Flags().StringVar(&renewOpts.DNSHook, "dns-hook", "", "Executable managing the TXT records of dns-01, replaces the recorded one")
+}
@@ -0,0 +1,86 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// ingressCertsCmd represents the ingress certs command
+var ingressCertsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "List the TLS certificates of the ingress and their expiry",
+	Long: `List the certificates obtained through ACME for the hosts of the ingress. They are kept in the Consul KV of the
+cluster, their keys sealed like the stack secrets, and served by the proxies on port 443 :
+
+	mikrodock-cli ingress certs obtain <cluster> shop.example.com
+	mikrodock-cli ingress certs renew <cluster> --schedule @daily
+
+To test offline, run a local Pebble server without validation and trust its certificate :
+
+	PEBBLE_VA_ALWAYS_VALID=1 pebble -config test/config/pebble-config.json
+	mikrodock-cli ingress certs obtain <cluster> shop.example.com --directory https://localhost:14000/dir --ca-cert test/certs/pebble.minica.pem`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		list, err := c.IngressCerts()
+		if err != nil {
+			logger.Fatal("Ingress.Certs", err.Error())
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Domains", "Expires", "Days left", "Challenge", "Directory"})
+		table.AppendBulk(ingressCertsTable(list))
+		table.Render()
+	},
+}
+
+func init() {
+	ingressCmd.AddCommand(ingressCertsCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ingressCertsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// ingressCertsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+func ingressCertsTable(list []cluster.IngressCert) [][]string {
+	tContent := make([][]string, len(list))
+	for i, ic := range list {
+		tLine := make([]string, 5)
+		tLine[0] = strings.Join(ic.Domains, ", ")
+		tLine[1] = ic.NotAfter.Format("2006-01-02")
+		tLine[2] = strconv.Itoa(int(time.Until(ic.NotAfter).Hours() / 24))
+		tLine[3] = ic.Challenge
+		tLine[4] = ic.Directory
+		tContent[i] = tLine
+	}
+	return tContent
+}
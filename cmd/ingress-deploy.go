@@ -0,0 +1,74 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var ingressImage string
+
+// ingressDeployCmd represents the ingress deploy command
+var ingressDeployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Run the reverse proxy of the ingress",
+	Long: `Run the reverse proxy of the ingress on the given klerks, every klerk when none is given.
+The proxy publishes port 80 of the klerks, deploying it again replaces the running one.`,
+	Args: cobra.MinimumNArgs(1), // cluster name, optional klerks
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		klerks := c.Klerks()
+		if len(args) > 1 {
+			klerks = nil
+			for _, name := range args[1:] {
+				p := c.PartikleByName(name)
+				if p == nil || p.IsConsulServer() || p.Name() == "konduktor" {
+					logger.Fatal("Ingress.Deploy", name+" is not a klerk of the cluster")
+				}
+				klerks = append(klerks, p)
+			}
+		}
+		if len(klerks) == 0 {
+			logger.Fatal("Ingress.Deploy", "The cluster has no klerk")
+		}
+
+		if err = c.DeployIngress(klerks, ingressImage); err != nil {
+			logger.Fatal("Ingress.Deploy", err.Error())
+		}
+	},
+}
+
+func init() {
+	ingressCmd.AddCommand(ingressDeployCmd)
+
+	ingressDeployCmd.Flags().StringVar(&ingressImage, "image", cluster.IngressImage, "Image of the reverse proxy, a Traefik 1.7 image")
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ingressDeployCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// ingressDeployCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,71 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// ingressLsCmd represents the ingress ls command
+var ingressLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the routes of the ingress",
+	Long:  `List the routes of the ingress and the klerks running its proxy.`,
+	Args:  cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		proxies, err := c.IngressProxies()
+		if err != nil {
+			logger.Fatal("Ingress.List", err.Error())
+		}
+		routes, err := c.IngressRoutes()
+		if err != nil {
+			logger.Fatal("Ingress.List", err.Error())
+		}
+
+		logger.Info("Ingress.List", "Proxies : "+strings.Join(proxies, ", "))
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Host", "Stack name", "Service Name", "Port"})
+		for _, route := range routes {
+			table.Append([]string{route.Host, route.Stack, route.Service, strconv.Itoa(route.Port)})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	ingressCmd.AddCommand(ingressLsCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ingressLsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// ingressLsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,57 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// ingressRmCmd represents the ingress rm command
+var ingressRmCmd = &cobra.Command{
+	Use:   "rm",
+	Short: "Stop routing hosts",
+	Long:  `Remove the routes of the hosts from the ingress.`,
+	Args:  cobra.MinimumNArgs(2), // cluster name, hosts
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		for _, host := range args[1:] {
+			if err = c.RemoveIngressRoute(host); err != nil {
+				logger.Fatal("Ingress.Remove", "Cannot remove the route of "+host+" : "+err.Error())
+			}
+			logger.Info("Ingress.Remove", host+" removed")
+		}
+	},
+}
+
+func init() {
+	ingressCmd.AddCommand(ingressRmCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ingressRmCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// ingressRmCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,46 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// ingressCmd represents the ingress command
+var ingressCmd = &cobra.Command{
+	Use:   "ingress",
+	Short: "Route hosts to the services of a cluster",
+	Long: `Run a reverse proxy on the klerks, attached to mikroverlay, and route hosts to the services :
+
+	mikrodock-cli ingress deploy <cluster>
+	mikrodock-cli ingress add <cluster> shop.example.com <stack>/<service>:80
+
+The services of a compose file labelled mikrodock.ingress.host (and mikrodock.ingress.port, 80 by default) are routed
+when they are deployed. The proxies find the instances of the routed services in the Consul catalog.`,
+}
+
+func init() {
+	rootCmd.AddCommand(ingressCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// ingressCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// ingressCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
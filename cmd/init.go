@@ -16,6 +16,10 @@ package cmd
 
 import (
 	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/certs"
+	"mikrodock-cli/utils/mssh"
+	"mikrodock-cli/utils/secrets"
 	"path"
 
 	homedir "github.com/mitchellh/go-homedir"
@@ -24,6 +28,14 @@ import (
 
 var doToken string
 var provider string
+var jumpHosts string
+var sshKeyType string
+var encryptSSHKey bool
+var caCertFile, caKeyFile string
+var consulCACertFile, consulCAKeyFile string
+var vaultIssuer, consulVaultIssuer string
+var secretProvider string
+var konsultants int
 
 // initCmd represents the init command
 var initCmd = &cobra.Command{
@@ -32,10 +44,31 @@ var initCmd = &cobra.Command{
 	Long:  ``,
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		if !cluster.ValidKonsultantCount(konsultants) {
+			logger.Fatal("ClusterInit", "--konsultants must be 1, 3 or 5")
+		}
 		dir, _ := homedir.Dir()
 		depDir := path.Join(dir, ".mikrodock", args[0])
+		store, err := secrets.Open(secretProvider, args[0])
+		if err != nil {
+			logger.Fatal("ClusterInit", "Invalid secret provider "+secretProvider+" : "+err.Error())
+		}
+		token := doToken
+		if token == "" {
+			if token, err = store.Get(secrets.AccessToken); err != nil {
+				logger.Fatal("ClusterInit", "No access token : "+err.Error())
+			}
+		}
 		config := make(map[string]string)
-		config["access-token"] = doToken
+		config["access-token"] = token
+		hops, err := mssh.ParseJumpHosts(jumpHosts)
+		if err != nil {
+			logger.Fatal("ClusterInit", "Invalid jump hosts : "+err.Error())
+		}
+		keyType, err := mssh.ParseKeyType(sshKeyType)
+		if err != nil {
+			logger.Fatal("ClusterInit", err.Error())
+		}
 		cl := cluster.Cluster{
 			Name:      args[0],
 			DeployDir: depDir,
@@ -43,16 +76,58 @@ var initCmd = &cobra.Command{
 				Config:     config,
 				DriverName: provider,
 			},
+			JumpHosts:        hops,
+			KonsultantCount:  konsultants,
+			SSHKeyType:       keyType,
+			EncryptSSHKey:    encryptSSHKey,
+			CertIssuer:       vaultIssuer,
+			ConsulCertIssuer: consulVaultIssuer,
+			Secrets:          store,
 		}
+		cl.ExternalCA = loadExternalCA(caCertFile, caKeyFile, vaultIssuer)
+		cl.ExternalConsulCA = loadExternalCA(consulCACertFile, consulCAKeyFile, consulVaultIssuer)
 		cl.Init()
 	},
 }
 
+func loadExternalCA(certFile string, keyFile string, vault string) *certs.FileCAGenerator {
+	if certFile == "" && keyFile == "" {
+		if vault != "" {
+			if _, err := certs.ParseVaultURL(vault); err != nil {
+				logger.Fatal("ClusterInit", err.Error())
+			}
+		}
+		return nil
+	}
+	if certFile == "" || keyFile == "" {
+		logger.Fatal("ClusterInit", "A CA needs both its certificate and its key")
+	}
+	if vault != "" {
+		logger.Fatal("ClusterInit", "A CA cannot be both given as files and issued by Vault")
+	}
+	ca, err := certs.NewFileCAGenerator(certFile, keyFile)
+	if err != nil {
+		logger.Fatal("ClusterInit", err.Error())
+	}
+	return ca
+}
+
 func init() {
 	rootCmd.AddCommand(initCmd)
 
-	initCmd.Flags().StringVar(&doToken, "do-token", "", "Digital Ocean API token")
+	initCmd.Flags().StringVar(&doToken, "do-token", "", "Digital Ocean API token (read from the secret provider when empty)")
+	initCmd.Flags().StringVar(&secretProvider, "secrets", "keyring", "Where the credentials are stored : keyring, env, age:<file>[:<identity>] or gpg:<file>:<recipient>")
 	initCmd.Flags().StringVarP(&provider, "driver", "d", "digitalocean", "Driver used to create the cluster")
+	initCmd.Flags().StringVar(&sshKeyType, "ssh-key-type", "rsa", "Type of the generated SSH key (rsa, ecdsa or ed25519)")
+	initCmd.Flags().BoolVar(&encryptSSHKey, "ssh-key-encrypt", false, "Encrypt the SSH key with a passphrase (prompted or read from "+mssh.PassphraseEnv+")")
+	initCmd.Flags().IntVar(&konsultants, "konsultants", 1, "Number of konsultants running a Consul server (1, 3 or 5)")
+	initCmd.Flags().StringVar(&jumpHosts, "jump-hosts", "", "Comma separated SSH jump hosts ([user@]host[:port][=keypath]) used to reach the nodes")
+	initCmd.Flags().StringVar(&caCertFile, "ca-cert", "", "Existing CA certificate signing the Docker certificates")
+	initCmd.Flags().StringVar(&caKeyFile, "ca-key", "", "Key of the --ca-cert CA")
+	initCmd.Flags().StringVar(&consulCACertFile, "consul-ca-cert", "", "Existing CA certificate signing the Consul certificates")
+	initCmd.Flags().StringVar(&consulCAKeyFile, "consul-ca-key", "", "Key of the --consul-ca-cert CA")
+	initCmd.Flags().StringVar(&vaultIssuer, "vault", "", "Vault PKI issuing the Docker certificates (https://host:8200/<mount>?role=<role>[&client-role=<role>], token read from "+certs.VaultTokenEnv+")")
+	initCmd.Flags().StringVar(&consulVaultIssuer, "consul-vault", "", "Vault PKI issuing the Consul certificates, same format as --vault")
 
 	// Here you will define your flags and configuration settings.
 
@@ -0,0 +1,96 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/mssh"
+
+	"github.com/spf13/cobra"
+)
+
+var jumpNode string
+var jumpClear bool
+
+// jumpCmd represents the jump command
+var jumpCmd = &cobra.Command{
+	Use:   "jump",
+	Short: "Show or set the SSH jump hosts of a cluster or a node",
+	Long: `Without a jump hosts list, this command prints the current configuration.
+Jump hosts are written [user@]host[:port][=keypath] and separated by commas.
+They are crossed in order, like the SSH ProxyJump option.`,
+	Args: cobra.RangeArgs(1, 2), // cluster name - jump hosts
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		var partikle *cluster.Partikle
+		if jumpNode != "" {
+			for _, p := range c.Partikles {
+				if p.Name() == jumpNode {
+					partikle = p
+				}
+			}
+			if partikle == nil {
+				logger.Fatal("Cluster.FindPartikle", "Cannot find partikle "+jumpNode)
+			}
+		}
+
+		if len(args) == 1 && !jumpClear {
+			fmt.Println("cluster : " + mssh.FormatJumpHosts(c.JumpHosts))
+			for _, p := range c.Partikles {
+				if len(p.JumpHosts) != 0 {
+					fmt.Println(p.Name() + " : " + mssh.FormatJumpHosts(p.JumpHosts))
+				}
+			}
+			return
+		}
+
+		hops := make([]mssh.JumpHost, 0)
+		if !jumpClear {
+			hops, err = mssh.ParseJumpHosts(args[1])
+			if err != nil {
+				logger.Fatal("Jump", "Invalid jump hosts : "+err.Error())
+			}
+		}
+
+		if partikle != nil {
+			partikle.JumpHosts = hops
+		} else {
+			c.JumpHosts = hops
+		}
+		c.Save()
+		logger.Info("Jump", "Jump hosts saved")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jumpCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// jumpCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	jumpCmd.Flags().StringVar(&jumpNode, "node", "", "Only set the jump hosts of this node")
+	jumpCmd.Flags().BoolVar(&jumpClear, "clear", false, "Remove the jump hosts")
+}
@@ -0,0 +1,62 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"mikrodock-cli/utils/mssh"
+
+	"github.com/spf13/cobra"
+)
+
+var rotateKeyType string
+
+// keysRotateCmd represents the keys rotate command
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace the SSH key of a cluster",
+	Long: `A new key is authorized on every node and checked before the old one is removed.
+The key registered at the driver provider and the key of the konduktor are replaced too.
+Jump hosts without their own key must already accept the new key.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		keyType, err := mssh.ParseKeyType(rotateKeyType)
+		if err != nil {
+			logger.Fatal("Keys.Rotate", err.Error())
+		}
+		if err = c.RotateSSHKey(keyType); err != nil {
+			logger.Fatal("Keys.Rotate", err.Error())
+		}
+	},
+}
+
+func init() {
+	keysCmd.AddCommand(keysRotateCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// keysRotateCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	keysRotateCmd.Flags().StringVar(&rotateKeyType, "type", "rsa", "Type of the new SSH key (rsa, ecdsa or ed25519)")
+}
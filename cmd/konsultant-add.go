@@ -0,0 +1,56 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// konsultantAddCmd represents the konsultant add command
+var konsultantAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a Consul server to a cluster",
+	Long: `Create a new konsultant, join it to the Consul servers and wait until it is a raft voter.
+Keep an odd number of konsultants, an even count tolerates no more failures than the count below it.`,
+	Args: cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		p, err := c.AddKonsultant()
+		if err != nil {
+			logger.Fatal("Konsultant.Add", err.Error())
+		}
+		logger.Info("Konsultant.Add", p.Name()+" ("+p.IP()+") joined the Consul servers")
+	},
+}
+
+func init() {
+	konsultantCmd.AddCommand(konsultantAddCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// konsultantAddCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// konsultantAddCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
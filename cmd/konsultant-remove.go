@@ -0,0 +1,55 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// konsultantRemoveCmd represents the konsultant remove command
+var konsultantRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a Consul server from a cluster",
+	Long: `Make a konsultant leave the raft peer set, then destroy it.
+The removal is refused when another konsultant is not a raft voter, the cluster could lose its quorum.`,
+	Args: cobra.ExactArgs(2), // cluster name, konsultant name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		if err = c.RemoveKonsultant(args[1]); err != nil {
+			logger.Fatal("Konsultant.Remove", err.Error())
+		}
+		logger.Info("Konsultant.Remove", args[1]+" removed from the Consul servers")
+	},
+}
+
+func init() {
+	konsultantCmd.AddCommand(konsultantRemoveCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// konsultantRemoveCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// konsultantRemoveCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,41 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// konsultantCmd represents the konsultant command
+var konsultantCmd = &cobra.Command{
+	Use:   "konsultant",
+	Short: "Manage the Consul servers of a cluster",
+	Long: `Manage the konsultants running the Consul servers of a cluster.
+Docker and kinetik reach them through consul.mikrodock.local, which resolves to every konsultant.`,
+}
+
+func init() {
+	rootCmd.AddCommand(konsultantCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// konsultantCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// konsultantCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,84 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	consulhelpers "mikrodock-cli/utils/consul-helpers"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var kvExportOutput string
+
+// kvExportCmd represents the kv export command
+var kvExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the keys under a prefix as JSON",
+	Long:  `Export the keys under a prefix, every key when no prefix is given, in the JSON format of consul kv export.`,
+	Args:  cobra.RangeArgs(1, 2), // cluster name, optional prefix
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		prefix := ""
+		if len(args) == 2 {
+			prefix = args[1]
+		}
+
+		helper, err := c.ConsulHelper()
+		if err != nil {
+			logger.Fatal("KV.Export", "Cannot connect to Consul : "+err.Error())
+		}
+		tree, err := helper.ReadTree(prefix)
+		if err != nil {
+			logger.Fatal("KV.Export", err.Error())
+		}
+
+		var out io.Writer = os.Stdout
+		if kvExportOutput != "" {
+			file, err := os.OpenFile(kvExportOutput, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				logger.Fatal("KV.Export", err.Error())
+			}
+			defer file.Close()
+			out = file
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "\t")
+		if err = encoder.Encode(consulhelpers.Export(tree)); err != nil {
+			logger.Fatal("KV.Export", err.Error())
+		}
+	},
+}
+
+func init() {
+	kvCmd.AddCommand(kvExportCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// kvExportCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	kvExportCmd.Flags().StringVarP(&kvExportOutput, "output", "o", "", "Write the export to this file instead of stdout")
+}
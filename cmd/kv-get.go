@@ -0,0 +1,63 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// kvGetCmd represents the kv get command
+var kvGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Print the value of a key",
+	Long:  ``,
+	Args:  cobra.ExactArgs(2), // cluster name, key
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		helper, err := c.ConsulHelper()
+		if err != nil {
+			logger.Fatal("KV.Get", "Cannot connect to Consul : "+err.Error())
+		}
+		pair, err := helper.Pair(args[1])
+		if err != nil {
+			logger.Fatal("KV.Get", err.Error())
+		}
+		if pair == nil {
+			logger.Fatal("KV.Get", "Key "+args[1]+" not found")
+		}
+		os.Stdout.Write(pair.Value)
+	},
+}
+
+func init() {
+	kvCmd.AddCommand(kvGetCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// kvGetCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// kvGetCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,106 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	consulhelpers "mikrodock-cli/utils/consul-helpers"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var kvImportPrefix string
+var kvImportPrune bool
+var kvImportCAS bool
+var kvImportDryRun bool
+
+// kvImportCmd represents the kv import command
+var kvImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import keys exported as JSON",
+	Long: `Import the keys of a consul kv export file (- reads stdin) found under --prefix.
+Only the keys that differ are written, in transactions of 64 keys.
+--prune deletes the keys under --prefix missing from the file, --cas fails on the keys modified during the import.`,
+	Args: cobra.ExactArgs(2), // cluster name, file
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		prefix := strings.Trim(kvImportPrefix, "/")
+		if kvImportPrune && prefix == "" {
+			logger.Fatal("KV.Import", "--prune needs a --prefix, it would delete every other key of the cluster")
+		}
+
+		var in io.Reader = os.Stdin
+		if args[1] != "-" {
+			file, err := os.Open(args[1])
+			if err != nil {
+				logger.Fatal("KV.Import", err.Error())
+			}
+			defer file.Close()
+			in = file
+		}
+		entries := make([]consulhelpers.ExportEntry, 0)
+		if err = json.NewDecoder(in).Decode(&entries); err != nil {
+			logger.Fatal("KV.Import", "Cannot read "+args[1]+" : "+err.Error())
+		}
+
+		helper, err := c.ConsulHelper()
+		if err != nil {
+			logger.Fatal("KV.Import", "Cannot connect to Consul : "+err.Error())
+		}
+		current, err := helper.ReadTree(prefix)
+		if err != nil {
+			logger.Fatal("KV.Import", err.Error())
+		}
+		changes := consulhelpers.Diff(current, consulhelpers.ImportTree(prefix, entries), kvImportPrune)
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		if kvImportDryRun {
+			return
+		}
+
+		if err = helper.Apply(changes, kvImportCAS); err != nil {
+			logger.Fatal("KV.Import", err.Error())
+		}
+		logger.Info("KV.Import", strconv.Itoa(len(changes))+" keys changed")
+	},
+}
+
+func init() {
+	kvCmd.AddCommand(kvImportCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// kvImportCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	kvImportCmd.Flags().StringVar(&kvImportPrefix, "prefix", "", "Only import the keys under this prefix")
+	kvImportCmd.Flags().BoolVar(&kvImportPrune, "prune", false, "Delete the keys under --prefix missing from the file")
+	kvImportCmd.Flags().BoolVar(&kvImportCAS, "cas", false, "Check-and-set every key against the index read before the import")
+	kvImportCmd.Flags().BoolVar(&kvImportDryRun, "dry-run", false, "Only print the changes")
+}
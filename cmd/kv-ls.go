@@ -0,0 +1,76 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	consulhelpers "mikrodock-cli/utils/consul-helpers"
+	"os"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// kvLsCmd represents the kv ls command
+var kvLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the keys under a prefix",
+	Long:  `List the keys under a prefix with their modify index and size, every key when no prefix is given.`,
+	Args:  cobra.RangeArgs(1, 2), // cluster name, optional prefix
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		prefix := ""
+		if len(args) == 2 {
+			prefix = args[1]
+		}
+
+		helper, err := c.ConsulHelper()
+		if err != nil {
+			logger.Fatal("KV.List", "Cannot connect to Consul : "+err.Error())
+		}
+		tree, err := helper.ReadTree(prefix)
+		if err != nil {
+			logger.Fatal("KV.List", err.Error())
+		}
+
+		entries := consulhelpers.Flatten(tree)
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Key", "Modify index", "Size"})
+		for _, key := range consulhelpers.SortedKeys(entries) {
+			entry := entries[key]
+			table.Append([]string{key, strconv.FormatUint(entry.ModifyIndex, 10), strconv.Itoa(len(entry.Value))})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	kvCmd.AddCommand(kvLsCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// kvLsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// kvLsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,81 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	consulhelpers "mikrodock-cli/utils/consul-helpers"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var kvPutFile string
+var kvPutCAS uint64
+
+// kvPutCmd represents the kv put command
+var kvPutCmd = &cobra.Command{
+	Use:   "put",
+	Short: "Set the value of a key",
+	Long: `Set the value of a key, given as argument, read from --file or from stdin.
+--cas <index> only writes when the key is still at this modify index, 0 only creates the key.`,
+	Args: cobra.RangeArgs(2, 3), // cluster name, key, optional value
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		var value []byte
+		switch {
+		case len(args) == 3:
+			value = []byte(args[2])
+		case kvPutFile != "":
+			value, err = ioutil.ReadFile(kvPutFile)
+		default:
+			value, err = ioutil.ReadAll(os.Stdin)
+		}
+		if err != nil {
+			logger.Fatal("KV.Put", "Cannot read the value : "+err.Error())
+		}
+
+		helper, err := c.ConsulHelper()
+		if err != nil {
+			logger.Fatal("KV.Put", "Cannot connect to Consul : "+err.Error())
+		}
+		change := consulhelpers.Change{Op: consulhelpers.SetOp, Key: args[1], Value: value, ModifyIndex: kvPutCAS}
+		if err = helper.Apply([]consulhelpers.Change{change}, cmd.Flags().Changed("cas")); err != nil {
+			logger.Fatal("KV.Put", err.Error())
+		}
+		logger.Info("KV.Put", "Key "+args[1]+" written")
+	},
+}
+
+func init() {
+	kvCmd.AddCommand(kvPutCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// kvPutCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	kvPutCmd.Flags().StringVar(&kvPutFile, "file", "", "Read the value from this file")
+	kvPutCmd.Flags().Uint64Var(&kvPutCAS, "cas", 0, "Check-and-set on this modify index")
+}
@@ -0,0 +1,41 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// kvCmd represents the kv command
+var kvCmd = &cobra.Command{
+	Use:   "kv",
+	Short: "Read and write the Consul KV store of a cluster",
+	Long: `Read and write the Consul KV store of a cluster.
+export and import use the JSON format of consul kv export, import applies the changes in transactions of 64 keys.`,
+}
+
+func init() {
+	rootCmd.AddCommand(kvCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// kvCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// kvCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
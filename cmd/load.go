@@ -15,9 +15,9 @@
 package cmd
 
 import (
-	"fmt"
 	"mikrodock-cli/cluster"
 	"mikrodock-cli/logger"
+	"strconv"
 
 	"github.com/spf13/cobra"
 )
@@ -33,7 +33,8 @@ var loadCmd = &cobra.Command{
 		if err != nil {
 			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
 		} else {
-			fmt.Printf("%#v\n", c)
+			// The cluster holds the access token, only its name and its partikles are shown
+			logger.Info("Cluster.Load", "Loaded "+c.Name+" from "+c.DeployDir+" with "+strconv.Itoa(len(c.Partikles))+" partikles")
 		}
 	},
 }
@@ -0,0 +1,102 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var networkSubnet string
+var networkGateway string
+var networkInternal bool
+var networkAttachable bool
+var networkEncrypted bool
+var networkStack string
+var networkLabels []string
+
+// networkCreateCmd represents the network create command
+var networkCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an overlay network",
+	Long: `Create an overlay network on the given subnet :
+
+	mikrodock-cli network create <cluster> <name> --subnet 172.143.0.0/16 --encrypted --label team=web`,
+	Args: cobra.ExactArgs(2), // cluster name, network name
+	Run: func(cmd *cobra.Command, args []string) {
+		if networkSubnet == "" {
+			logger.Fatal("Network.Create", "The subnet of the network is required (--subnet)")
+		}
+		labels := make(map[string]string)
+		for _, label := range networkLabels {
+			parts := strings.SplitN(label, "=", 2)
+			if parts[0] == "" {
+				logger.Fatal("Network.Create", "Invalid label "+label+", expected key=value")
+			}
+			if len(parts) == 1 {
+				parts = append(parts, "")
+			}
+			labels[parts[0]] = parts[1]
+		}
+		if networkStack != "" {
+			labels[cluster.NetworkStackLabel] = networkStack
+		}
+
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		id, err := c.CreateNetwork(cluster.NetworkOptions{
+			Name:       args[1],
+			Subnet:     networkSubnet,
+			Gateway:    networkGateway,
+			Internal:   networkInternal,
+			Attachable: networkAttachable,
+			Encrypted:  networkEncrypted,
+			Labels:     labels,
+		})
+		if err != nil {
+			logger.Fatal("Network.Create", err.Error())
+		}
+		fmt.Println(id)
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkCreateCmd)
+
+	networkCreateCmd.Flags().StringVar(&networkSubnet, "subnet", "", "Subnet of the network in CIDR notation")
+	networkCreateCmd.Flags().StringVar(&networkGateway, "gateway", "", "Gateway of the subnet (first address of the subnet when empty)")
+	networkCreateCmd.Flags().BoolVar(&networkInternal, "internal", false, "Restrict the external access to the network")
+	networkCreateCmd.Flags().BoolVar(&networkAttachable, "attachable", false, "Allow standalone containers to attach to the network")
+	networkCreateCmd.Flags().BoolVar(&networkEncrypted, "encrypted", false, "Encrypt the traffic between the nodes (IPsec)")
+	networkCreateCmd.Flags().StringVar(&networkStack, "stack", "", "Stack the network belongs to")
+	networkCreateCmd.Flags().StringArrayVar(&networkLabels, "label", nil, "Label of the network, key=value")
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// networkCreateCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// networkCreateCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,62 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// networkInspectCmd represents the network inspect command
+var networkInspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Print the details of an overlay network",
+	Long:  `Print the details of an overlay network as JSON, like docker network inspect.`,
+	Args:  cobra.ExactArgs(2), // cluster name, network name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		resource, err := c.InspectNetwork(args[1])
+		if err != nil {
+			logger.Fatal("Network.Inspect", err.Error())
+		}
+		content, err := json.MarshalIndent(resource, "", "    ")
+		if err != nil {
+			logger.Fatal("Network.Inspect", "Cannot encode the network : "+err.Error())
+		}
+		fmt.Println(string(content))
+	},
+}
+
+func init() {
+	networkCmd.AddCommand(networkInspectCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// networkInspectCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// networkInspectCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,105 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// networkLsCmd represents the network ls command
+var networkLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the overlay networks",
+	Long:  `List the overlay networks managed by mikrodock with their subnets and options.`,
+	Args:  cobra.ExactArgs(1), // cluster name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		networks, err := c.Networks()
+		if err != nil {
+			logger.Fatal("Network.List", "Cannot list the networks : "+err.Error())
+		}
+		sort.Slice(networks, func(i, j int) bool {
+			return networks[i].Name < networks[j].Name
+		})
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "ID", "Subnet", "Gateway", "Stack name", "Options"})
+		for _, n := range networks {
+			subnet, gateway := networkIPAM(n)
+			table.Append([]string{n.Name, shortID(n.ID), subnet, gateway, n.Labels[cluster.NetworkStackLabel], networkFlags(n)})
+		}
+		table.Render()
+	},
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+func networkIPAM(n types.NetworkResource) (string, string) {
+	var subnets, gateways []string
+	for _, config := range n.IPAM.Config {
+		subnets = append(subnets, config.Subnet)
+		gateways = append(gateways, config.Gateway)
+	}
+	return strings.Join(subnets, ", "), strings.Join(gateways, ", ")
+}
+
+func networkFlags(n types.NetworkResource) string {
+	var flags []string
+	if n.Internal {
+		flags = append(flags, "internal")
+	}
+	if n.Attachable {
+		flags = append(flags, "attachable")
+	}
+	if _, ok := n.Options["encrypted"]; ok {
+		flags = append(flags, "encrypted")
+	}
+	if len(n.Containers) != 0 {
+		flags = append(flags, strconv.Itoa(len(n.Containers))+" containers")
+	}
+	return strings.Join(flags, ", ")
+}
+
+func init() {
+	networkCmd.AddCommand(networkLsCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// networkLsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// networkLsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
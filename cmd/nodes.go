@@ -36,9 +36,13 @@ var nodesCmd = &cobra.Command{
 		if err != nil {
 			logger.Fatal("Cluster.Load", "Cannot load cluster test")
 		}
+		health, err := c.PartiklesHealth()
+		if err != nil {
+			logger.Warn("Nodes.Health", "Cannot read the health checks : "+err.Error())
+		}
 		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{"Name", "IP", "Master"})
-		table.AppendBulk(clusterTable(c))
+		table.SetHeader([]string{"Name", "IP", "Master", "Health"})
+		table.AppendBulk(clusterTable(c, health))
 		table.Render()
 	},
 }
@@ -57,17 +61,29 @@ func init() {
 	// nodesCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func clusterTable(c *cluster.Cluster) [][]string {
+func clusterTable(c *cluster.Cluster, health map[string]string) [][]string {
 	tContent := make([][]string, len(c.Partikles))
 	sort.Slice(c.Partikles, func(i, j int) bool {
 		return c.Partikles[i].Name() < c.Partikles[j].Name()
 	})
 	for i, p := range c.Partikles {
-		tLine := make([]string, 3)
+		tLine := make([]string, 4)
 		tLine[0] = p.Name()
 		tLine[1] = p.IP()
 		tLine[2] = strconv.FormatBool(p.IsMaster)
+		tLine[3] = healthStatus(health, p.Name())
 		tContent[i] = tLine
 	}
 	return tContent
 }
+
+// healthStatus returns the status of key, unknown when Consul could not be read
+func healthStatus(health map[string]string, key string) string {
+	if health == nil {
+		return "unknown"
+	}
+	if status, ok := health[key]; ok {
+		return status
+	}
+	return cluster.HealthUnregistered
+}
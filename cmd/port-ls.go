@@ -0,0 +1,80 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// portLsCmd represents the port ls command
+var portLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the published ports",
+	Long:  `List the published ports, for every service or for the given stack, and check their rules are on the nodes.`,
+	Args:  cobra.RangeArgs(1, 2), // cluster name, optional stack name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		ports, err := c.PublishedPorts()
+		if err != nil {
+			logger.Fatal("Port.List", err.Error())
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Stack name", "Service Name", "Published", "Target", "Protocol", "Nodes", "Applied"})
+		for _, port := range ports {
+			if len(args) == 2 && port.Stack != args[1] {
+				continue
+			}
+			var partikles []string
+			for _, binding := range port.Bindings {
+				partikles = append(partikles, binding.Partikle)
+			}
+			table.Append([]string{
+				port.Stack,
+				port.Service,
+				strconv.Itoa(port.Mapping.Published),
+				strconv.Itoa(port.Mapping.Target),
+				port.Mapping.Protocol,
+				strings.Join(partikles, ", "),
+				strconv.Itoa(c.PortApplied(port)) + "/" + strconv.Itoa(len(port.Bindings)),
+			})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	portCmd.AddCommand(portLsCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// portLsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// portLsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
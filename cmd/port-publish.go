@@ -0,0 +1,71 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/iptables"
+	"mikrodock-cli/logger"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// portPublishCmd represents the port publish command
+var portPublishCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "Publish a port of a service",
+	Long: `Publish a port of the nodes on a port of the instances of a service, <published>:<target>/<tcp|udp>.
+Publishing a port again updates its rules to the current instances of the service.`,
+	Args: cobra.ExactArgs(3), // cluster name, stack/service, port mapping
+	Run: func(cmd *cobra.Command, args []string) {
+		stack, service, err := parseStackService(args[1])
+		if err != nil {
+			logger.Fatal("Port.Publish", err.Error())
+		}
+		mapping, err := iptables.ParsePortMapping(args[2])
+		if err != nil {
+			logger.Fatal("Port.Publish", err.Error())
+		}
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		published, err := c.PublishPort(stack, service, mapping)
+		if err != nil {
+			logger.Fatal("Port.Publish", err.Error())
+		}
+		var partikles []string
+		for _, binding := range published.Bindings {
+			partikles = append(partikles, binding.Partikle)
+		}
+		logger.Info("Port.Publish", mapping.String()+" published on "+strings.Join(partikles, ", "))
+	},
+}
+
+func init() {
+	portCmd.AddCommand(portPublishCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// portPublishCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// portPublishCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
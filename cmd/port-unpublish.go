@@ -0,0 +1,64 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/iptables"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// portUnpublishCmd represents the port unpublish command
+var portUnpublishCmd = &cobra.Command{
+	Use:   "unpublish",
+	Short: "Unpublish a port of a service",
+	Long:  `Remove the rules of a published port from the nodes and forget it.`,
+	Args:  cobra.ExactArgs(3), // cluster name, stack/service, port mapping
+	Run: func(cmd *cobra.Command, args []string) {
+		stack, service, err := parseStackService(args[1])
+		if err != nil {
+			logger.Fatal("Port.Unpublish", err.Error())
+		}
+		mapping, err := iptables.ParsePortMapping(args[2])
+		if err != nil {
+			logger.Fatal("Port.Unpublish", err.Error())
+		}
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		if err = c.UnpublishPort(stack, service, mapping); err != nil {
+			logger.Fatal("Port.Unpublish", err.Error())
+		}
+		logger.Info("Port.Unpublish", mapping.String()+" unpublished")
+	},
+}
+
+func init() {
+	portCmd.AddCommand(portUnpublishCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// portUnpublishCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// portUnpublishCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,56 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// portCmd represents the port command
+var portCmd = &cobra.Command{
+	Use:   "port",
+	Short: "Publish the ports of the services on their nodes",
+	Long: `Publish the ports of the services on the nodes running their instances :
+
+	mikrodock-cli port publish <cluster> <stack>/<service> 8080:80/tcp
+
+The published ports are recorded in Consul and applied again when the nodes boot.`,
+}
+
+// parseStackService reads stack/service
+func parseStackService(arg string) (string, string, error) {
+	parts := strings.Split(arg, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Invalid service %s, expected <stack>/<service>", arg)
+	}
+	return parts[0], parts[1], nil
+}
+
+func init() {
+	rootCmd.AddCommand(portCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// portCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// portCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
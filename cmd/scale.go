@@ -16,7 +16,6 @@ package cmd
 
 import (
 	"bytes"
-	"fmt"
 	"io/ioutil"
 	"mikrodock-cli/cluster"
 	"mikrodock-cli/logger"
@@ -39,7 +38,8 @@ var scaleCmd = &cobra.Command{
 		if err != nil {
 			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
 		} else {
-			fmt.Printf("%#v\n", c)
+			// The cluster holds the access token, only its name and its partikles are shown
+			logger.Debug("Cluster.Load", "Loaded "+c.Name+" from "+c.DeployDir+" with "+strconv.Itoa(len(c.Partikles))+" partikles")
 		}
 
 		qty, _ := strconv.Atoi(args[4])
@@ -75,6 +75,11 @@ var scaleCmd = &cobra.Command{
 		}
 
 		wg.Wait()
+
+		// The routes follow the instances of the services
+		if err = c.RefreshIngress(args[1]); err != nil {
+			logger.Warn("Kinetik.Service.Ingress", "Cannot refresh the routes of the stack : "+err.Error())
+		}
 	},
 }
 
@@ -0,0 +1,82 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var secretFile string
+var secretEnv string
+
+// secretCreateCmd represents the secret create command
+var secretCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Store a secret or a config of a stack",
+	Long: `Store a secret or a config of a stack, read from --file, from the --env variable or from stdin.
+An existing value is replaced. Compose files reference it as external.`,
+	Args: cobra.ExactArgs(3), // cluster name, stack name, secret name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+
+		var value []byte
+		switch {
+		case secretFile != "" && secretEnv != "":
+			logger.Fatal("Secret.Create", "--file and --env cannot be used together")
+		case secretFile != "":
+			value, err = ioutil.ReadFile(secretFile)
+		case secretEnv != "":
+			env, ok := os.LookupEnv(secretEnv)
+			if !ok {
+				logger.Fatal("Secret.Create", secretEnv+" is not set")
+			}
+			value = []byte(env)
+		default:
+			value, err = ioutil.ReadAll(os.Stdin)
+		}
+		if err != nil {
+			logger.Fatal("Secret.Create", "Cannot read the value : "+err.Error())
+		}
+
+		secret, err := c.StoreStackSecret(args[1], secretKind(), args[2], value)
+		if err != nil {
+			logger.Fatal("Secret.Create", err.Error())
+		}
+		logger.Info("Secret.Create", "Stored "+secret.Key)
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretCreateCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// secretCreateCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	secretCreateCmd.Flags().StringVar(&secretFile, "file", "", "Read the value from this file")
+	secretCreateCmd.Flags().StringVar(&secretEnv, "env", "", "Read the value from this environment variable")
+}
@@ -0,0 +1,68 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// secretLsCmd represents the secret ls command
+var secretLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the secrets and configs of the stacks",
+	Long:  `List the secrets and configs stored in the cluster, for every stack or for the given one. Values are never shown.`,
+	Args:  cobra.RangeArgs(1, 2), // cluster name, optional stack name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		stack := ""
+		if len(args) == 2 {
+			stack = args[1]
+		}
+
+		list, err := c.StackSecrets(stack)
+		if err != nil {
+			logger.Fatal("Secret.List", err.Error())
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Stack name", "Kind", "Name", "Key"})
+		for _, secret := range list {
+			table.Append([]string{secret.Stack, secret.Kind, secret.Name, secret.Key})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretLsCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// secretLsCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// secretLsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
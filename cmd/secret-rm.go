@@ -0,0 +1,63 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/cluster"
+	"mikrodock-cli/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// secretRmCmd represents the secret rm command
+var secretRmCmd = &cobra.Command{
+	Use:   "rm",
+	Short: "Remove a secret or a config of a stack",
+	Long:  `Remove a secret or a config of a stack, or every secret and config of the stack when no name is given.`,
+	Args:  cobra.RangeArgs(2, 3), // cluster name, stack name, optional secret name
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := cluster.LoadCluster(args[0])
+		if err != nil {
+			logger.Fatal("Cluster.Load", "Cannot load cluster "+err.Error())
+		}
+		name := ""
+		if len(args) == 3 {
+			name = args[2]
+		}
+
+		if err = c.RemoveStackSecret(args[1], secretKind(), name); err != nil {
+			logger.Fatal("Secret.Remove", err.Error())
+		}
+		if name == "" {
+			logger.Info("Secret.Remove", "Removed every secret and config of "+args[1])
+		} else {
+			logger.Info("Secret.Remove", "Removed "+secretKind()+" "+name+" of "+args[1])
+		}
+	},
+}
+
+func init() {
+	secretCmd.AddCommand(secretRmCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	// secretRmCmd.PersistentFlags().String("foo", "", "A help for foo")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// secretRmCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
@@ -0,0 +1,53 @@
+// Copyright © 2018 NAME HERE <EMAIL ADDRESS>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"mikrodock-cli/utils/compose"
+
+	"github.com/spf13/cobra"
+)
+
+var secretConfig bool
+
+// secretCmd represents the secret command
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage the secrets and configs of the stacks",
+	Long: `Manage the secrets and configs of the stacks.
+They are sealed with the stack secrets key of the cluster and stored in Consul under mikrodock/secrets/<stack>.
+service deploy stores the secrets and configs of the compose file itself, these commands manage them standalone.`,
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+
+	// Here you will define your flags and configuration settings.
+
+	// Cobra supports Persistent Flags which will work for this command
+	// and all subcommands, e.g.:
+	secretCmd.PersistentFlags().BoolVar(&secretConfig, "config", false, "Manage a config instead of a secret")
+
+	// Cobra supports local flags which will only run when this command
+	// is called directly, e.g.:
+	// secretCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+}
+
+func secretKind() string {
+	if secretConfig {
+		return compose.ConfigKind
+	}
+	return compose.SecretKind
+}
@@ -28,6 +28,7 @@ import (
 	kModels "github.com/mikrodock/kinetik-server/models"
 
 	"github.com/docker/cli/cli/compose/types"
+	consulAPI "github.com/hashicorp/consul/api"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
@@ -56,13 +57,13 @@ var servicesCmd = &cobra.Command{
 				if len(args) == 1 {
 					// Overview mode
 					table := tablewriter.NewWriter(os.Stdout)
-					table.SetHeader([]string{"Stack name", "Service Name", "Count", "Ports"})
-					table.AppendBulk(convertTable(services))
+					table.SetHeader([]string{"Stack name", "Service Name", "Count", "Ports", "Health"})
+					table.AppendBulk(convertTable(services, servicesHealth(c, services)))
 					table.Render()
 				} else if len(args) == 2 {
 					// Overview mode, filter on stackname
 					table := tablewriter.NewWriter(os.Stdout)
-					table.SetHeader([]string{"Stack name", "Service Name", "Count", "Ports"})
+					table.SetHeader([]string{"Stack name", "Service Name", "Count", "Ports", "Health"})
 					deleted := 0
 					for i := range services {
 						j := i - deleted
@@ -71,7 +72,7 @@ var servicesCmd = &cobra.Command{
 							deleted++
 						}
 					}
-					table.AppendBulk(convertTable(services))
+					table.AppendBulk(convertTable(services, servicesHealth(c, services)))
 					table.Render()
 				} else {
 					// Detail mode, stackname and service name
@@ -84,9 +85,10 @@ var servicesCmd = &cobra.Command{
 					}
 
 					if service != nil {
+						health := servicesHealth(c, []kModels.Service{*service})
 						table := tablewriter.NewWriter(os.Stdout)
-						table.SetHeader([]string{"Host IP", "Instance name"})
-						table.AppendBulk(convertDetailTable(service))
+						table.SetHeader([]string{"Host IP", "Instance name", "Health"})
+						table.AppendBulk(convertDetailTable(service, health[service.StackName+"/"+service.ServiceName]))
 						table.Render()
 					}
 				}
@@ -109,14 +111,39 @@ func init() {
 	// servicesCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
-func convertTable(services []kModels.Service) [][]string {
+// servicesHealth returns the Consul status of the instances of each service by stack/service then container ID,
+// nil when Consul cannot be read
+func servicesHealth(c *cluster.Cluster, services []kModels.Service) map[string]map[string]string {
+	health := make(map[string]map[string]string)
+	for _, srv := range services {
+		instances, err := c.StackServiceHealth(srv.StackName, srv.ServiceName)
+		if err != nil {
+			logger.Warn("Services.Health", "Cannot read the health checks : "+err.Error())
+			return nil
+		}
+		health[srv.StackName+"/"+srv.ServiceName] = instances
+	}
+	return health
+}
+
+func convertTable(services []kModels.Service, health map[string]map[string]string) [][]string {
 	srvs := make([][]string, len(services))
 	for i, srv := range services {
-		srvL := make([]string, 4)
+		srvL := make([]string, 5)
 		srvL[0] = srv.StackName
 		srvL[1] = srv.ServiceName
 		srvL[2] = strconv.Itoa(len(srv.Instances))
 		srvL[3] = convertPorts(srv.Ports)
+		srvL[4] = "unknown"
+		if health != nil {
+			passing := 0
+			for _, status := range health[srv.StackName+"/"+srv.ServiceName] {
+				if status == consulAPI.HealthPassing {
+					passing++
+				}
+			}
+			srvL[4] = strconv.Itoa(passing) + "/" + strconv.Itoa(len(srv.Instances)) + " passing"
+		}
 		srvs[i] = srvL
 	}
 	return srvs
@@ -130,12 +157,13 @@ func convertPorts(ports []types.ServicePortConfig) string {
 	return buf.String()
 }
 
-func convertDetailTable(srv *kModels.Service) [][]string {
+func convertDetailTable(srv *kModels.Service, health map[string]string) [][]string {
 	a := make([][]string, len(srv.Instances))
 	for i, inst := range srv.Instances {
-		b := make([]string, 2)
+		b := make([]string, 3)
 		b[0] = inst.NodeID
 		b[1] = inst.ContainerID
+		b[2] = healthStatus(health, inst.ContainerID)
 		a[i] = b
 	}
 	sort.Slice(a, func(i, j int) bool {
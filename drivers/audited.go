@@ -0,0 +1,67 @@
+package drivers
+
+import (
+	"io"
+	"mikrodock-cli/utils/audit"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// AuditedDriver records every SSH command and file copy of the wrapped driver
+type AuditedDriver struct {
+	Driver
+	Log *audit.Log
+}
+
+// NewAuditedFactory wraps every driver created by factory in an AuditedDriver
+func NewAuditedFactory(factory InitDriver, log *audit.Log) InitDriver {
+	return func(instanceConf map[string]interface{}) (Driver, error) {
+		d, err := factory(instanceConf)
+		if err != nil {
+			return nil, err
+		}
+		return &AuditedDriver{Driver: d, Log: log}, nil
+	}
+}
+
+func (d *AuditedDriver) SSHCommand(cmd string) (string, string, error) {
+	start := time.Now()
+	stdout, stderr, err := d.Driver.SSHCommand(cmd)
+	d.record(start, "exec", cmd, err)
+	return stdout, stderr, err
+}
+
+func (d *AuditedDriver) CopyFile(source string, destination string) error {
+	start := time.Now()
+	err := d.Driver.CopyFile(source, destination)
+	d.record(start, "copy", source+" -> "+destination, err)
+	return err
+}
+
+func (d *AuditedDriver) Copy(size int64, mode os.FileMode, fileName string, contents io.Reader, destinationPath string, session *ssh.Session) error {
+	start := time.Now()
+	err := d.Driver.Copy(size, mode, fileName, contents, destinationPath, session)
+	d.record(start, "copy", fileName+" -> "+destinationPath, err)
+	return err
+}
+
+func (d *AuditedDriver) record(start time.Time, action string, cmd string, err error) {
+	entry := audit.Entry{
+		Time:       start,
+		Node:       d.GetBaseDriver().MachineName,
+		NodeIP:     d.GetBaseDriver().IPAddress,
+		Action:     action,
+		Command:    cmd,
+		DurationMs: time.Since(start).Nanoseconds() / int64(time.Millisecond),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry.ExitStatus = -1
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			entry.ExitStatus = exitErr.ExitStatus()
+		}
+	}
+	d.Log.Record(entry)
+}
@@ -3,6 +3,8 @@ package drivers
 import (
 	"errors"
 	"io"
+	"mikrodock-cli/utils/mssh"
+	"net"
 	"os"
 
 	"golang.org/x/crypto/ssh"
@@ -16,6 +18,7 @@ type BaseDriver struct {
 	SSHUser     string
 	SSHPort     string
 	SSHKeyPath  string
+	JumpHosts   []mssh.JumpHost
 	RawConfig   map[string]interface{}
 }
 
@@ -82,3 +85,11 @@ func (d *BaseDriver) CopyFile(source string, destination string) error {
 func (d *BaseDriver) Copy(size int64, mode os.FileMode, fileName string, contents io.Reader, destinationPath string, session *ssh.Session) error {
 	return errors.New("Base driver cannot copy files")
 }
+
+func (d *BaseDriver) Dial(network string, address string) (net.Conn, error) {
+	return nil, errors.New("Base driver cannot open tunnels")
+}
+
+func (d *BaseDriver) ReplaceSSHKey(newKeyPath string) error {
+	return errors.New("Base driver cannot replace SSH keys")
+}
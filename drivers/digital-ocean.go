@@ -11,6 +11,7 @@ import (
 	"log"
 	"mikrodock-cli/logger"
 	mSSSH "mikrodock-cli/utils/mssh"
+	"net"
 	"os"
 	"time"
 
@@ -53,7 +54,7 @@ func (d *DigitalOceanDriver) PreCreate(conf map[string]interface{}) error {
 	}
 	fingerprint := mSSSH.ComputePublicFingerprint(pKey)
 	key, resp, err := client.Keys.GetByFingerprint(context.TODO(), fingerprint)
-	if err != nil && resp.StatusCode != 404 {
+	if err != nil && (resp == nil || resp.StatusCode != 404) {
 		return err
 	}
 	if conf["name"] == nil {
@@ -83,6 +84,9 @@ func (d *DigitalOceanDriver) PreCreate(conf map[string]interface{}) error {
 	}
 
 	d.SSHKeyPath = conf["ssh-key-path"].(string)
+	if conf["jump-hosts"] != nil {
+		d.JumpHosts = conf["jump-hosts"].([]mSSSH.JumpHost)
+	}
 	d.sshClient = nil
 
 	return nil
@@ -125,6 +129,8 @@ func (d *DigitalOceanDriver) Create() error {
 		SSHKeys: []godo.DropletCreateSSHKey{godo.DropletCreateSSHKey{
 			Fingerprint: d.Fingerprint,
 		}},
+		// Behind a bastion, the nodes are reached on their private address
+		PrivateNetworking: len(d.JumpHosts) != 0,
 	}
 
 	fmt.Printf("%#v\r\n", createRequest)
@@ -152,13 +158,22 @@ func (d *DigitalOceanDriver) Create() error {
 
 	drop, _, _ = client.Droplets.Get(context.TODO(), d.DropletID)
 
-	pub, err := drop.PublicIPv4()
-	if err != nil {
-		return err
+	var ip string
+	if len(d.JumpHosts) != 0 {
+		ip, err = drop.PrivateIPv4()
+		if err != nil {
+			return err
+		}
+		logger.Info("Driver.DigitalOcean", "The private IPv4 is "+ip)
+	} else {
+		ip, err = drop.PublicIPv4()
+		if err != nil {
+			return err
+		}
+		logger.Info("Driver.DigitalOcean", "The public IPv4 is "+ip)
 	}
-	logger.Info("Driver.DigitalOcean", "The public IPv4 is "+pub)
 
-	d.IPAddress = pub
+	d.IPAddress = ip
 	d.SSHPort = "22"
 	d.SSHUser = "root"
 
@@ -211,19 +226,27 @@ func (d *DigitalOceanDriver) WaitState(state State, timeout int) (bool, error) {
 }
 
 func (d *DigitalOceanDriver) sshConnect() error {
+	auths, err := mSSSH.AuthMethods(d.SSHKeyPath)
+	if len(auths) == 0 {
+		return fmt.Errorf("Cannot load SSH key %s : %s", d.SSHKeyPath, err)
+	}
+	if err != nil {
+		logger.Warn("SSHUtils", "Cannot load SSH key, falling back to ssh-agent : "+err.Error())
+	}
 	sshConfig := &ssh.ClientConfig{
-		User: d.SSHUser,
-		Auth: []ssh.AuthMethod{
-			mSSSH.PublicKeyFile(d.SSHKeyPath),
-		},
+		User:            d.SSHUser,
+		Auth:            auths,
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 	}
 	logger.Info("SSHUtils", "Opening connection to "+d.IPAddress+":"+d.SSHPort)
+	if len(d.JumpHosts) != 0 {
+		logger.Info("SSHUtils", "Jumping through "+mSSSH.FormatJumpHosts(d.JumpHosts))
+	}
 	retryCount := 1
 	var _err error
 	for retryCount < 5 {
 		var err error
-		d.sshClient, err = ssh.Dial("tcp", d.IPAddress+":"+d.SSHPort, sshConfig)
+		d.sshClient, err = mSSSH.DialJump(d.JumpHosts, d.IPAddress+":"+d.SSHPort, sshConfig)
 		if err != nil {
 			logger.Warn("SSHUtils", "Cannot connect SSH to host, retrying in 10 seconds...")
 			time.Sleep(10 * time.Second)
@@ -337,6 +360,58 @@ func (d *DigitalOceanDriver) SSHCommand(cmd string) (string, string, error) {
 	return stdoutBuf.String(), stderrBuf.String(), err
 }
 
+func (d *DigitalOceanDriver) Dial(network string, address string) (net.Conn, error) {
+	if d.sshClient == nil {
+		err := d.sshConnect()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d.sshClient.Dial(network, address)
+}
+
+func (d *DigitalOceanDriver) ReplaceSSHKey(newKeyPath string) error {
+	client, err := d.getClient()
+	if err != nil {
+		return err
+	}
+
+	newKey, err := mSSSH.LoadPrivateKey(newKeyPath)
+	if err != nil {
+		return err
+	}
+	oldKey, err := mSSSH.LoadPrivateKey(d.SSHKeyPath)
+	if err != nil {
+		return err
+	}
+
+	fingerprint := mSSSH.ComputePublicFingerprint(newKey)
+	key, resp, err := client.Keys.GetByFingerprint(context.TODO(), fingerprint)
+	if err != nil && (resp == nil || resp.StatusCode != 404) {
+		return err
+	}
+	if key == nil {
+		logger.Info("Driver.DigitalOcean", "Uploading new SSH key")
+		request := &godo.KeyCreateRequest{
+			Name:      d.BaseDriver.MachineName,
+			PublicKey: string(ssh.MarshalAuthorizedKey(newKey.PublicKey())),
+		}
+		if _, _, err = client.Keys.Create(context.TODO(), request); err != nil {
+			return err
+		}
+	}
+
+	oldFingerprint := mSSSH.ComputePublicFingerprint(oldKey)
+	logger.Info("Driver.DigitalOcean", "Deleting old SSH key "+oldFingerprint)
+	resp, err = client.Keys.DeleteByFingerprint(context.TODO(), oldFingerprint)
+	if err != nil && (resp == nil || resp.StatusCode != 404) {
+		return err
+	}
+
+	d.Fingerprint = fingerprint
+	return nil
+}
+
 func (d *DigitalOceanDriver) Kill() error {
 	panic("not implemented")
 }
@@ -2,6 +2,7 @@ package drivers
 
 import (
 	"io"
+	"net"
 	"os"
 
 	"golang.org/x/crypto/ssh"
@@ -46,4 +47,10 @@ type Driver interface {
 	SSHCommand(cmd string) (string, string, error)
 	CopyFile(source string, destination string) error
 	Copy(size int64, mode os.FileMode, fileName string, contents io.Reader, destinationPath string, session *ssh.Session) error
+
+	// Dial opens a connection to address as seen from the host, tunneled through SSH
+	Dial(network string, address string) (net.Conn, error)
+
+	// ReplaceSSHKey registers newKeyPath at the provider in place of the current key
+	ReplaceSSHKey(newKeyPath string) error
 }
@@ -0,0 +1,102 @@
+package iptables
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Runner runs a shell command on a host, the drivers are runners
+type Runner interface {
+	SSHCommand(cmd string) (string, string, error)
+}
+
+const restoreDelimiter = "MIKRODOCK_IPTABLES"
+
+// Save returns the rules of every table of the host, as printed by iptables-save
+func Save(runner Runner) (string, error) {
+	stdout, errOut, err := runner.SSHCommand("iptables-save")
+	if err != nil {
+		return "", fmt.Errorf("Cannot save the rules : %s %s", err, errOut)
+	}
+	return stdout, nil
+}
+
+// Restore replaces the rules of the host with saved ones, each table is committed at once by iptables-restore
+func Restore(runner Runner, saved string) error {
+	if strings.Contains(saved, restoreDelimiter) {
+		return fmt.Errorf("Cannot restore rules holding %s", restoreDelimiter)
+	}
+	cmd := "iptables-restore <<'" + restoreDelimiter + "'\n" + strings.TrimRight(saved, "\n") + "\n" + restoreDelimiter
+	if _, errOut, err := runner.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("Cannot restore the rules : %s %s", err, errOut)
+	}
+	return nil
+}
+
+// Check tells if the rule is on the host
+func Check(runner Runner, rule Rule) (bool, error) {
+	cmd, err := rule.Command(CHECK)
+	if err != nil {
+		return false, err
+	}
+	stdout, errOut, err := runner.SSHCommand(cmd + " 2>/dev/null; echo $?")
+	if err != nil {
+		return false, fmt.Errorf("Cannot check %s : %s %s", rule.Render(APPEND), err, errOut)
+	}
+	switch strings.TrimSpace(stdout) {
+	case "0":
+		return true, nil
+	case "1":
+		return false, nil
+	}
+	return false, fmt.Errorf("Cannot check %s : iptables exited with %s", rule.Render(APPEND), strings.TrimSpace(stdout))
+}
+
+// Apply appends the rules missing on the host. When one fails, the rules of the host are rolled back
+func Apply(runner Runner, rules []Rule) error {
+	return change(runner, rules, APPEND, false)
+}
+
+// Delete removes the rules present on the host. When one fails, the rules of the host are rolled back
+func Delete(runner Runner, rules []Rule) error {
+	return change(runner, rules, DELETE, true)
+}
+
+// change runs the action on the rules whose presence is when, after having saved the rules to roll back
+func change(runner Runner, rules []Rule, action IPTableAction, when bool) error {
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("Invalid rule %s : %s", rule.Render(action), err)
+		}
+	}
+	saved, err := Save(runner)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		err = changeRule(runner, rule, action, when)
+		if err != nil {
+			if rollbackErr := Restore(runner, saved); rollbackErr != nil {
+				return fmt.Errorf("%s, and the rollback failed : %s", err, rollbackErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func changeRule(runner Runner, rule Rule, action IPTableAction, when bool) error {
+	present, err := Check(runner, rule)
+	if err != nil || present != when {
+		return err
+	}
+	cmd, err := rule.Command(action)
+	if err != nil {
+		return err
+	}
+	if _, errOut, err := runner.SSHCommand(cmd); err != nil {
+		return fmt.Errorf("Cannot run %s : %s %s", cmd, err, errOut)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package iptables
+
+import (
+	"strconv"
+	"strings"
+)
+
+var nftVerdicts = map[string]string{
+	"ACCEPT":     "accept",
+	"DROP":       "drop",
+	"RETURN":     "return",
+	"REJECT":     "reject",
+	"MASQUERADE": "masquerade",
+}
+
+// Nft returns the nft command adding the rule to the ip family.
+// nft deletes rules by handle only, the rule has to be found with nft -a list chain first
+func (r Rule) Nft() (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+	table := r.Table
+	if table == "" {
+		table = "filter"
+	}
+
+	expr := []string{"add", "rule", "ip", table, r.Chain}
+	if r.InInterface != "" {
+		expr = append(expr, "iifname", nftOperand(r.NotInInterface, strconv.Quote(r.InInterface)))
+	}
+	if r.OutInterface != "" {
+		expr = append(expr, "oifname", nftOperand(r.NotOutInterface, strconv.Quote(r.OutInterface)))
+	}
+	if r.Source != "" {
+		expr = append(expr, "ip", "saddr", r.Source)
+	}
+	if r.Destination != "" {
+		expr = append(expr, "ip", "daddr", r.Destination)
+	}
+	if r.DestinationPort != 0 {
+		expr = append(expr, r.Protocol, "dport", strconv.Itoa(r.DestinationPort))
+	} else if r.Protocol != "" {
+		expr = append(expr, "meta", "l4proto", r.Protocol)
+	}
+
+	if verdict, ok := nftVerdicts[r.Target]; ok {
+		expr = append(expr, verdict)
+	} else if r.Target == "DNAT" {
+		expr = append(expr, "dnat", "to", r.ToDestination)
+	} else {
+		// The other targets are user chains
+		expr = append(expr, "jump", r.Target)
+	}
+	if r.Comment != "" {
+		expr = append(expr, "comment", strconv.Quote(r.Comment))
+	}
+	return "nft " + strings.Join(expr, " "), nil
+}
+
+func nftOperand(negate bool, value string) string {
+	if negate {
+		return "!= " + value
+	}
+	return value
+}
@@ -0,0 +1,100 @@
+package iptables
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PortMapping publishes the Target port of a container on the Published port of its host
+type PortMapping struct {
+	Published int
+	Target    int
+	Protocol  string
+}
+
+// ParsePortMapping reads published:target/protocol, the target defaults to the published port and the protocol to tcp
+func ParsePortMapping(spec string) (PortMapping, error) {
+	m := PortMapping{Protocol: "tcp"}
+	ports := spec
+	if i := strings.Index(spec, "/"); i != -1 {
+		ports, m.Protocol = spec[:i], spec[i+1:]
+	}
+	if m.Protocol != "tcp" && m.Protocol != "udp" {
+		return m, fmt.Errorf("Invalid protocol %q in %s, expected tcp or udp", m.Protocol, spec)
+	}
+
+	parts := strings.Split(ports, ":")
+	if len(parts) > 2 {
+		return m, fmt.Errorf("Invalid port mapping %s, expected published:target/protocol", spec)
+	}
+	values := make([]int, len(parts))
+	for i, part := range parts {
+		port, err := strconv.Atoi(part)
+		if err != nil || port < 1 || port > 65535 {
+			return m, fmt.Errorf("Invalid port %q in %s", part, spec)
+		}
+		values[i] = port
+	}
+	m.Published, m.Target = values[0], values[len(values)-1]
+	return m, nil
+}
+
+func (m PortMapping) String() string {
+	return strconv.Itoa(m.Published) + ":" + strconv.Itoa(m.Target) + "/" + m.Protocol
+}
+
+// Rules returns the rules publishing the mapping for the container of address containerIP on docker_gwbridge
+func (m PortMapping) Rules(containerIP string) []Rule {
+	return NewLinkPort(containerIP, m.Published, m.Target, m.Protocol)
+}
+
+// ContainerPort is a port mapping of a container
+type ContainerPort struct {
+	ContainerID string
+	Mapping     PortMapping
+}
+
+var containerID = regexp.MustCompile(`^[0-9a-f]{12,64}$`)
+
+// gwbridgeAddress reads the address of a container on docker_gwbridge
+const gwbridgeAddress = `docker inspect -f '{{with index .NetworkSettings.Networks "docker_gwbridge"}}{{.IPAddress}}{{end}}'`
+
+// BootScript returns a shell script applying the rules of the ports once their containers are started.
+// Addresses change when the containers restart, they are read again when the script runs
+func BootScript(ports []ContainerPort) (string, error) {
+	lines := []string{
+		"#!/bin/sh",
+		"# Ports published by mikrodock-cli, do not edit",
+		"address() {",
+		"	for i in $(seq 1 30); do",
+		"		ip=$(" + gwbridgeAddress + ` "$1" 2>/dev/null)`,
+		`		[ -n "$ip" ] && return 0`,
+		"		sleep 2",
+		"	done",
+		"	return 1",
+		"}",
+		"status=0",
+	}
+	for _, port := range ports {
+		if !containerID.MatchString(port.ContainerID) {
+			return "", fmt.Errorf("Invalid container ID %q", port.ContainerID)
+		}
+		rules := port.Mapping.Rules("10.0.0.1")
+		for _, rule := range rules {
+			if err := rule.Validate(); err != nil {
+				return "", fmt.Errorf("Invalid mapping %s : %s", port.Mapping, err)
+			}
+		}
+
+		lines = append(lines, "# "+port.ContainerID+" "+port.Mapping.String())
+		lines = append(lines, "if address "+port.ContainerID+"; then")
+		for _, rule := range port.Mapping.Rules("$ip") {
+			lines = append(lines, "	iptables -w "+rule.Render(CHECK)+" 2>/dev/null || iptables -w "+rule.Render(APPEND)+" || status=1")
+		}
+		lines = append(lines, "else", "	status=1", "fi")
+	}
+	lines = append(lines, "exit $status")
+	return strings.Join(lines, "\n") + "\n", nil
+}
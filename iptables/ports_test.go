@@ -0,0 +1,51 @@
+package iptables
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePortMapping(t *testing.T) {
+	mappings := map[string]string{
+		"8080:80/tcp": "8080:80/tcp",
+		"53:5353/udp": "53:5353/udp",
+		"443":         "443:443/tcp",
+		"9000:9001":   "9000:9001/tcp",
+	}
+	for spec, expected := range mappings {
+		m, err := ParsePortMapping(spec)
+		if err != nil {
+			t.Errorf("Got an unexpected error while parsing %s : %s\r\n", spec, err)
+			continue
+		}
+		if m.String() != expected {
+			t.Errorf("Expected %s, got %s\r\n", expected, m)
+		}
+	}
+
+	for _, spec := range []string{"", "80/sctp", "1:2:3", "0:80", "80:70000", "http"} {
+		if _, err := ParsePortMapping(spec); err == nil {
+			t.Errorf("Got no error while an error was expected (%s)\r\n", spec)
+		}
+	}
+}
+
+func TestBootScript(t *testing.T) {
+	m, _ := ParsePortMapping("53:5353/udp")
+	script, err := BootScript([]ContainerPort{{ContainerID: "0123456789ab", Mapping: m}})
+	if err != nil {
+		t.Fatalf("Got an unexpected error while generating the script : %s\r\n", err)
+	}
+	expected := "iptables -w -t nat -C DOCKER ! -i docker_gwbridge -p udp -m udp --dport 53 -j DNAT --to-destination $ip:5353 2>/dev/null || " +
+		"iptables -w -t nat -A DOCKER ! -i docker_gwbridge -p udp -m udp --dport 53 -j DNAT --to-destination $ip:5353 || status=1"
+	if !strings.Contains(script, expected) {
+		t.Errorf("The DNAT rule is missing from\r\n%s\r\n", script)
+	}
+	if !strings.Contains(script, "if address 0123456789ab; then") {
+		t.Errorf("The address of the container is not read in\r\n%s\r\n", script)
+	}
+
+	if _, err = BootScript([]ContainerPort{{ContainerID: "abc; reboot", Mapping: m}}); err == nil {
+		t.Errorf("Got no error while an error was expected (container ID)\r\n")
+	}
+}
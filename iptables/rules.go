@@ -1,61 +1,218 @@
 package iptables
 
-import "strconv"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 type IPTableAction int
 
 const (
 	APPEND IPTableAction = iota
 	DELETE
+	CHECK
+	INSERT
 )
 
-// iptables -t nat -A POSTROUTING -s 172.18.0.5/32 -d 172.18.0.5/32 -p tcp -m tcp --dport 8080 -j MASQUERADE
-// iptables -t nat -A DOCKER -p tcp -m tcp --dport 8080 -j DNAT --to-destination 172.18.0.5:8080
-// iptables -A DOCKER -d 172.18.0.5/32 ! -i docker_gwbridge -o docker_gwbridge -p tcp -m tcp --dport 8080 -j ACCEPT
+var actionFlags = map[IPTableAction]string{APPEND: "-A", DELETE: "-D", CHECK: "-C", INSERT: "-I"}
 
-// TODO: Differ internal port and external port
-func NewLinkPort(proxyIP string, destinationPort int) []string {
-	rules := make([]string, 3)
-	rules[0] = createRule("nat", "POSTROUTING", "", APPEND, "tcp", proxyIP+"/32", proxyIP+"/32", 8080, "", "MASQUERADE")
-	rules[1] = createRule("nat", "DOCKER", "", APPEND, "tcp", "", proxyIP+":"+strconv.Itoa(destinationPort), 8080, "", "DNAT")
-	rules[2] = createRule("", "DOCKER", "docker_gwbridge", APPEND, "tcp", "", proxyIP+"/32", 8080, "", "ACCEPT")
+// Rule is an iptables rule. An empty table is the filter table, the port match is the module of the protocol
+type Rule struct {
+	Table           string
+	Chain           string
+	Protocol        string
+	Source          string
+	Destination     string
+	InInterface     string
+	NotInInterface  bool
+	OutInterface    string
+	NotOutInterface bool
+	DestinationPort int
+	Comment         string
+	Target          string
+	// ToDestination is the address and port of a DNAT target
+	ToDestination string
 }
 
-func createRule(tableName, chainName, mustOutputInterface string, action IPTableAction, protocol, source, destination string, destinationPort int, natDestination, jump string) string {
-	rule := "iptables "
-	if tableName != "" {
-		rule += "-t " + tableName + " "
+// Tokens are passed to a shell through SSH, they cannot hold anything else
+var validToken = regexp.MustCompile(`^[A-Za-z0-9_.:/\-]+$`)
+
+// Validate checks the rule can be rendered and run
+func (r Rule) Validate() error {
+	if r.Chain == "" {
+		return fmt.Errorf("The chain of the rule is required")
 	}
-	switch action {
-	case APPEND:
-		rule += "-A "
-		break
-	case DELETE:
-		rule += "-D "
-		break
+	if r.Target == "" {
+		return fmt.Errorf("The target of the rule is required")
 	}
-
-	if mustOutputInterface != "" {
-		rule += "! -i " + mustOutputInterface + " -o " + mustOutputInterface + " "
+	fields := map[string]string{
+		"table": r.Table, "chain": r.Chain, "protocol": r.Protocol, "source": r.Source, "destination": r.Destination,
+		"input interface": r.InInterface, "output interface": r.OutInterface, "comment": r.Comment, "target": r.Target,
+		"DNAT destination": r.ToDestination,
 	}
+	for name, value := range fields {
+		if value != "" && !validToken.MatchString(value) {
+			return fmt.Errorf("Invalid %s %q", name, value)
+		}
+	}
+	if r.NotInInterface && r.InInterface == "" || r.NotOutInterface && r.OutInterface == "" {
+		return fmt.Errorf("Cannot negate an empty interface")
+	}
+	if r.DestinationPort != 0 {
+		if r.Protocol != "tcp" && r.Protocol != "udp" {
+			return fmt.Errorf("A destination port needs the tcp or udp protocol, got %q", r.Protocol)
+		}
+		if r.DestinationPort < 0 || r.DestinationPort > 65535 {
+			return fmt.Errorf("Invalid destination port %d", r.DestinationPort)
+		}
+	}
+	if (r.Target == "DNAT") != (r.ToDestination != "") {
+		return fmt.Errorf("The DNAT target needs a destination, and only it")
+	}
+	return nil
+}
 
-	rule += chainName + " -m " + protocol + " -p " + protocol + " "
-
-	if source != "" {
-		rule += "-s " + source + " "
+// Render returns the arguments of iptables for the rule, in the order of iptables-save
+func (r Rule) Render(action IPTableAction) string {
+	var args []string
+	if r.Table != "" && r.Table != "filter" {
+		args = append(args, "-t", r.Table)
+	}
+	args = append(args, actionFlags[action], r.Chain)
+	if r.Source != "" {
+		args = append(args, "-s", r.Source)
+	}
+	if r.Destination != "" {
+		args = append(args, "-d", r.Destination)
+	}
+	if r.InInterface != "" {
+		if r.NotInInterface {
+			args = append(args, "!")
+		}
+		args = append(args, "-i", r.InInterface)
 	}
+	if r.OutInterface != "" {
+		if r.NotOutInterface {
+			args = append(args, "!")
+		}
+		args = append(args, "-o", r.OutInterface)
+	}
+	if r.Protocol != "" {
+		args = append(args, "-p", r.Protocol)
+	}
+	if r.DestinationPort != 0 {
+		args = append(args, "-m", r.Protocol, "--dport", strconv.Itoa(r.DestinationPort))
+	}
+	if r.Comment != "" {
+		args = append(args, "-m", "comment", "--comment", r.Comment)
+	}
+	args = append(args, "-j", r.Target)
+	if r.ToDestination != "" {
+		args = append(args, "--to-destination", r.ToDestination)
+	}
+	return strings.Join(args, " ")
+}
 
-	if destination != "" {
-		rule += "-d " + destination + " "
+// Command returns the iptables command running the action on the rule, waiting for the xtables lock
+func (r Rule) Command(action IPTableAction) (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
 	}
+	return "iptables -w " + r.Render(action), nil
+}
 
-	rule += "--dport " + strconv.Itoa(destinationPort) + " "
+// ParseRule reads an iptables command or a rule of iptables-save
+func ParseRule(line string) (Rule, IPTableAction, error) {
+	var r Rule
+	action := APPEND
+	actionSet := false
+	tokens := strings.Fields(line)
+	if len(tokens) != 0 && tokens[0] == "iptables" {
+		tokens = tokens[1:]
+	}
 
-	rule += "-j " + jump + " "
+	negate := false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token == "!" {
+			negate = true
+			continue
+		}
+		if token == "-w" {
+			continue
+		}
+		if i+1 >= len(tokens) {
+			return r, action, fmt.Errorf("Missing the value of %s", token)
+		}
+		value := tokens[i+1]
+		i++
+		if negate && token != "-i" && token != "-o" {
+			return r, action, fmt.Errorf("Cannot negate %s", token)
+		}
 
-	if jump == "DNAT" {
-		rule += "--to-destination " + natDestination
+		switch token {
+		case "-t":
+			r.Table = value
+		case "-A", "-D", "-C", "-I":
+			if actionSet {
+				return r, action, fmt.Errorf("More than one action in %q", line)
+			}
+			for a, flag := range actionFlags {
+				if flag == token {
+					action = a
+				}
+			}
+			actionSet = true
+			r.Chain = value
+		case "-s":
+			r.Source = value
+		case "-d":
+			r.Destination = value
+		case "-i":
+			r.InInterface, r.NotInInterface = value, negate
+		case "-o":
+			r.OutInterface, r.NotOutInterface = value, negate
+		case "-p":
+			r.Protocol = value
+		case "-m":
+			if value == "comment" {
+				if i+2 >= len(tokens) || tokens[i+1] != "--comment" {
+					return r, action, fmt.Errorf("Missing the comment of the comment match")
+				}
+				r.Comment = strings.Trim(tokens[i+2], `"`)
+				i += 2
+			} else if value != r.Protocol {
+				return r, action, fmt.Errorf("Unsupported match %s", value)
+			}
+		case "--dport":
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				return r, action, fmt.Errorf("Invalid destination port %s", value)
+			}
+			r.DestinationPort = port
+		case "-j":
+			r.Target = value
+		case "--to-destination":
+			r.ToDestination = value
+		default:
+			return r, action, fmt.Errorf("Unsupported option %s", token)
+		}
+		negate = false
+	}
+	if !actionSet {
+		return r, action, fmt.Errorf("No action in %q", line)
 	}
+	return r, action, r.Validate()
+}
 
-	return rule
+// NewLinkPort returns the rules publishing publishedPort of the host on destinationPort of proxyIP,
+// like Docker does for the containers of docker_gwbridge
+func NewLinkPort(proxyIP string, publishedPort int, destinationPort int, protocol string) []Rule {
+	return []Rule{
+		{Table: "nat", Chain: "POSTROUTING", Source: proxyIP + "/32", Destination: proxyIP + "/32", Protocol: protocol, DestinationPort: destinationPort, Target: "MASQUERADE"},
+		{Table: "nat", Chain: "DOCKER", InInterface: "docker_gwbridge", NotInInterface: true, Protocol: protocol, DestinationPort: publishedPort, Target: "DNAT", ToDestination: proxyIP + ":" + strconv.Itoa(destinationPort)},
+		{Chain: "DOCKER", Destination: proxyIP + "/32", InInterface: "docker_gwbridge", NotInInterface: true, OutInterface: "docker_gwbridge", Protocol: protocol, DestinationPort: destinationPort, Target: "ACCEPT"},
+	}
 }
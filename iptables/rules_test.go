@@ -0,0 +1,172 @@
+package iptables
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderParse(t *testing.T) {
+	lines := []string{
+		"-t nat -A POSTROUTING -s 172.18.0.5/32 -d 172.18.0.5/32 -p tcp -m tcp --dport 80 -j MASQUERADE",
+		"-t nat -A DOCKER ! -i docker_gwbridge -p tcp -m tcp --dport 8080 -j DNAT --to-destination 172.18.0.5:80",
+		"-A DOCKER -d 172.18.0.5/32 ! -i docker_gwbridge -o docker_gwbridge -p tcp -m tcp --dport 80 -j ACCEPT",
+		"-I INPUT -i eth0 -p udp -m udp --dport 4789 -m comment --comment mikrodock-vxlan -j DROP",
+		"-D FORWARD -o docker0 -j DOCKER",
+	}
+	for _, line := range lines {
+		rule, action, err := ParseRule(line)
+		if err != nil {
+			t.Errorf("Got an unexpected error while parsing %s : %s\r\n", line, err)
+			continue
+		}
+		if rendered := rule.Render(action); rendered != line {
+			t.Errorf("Expected %s, got %s\r\n", line, rendered)
+		}
+	}
+
+	rule, action, err := ParseRule("iptables -w -t filter -C DOCKER -p tcp --dport 443 -j ACCEPT")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing a command : %s\r\n", err)
+	}
+	if action != CHECK || rule.Table != "filter" || rule.DestinationPort != 443 {
+		t.Errorf("Unexpected rule %#v\r\n", rule)
+	}
+	if cmd, _ := rule.Command(APPEND); cmd != "iptables -w -A DOCKER -p tcp -m tcp --dport 443 -j ACCEPT" {
+		t.Errorf("Unexpected command %s\r\n", cmd)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	lines := []string{
+		"-p tcp -j ACCEPT",
+		"-A INPUT -p tcp --dport",
+		"-A INPUT ! -s 10.0.0.1 -j ACCEPT",
+		"-A INPUT -m state --state NEW -j ACCEPT",
+		"-A INPUT --dport 80 -j ACCEPT",
+		"-A INPUT -p tcp --dport 70000 -j ACCEPT",
+		"-A INPUT -j DNAT",
+		"-A INPUT -s 10.0.0.1;reboot -j ACCEPT",
+		"-A INPUT -D OUTPUT -j ACCEPT",
+	}
+	for _, line := range lines {
+		if _, _, err := ParseRule(line); err == nil {
+			t.Errorf("Got no error while an error was expected (%s)\r\n", line)
+		}
+	}
+}
+
+func TestNft(t *testing.T) {
+	rules := NewLinkPort("172.18.0.5", 8080, 80, "tcp")
+	expected := []string{
+		"nft add rule ip nat POSTROUTING ip saddr 172.18.0.5/32 ip daddr 172.18.0.5/32 tcp dport 80 masquerade",
+		`nft add rule ip nat DOCKER iifname != "docker_gwbridge" tcp dport 8080 dnat to 172.18.0.5:80`,
+		`nft add rule ip filter DOCKER iifname != "docker_gwbridge" oifname "docker_gwbridge" ip daddr 172.18.0.5/32 tcp dport 80 accept`,
+	}
+	for i, rule := range rules {
+		nft, err := rule.Nft()
+		if err != nil {
+			t.Errorf("Got an unexpected error while rendering the nft rule : %s\r\n", err)
+		}
+		if nft != expected[i] {
+			t.Errorf("Expected %s, got %s\r\n", expected[i], nft)
+		}
+	}
+
+	nft, _ := Rule{Chain: "FORWARD", OutInterface: "docker0", Protocol: "udp", Comment: "to-docker", Target: "DOCKER"}.Nft()
+	if nft != `nft add rule ip filter FORWARD oifname "docker0" meta l4proto udp jump DOCKER comment "to-docker"` {
+		t.Errorf("Unexpected nft rule %s\r\n", nft)
+	}
+}
+
+// fakeRunner keeps the rules of a host in memory
+type fakeRunner struct {
+	rules    []string
+	failOn   string
+	restored bool
+}
+
+func (f *fakeRunner) SSHCommand(cmd string) (string, string, error) {
+	switch {
+	case cmd == "iptables-save":
+		return strings.Join(f.rules, "\n") + "\n", "", nil
+	case strings.HasPrefix(cmd, "iptables-restore"):
+		lines := strings.Split(cmd, "\n")
+		f.rules = nil
+		for _, line := range lines[1 : len(lines)-1] {
+			if line != "" {
+				f.rules = append(f.rules, line)
+			}
+		}
+		f.restored = true
+		return "", "", nil
+	case f.failOn != "" && strings.Contains(cmd, f.failOn) && !strings.Contains(cmd, " -C "):
+		return "", "iptables: No chain/target/match by that name.", errors.New("exit status 1")
+	}
+
+	rule, action, err := ParseRule(strings.TrimSuffix(cmd, " 2>/dev/null; echo $?"))
+	if err != nil {
+		return "", err.Error(), err
+	}
+	key := rule.Render(APPEND)
+	index := -1
+	for i, r := range f.rules {
+		if r == key {
+			index = i
+		}
+	}
+	switch action {
+	case CHECK:
+		if index == -1 {
+			return "1\n", "", nil
+		}
+		return "0\n", "", nil
+	case APPEND:
+		f.rules = append(f.rules, key)
+	case DELETE:
+		f.rules = append(f.rules[:index], f.rules[index+1:]...)
+	}
+	return "", "", nil
+}
+
+func TestApplyDelete(t *testing.T) {
+	rules := NewLinkPort("172.18.0.5", 8080, 80, "tcp")
+	runner := &fakeRunner{rules: []string{rules[0].Render(APPEND)}}
+
+	if err := Apply(runner, rules); err != nil {
+		t.Fatalf("Got an unexpected error while applying the rules : %s\r\n", err)
+	}
+	if err := Apply(runner, rules); err != nil {
+		t.Fatalf("Got an unexpected error while applying the rules again : %s\r\n", err)
+	}
+	if len(runner.rules) != 3 {
+		t.Errorf("Expected the 3 rules once, got %v\r\n", runner.rules)
+	}
+	for _, rule := range rules {
+		if present, err := Check(runner, rule); err != nil || !present {
+			t.Errorf("The rule %s is missing (%v)\r\n", rule.Render(APPEND), err)
+		}
+	}
+
+	if err := Delete(runner, rules[1:]); err != nil {
+		t.Fatalf("Got an unexpected error while deleting the rules : %s\r\n", err)
+	}
+	if err := Delete(runner, rules[1:]); err != nil {
+		t.Fatalf("Got an unexpected error while deleting the rules again : %s\r\n", err)
+	}
+	if len(runner.rules) != 1 {
+		t.Errorf("Expected 1 rule left, got %v\r\n", runner.rules)
+	}
+}
+
+func TestApplyRollback(t *testing.T) {
+	rules := NewLinkPort("172.18.0.5", 8080, 80, "tcp")
+	runner := &fakeRunner{failOn: "-A DOCKER -d"}
+
+	if err := Apply(runner, rules); err == nil {
+		t.Fatalf("Got no error while an error was expected (failing rule)\r\n")
+	}
+	if !runner.restored || len(runner.rules) != 0 {
+		t.Errorf("The rules were not rolled back, got %v\r\n", runner.rules)
+	}
+}
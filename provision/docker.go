@@ -0,0 +1,25 @@
+package provision
+
+// The Docker daemon listens on DockerTCPHost, or on GuardedDockerTCPHost when the TLS guard
+// of the cluster checks the revoked certificates on 2376 in its place
+const (
+	DockerTCPHost        = "tcp://0.0.0.0:2376"
+	GuardedDockerTCPHost = "tcp://127.0.0.1:2377"
+)
+
+// DockerDefaults renders the DOCKER_OPTS of /etc/default/docker
+func DockerDefaults(additionnalConfig string, tlsGuard bool) string {
+	host := DockerTCPHost
+	if tlsGuard {
+		host = GuardedDockerTCPHost
+	}
+
+	return `DOCKER_OPTS='
+-H ` + host + `
+-H unix:///var/run/docker.sock
+--tlsverify
+--tlscacert /etc/docker/ca.cert
+--tlscert /etc/docker/cert.pem
+--tlskey /etc/docker/key.pem
+` + additionnalConfig + `'`
+}
@@ -0,0 +1,25 @@
+package provision
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDockerDefaults(t *testing.T) {
+	conf := DockerDefaults("--cluster-store=consul://127.0.0.1:8500", false)
+	if !strings.Contains(conf, "-H "+DockerTCPHost+"\n") || strings.Contains(conf, GuardedDockerTCPHost) {
+		t.Errorf("Docker should listen on %s :\r\n%s\r\n", DockerTCPHost, conf)
+	}
+	if !strings.HasSuffix(conf, "--cluster-store=consul://127.0.0.1:8500'") {
+		t.Errorf("The additionnal config is missing :\r\n%s\r\n", conf)
+	}
+
+	// The TLS guard listens on 2376, Docker must not take it back
+	conf = DockerDefaults("", true)
+	if !strings.Contains(conf, "-H "+GuardedDockerTCPHost+"\n") || strings.Contains(conf, ":2376") {
+		t.Errorf("Docker should listen on %s behind the TLS guard :\r\n%s\r\n", GuardedDockerTCPHost, conf)
+	}
+	if !strings.Contains(conf, "-H unix:///var/run/docker.sock\n") {
+		t.Errorf("The TLS guard needs the Docker socket :\r\n%s\r\n", conf)
+	}
+}
@@ -18,7 +18,7 @@ type Provider interface {
 	DetectDocker() (bool, error)
 	InstallDocker() error
 	InstallPackage(pkgName string) error
-	ConfigureDocker(additionnalConfig string) error
+	ConfigureDocker(additionnalConfig string, tlsGuard bool) error
 	StartDocker() error
 	StopDocker() error
 	CreateDirectory(path string) error
@@ -79,10 +79,16 @@ func (pb *BaseProvider) ConnectDocker(partikleCertPath string, dockerCertPath st
 		return nil, err
 	}
 
+	transport := &http.Transport{
+		TLSClientConfig: tlsc,
+	}
+	if pb.Driver != nil && len(pb.Driver.GetBaseDriver().JumpHosts) != 0 {
+		// The Docker port is not reachable directly, tunnel it through SSH
+		transport.Dial = pb.Driver.Dial
+	}
+
 	client = &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: tlsc,
-		},
+		Transport: transport,
 	}
 
 	headers := make(map[string]string)
@@ -42,7 +42,7 @@ func (up *UbuntuProvider) InstallPackage(pkgName string) error {
 	return err
 }
 
-func (up *UbuntuProvider) ConfigureDocker(additionnalConfig string) error {
+func (up *UbuntuProvider) ConfigureDocker(additionnalConfig string, tlsGuard bool) error {
 
 	fmt.Printf("Additionnal config : %s \n", additionnalConfig)
 
@@ -57,14 +57,7 @@ func (up *UbuntuProvider) ConfigureDocker(additionnalConfig string) error {
 	{{range .EngineOptions.Env}}export \"{{ printf "%q" . }}\"
 	{{end}} */
 
-	dockerConf := `DOCKER_OPTS='
--H tcp://0.0.0.0:2376
--H unix:///var/run/docker.sock
---tlsverify
---tlscacert /etc/docker/ca.cert
---tlscert /etc/docker/cert.pem
---tlskey /etc/docker/key.pem
-` + additionnalConfig + `'`
+	dockerConf := DockerDefaults(additionnalConfig, tlsGuard)
 
 	fmt.Printf("Total config : %s \n", dockerConf)
 
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "******"
+
+// Entry is one remote action executed against a node
+type Entry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	Node       string    `json:"node"`
+	NodeIP     string    `json:"node_ip"`
+	Action     string    `json:"action"`
+	Command    string    `json:"command"`
+	ExitStatus int       `json:"exit_status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// Log is an append-only JSON lines file
+type Log struct {
+	Path string
+
+	lock    sync.Mutex
+	secrets []string
+}
+
+// Filter selects entries while reading a Log, empty fields match everything
+type Filter struct {
+	Node       string
+	Since      time.Time
+	OnlyFailed bool
+}
+
+// Assignments of sensitive variables, like DO_TOKEN=xxx or --password xxx
+var secretPattern = regexp.MustCompile(`(?i)((?:[A-Z0-9_-]*(?:TOKEN|SECRET|PASSWORD|PASSPHRASE|APIKEY|API_KEY))["']?(?:=|\s+))("[^"]*"|'[^']*'|[^\s'";&|]+)`)
+
+func NewLog(path string) *Log {
+	return &Log{
+		Path:    path,
+		secrets: make([]string, 0),
+	}
+}
+
+// AddSecret registers a value that must never be written to the log
+func (l *Log) AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	l.lock.Lock()
+	l.secrets = append(l.secrets, secret)
+	l.lock.Unlock()
+}
+
+// Redact hides the registered secrets and the values of sensitive variables
+func (l *Log) Redact(cmd string) string {
+	l.lock.Lock()
+	for _, secret := range l.secrets {
+		cmd = strings.Replace(cmd, secret, redacted, -1)
+	}
+	l.lock.Unlock()
+	return secretPattern.ReplaceAllString(cmd, "${1}"+redacted)
+}
+
+// Record appends an entry, the command is redacted first
+func (l *Log) Record(entry Entry) error {
+	entry.Command = l.Redact(entry.Command)
+	entry.Error = l.Redact(entry.Error)
+	if entry.User == "" {
+		entry.User = LocalUser()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	file, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Read returns the entries matching the filter, oldest first
+func (l *Log) Read(filter Filter) ([]Entry, error) {
+	entries := make([]Entry, 0)
+
+	file, err := os.Open(l.Path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, err
+		}
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func (f Filter) Match(entry Entry) bool {
+	if f.Node != "" && f.Node != entry.Node && f.Node != entry.NodeIP {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if f.OnlyFailed && entry.ExitStatus == 0 {
+		return false
+	}
+	return true
+}
+
+// LocalUser is the user running the CLI, the real one when run through sudo
+func LocalUser() string {
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		return sudoUser
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "unknown"
+	}
+	return u.Username
+}
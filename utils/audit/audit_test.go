@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	log := NewLog("/tmp/audit.log")
+	log.AddSecret("abcdef123456")
+
+	cases := map[string]string{
+		"echo 'export DO_TOKEN=abcdef123456' >> ~/.env":  "echo 'export DO_TOKEN=******' >> ~/.env",
+		"echo 'export CONSUL_IP=1.2.3.4:8081' >> ~/.env": "echo 'export CONSUL_IP=1.2.3.4:8081' >> ~/.env",
+		"vault login --password hunter2":                 "vault login --password ******",
+		"curl -H token abcdef123456":                     "curl -H token ******",
+	}
+
+	for cmd, expected := range cases {
+		if res := log.Redact(cmd); res != expected {
+			t.Errorf("Got an unexpected result while Redact : %s (expected %s)\r\n", res, expected)
+		}
+	}
+}
+
+func TestRecordRead(t *testing.T) {
+	log := NewLog("/tmp/audit.log")
+	defer os.Remove("/tmp/audit.log")
+	log.AddSecret("s3cr3t")
+
+	now := time.Now()
+	err := log.Record(Entry{Time: now.Add(-time.Hour), Node: "konsultant", Action: "exec", Command: "echo s3cr3t"})
+	if err != nil {
+		t.Errorf("Got an unexpected error while Record : %s\r\n", err)
+	}
+	log.Record(Entry{Time: now, Node: "klerk", Action: "exec", Command: "false", ExitStatus: 1})
+
+	entries, err := log.Read(Filter{})
+	if err != nil {
+		t.Errorf("Got an unexpected error while Read : %s\r\n", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d\r\n", len(entries))
+	}
+	if entries[0].Command != "echo ******" {
+		t.Errorf("Got an unredacted command : %s\r\n", entries[0].Command)
+	}
+	if entries[0].User == "" {
+		t.Errorf("Got no local user\r\n")
+	}
+
+	entries, _ = log.Read(Filter{Node: "klerk"})
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 entry for klerk, got %d\r\n", len(entries))
+	}
+	entries, _ = log.Read(Filter{Since: now.Add(-time.Minute)})
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 recent entry, got %d\r\n", len(entries))
+	}
+	entries, _ = log.Read(Filter{OnlyFailed: true})
+	if len(entries) != 1 || entries[0].Node != "klerk" {
+		t.Errorf("Expected only the failed entry\r\n")
+	}
+
+	entries, err = NewLog("/tmp/noaudit.log").Read(Filter{})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Got an unexpected result while Read (nofile)\r\n")
+	}
+}
@@ -0,0 +1,260 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// LetsEncryptDirectory is the default ACME server, a local Pebble server is https://localhost:14000/dir
+const LetsEncryptDirectory = "https://acme-v02.api.letsencrypt.org/directory"
+
+// The ACME challenges proving the control of a domain
+const (
+	HTTP01 = "http-01"
+	DNS01  = "dns-01"
+)
+
+var acmeDomain = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ACMESolver answers the challenges of the ACME server. The value is the key authorization served at
+// /.well-known/acme-challenge/<token> for HTTP01, the content of the TXT record _acme-challenge.<domain> for DNS01
+type ACMESolver interface {
+	Present(domain string, token string, value string) error
+	CleanUp(domain string, token string, value string) error
+}
+
+// ExecDNSSolver runs a hook to manage the TXT records of DNS01 : <hook> present|cleanup <fqdn> <value>.
+// The hook returns once the record is visible to the ACME server
+type ExecDNSSolver struct {
+	Hook string
+}
+
+func (s ExecDNSSolver) run(action string, domain string, value string) error {
+	fqdn := "_acme-challenge." + domain + "."
+	out, err := exec.Command(s.Hook, action, fqdn, value).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Cannot %s the TXT record of %s : %s %s", action, fqdn, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Present creates the TXT record of the domain
+func (s ExecDNSSolver) Present(domain string, token string, value string) error {
+	return s.run("present", domain, value)
+}
+
+// CleanUp removes the TXT record of the domain
+func (s ExecDNSSolver) CleanUp(domain string, token string, value string) error {
+	return s.run("cleanup", domain, value)
+}
+
+// ValidateACMEDomains lowercases the domains and checks that the challenge can prove them, wildcards need DNS01
+func ValidateACMEDomains(domains []string, challenge string) ([]string, error) {
+	if challenge != HTTP01 && challenge != DNS01 {
+		return nil, fmt.Errorf("Unknown challenge %s, expected %s or %s", challenge, HTTP01, DNS01)
+	}
+	if len(domains) == 0 {
+		return nil, errors.New("No domain to certify")
+	}
+	seen := make(map[string]bool)
+	valid := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if !acmeDomain.MatchString(domain) {
+			return nil, fmt.Errorf("Invalid domain %s", domain)
+		}
+		if strings.HasPrefix(domain, "*.") && challenge != DNS01 {
+			return nil, fmt.Errorf("The wildcard %s needs the %s challenge", domain, DNS01)
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			valid = append(valid, domain)
+		}
+	}
+	return valid, nil
+}
+
+// NewACMEClient creates a client of the ACME directory signing with the account key, a new key when keyPEM is empty.
+// caFile trusts the TLS certificate of a private server such as Pebble
+func NewACMEClient(directory string, caFile string, keyPEM []byte) (*acme.Client, error) {
+	client := &acme.Client{DirectoryURL: directory, UserAgent: "mikrodock-cli"}
+	if len(keyPEM) == 0 {
+		key, err := generateKey(ECDSAP256Key, 0)
+		if err != nil {
+			return nil, err
+		}
+		client.Key = key
+	} else {
+		block, _ := pem.Decode(keyPEM)
+		if block == nil {
+			return nil, errors.New("No key found in the ACME account")
+		}
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the ACME account key : %s", err)
+		}
+		client.Key = key
+	}
+
+	if caFile != "" {
+		caCert, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("No certificate found in " + caFile)
+		}
+		client.HTTPClient = &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		}
+	}
+	return client, nil
+}
+
+// ACMEAccountKey returns the PEM of the account key of the client
+func ACMEAccountKey(client *acme.Client) ([]byte, error) {
+	block, err := marshalKey(client.Key, false)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// RegisterACME registers the account of the client and agrees to the terms of service, a known account is reused
+func RegisterACME(ctx context.Context, client *acme.Client, email string) (*acme.Account, error) {
+	account := &acme.Account{}
+	if email != "" {
+		account.Contact = []string{"mailto:" + email}
+	}
+	registered, err := client.Register(ctx, account, acme.AcceptTOS)
+	if err == acme.ErrAccountAlreadyExists {
+		registered, err = client.GetReg(ctx, "")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot register the ACME account : %s", err)
+	}
+	return registered, nil
+}
+
+// ObtainACME orders a certificate of the domains, the first one being its subject, and answers the pending
+// authorizations with the challenge. It returns the PEM of the certificate chain and of its new key
+func ObtainACME(ctx context.Context, client *acme.Client, domains []string, challenge string, solver ACMESolver) ([]byte, []byte, error) {
+	ids := make([]acme.AuthzID, len(domains))
+	for i, domain := range domains {
+		ids[i] = acme.AuthzID{Type: "dns", Value: domain}
+	}
+	order, err := client.AuthorizeOrder(ctx, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Cannot order the certificate of %s : %s", strings.Join(domains, ", "), err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Cannot get the authorization %s : %s", authzURL, err)
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+		if err = answerChallenge(ctx, client, authz, challenge, solver); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, nil, fmt.Errorf("The order of %s failed : %s", strings.Join(domains, ", "), err)
+	}
+	key, err := generateKey(ECDSAP256Key, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: strings.TrimPrefix(domains[0], "*.")},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Cannot finalize the order of %s : %s", strings.Join(domains, ", "), err)
+	}
+
+	var certPEM bytes.Buffer
+	for _, der := range chain {
+		pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	keyBlock, err := marshalKey(key, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM.Bytes(), pem.EncodeToMemory(keyBlock), nil
+}
+
+func answerChallenge(ctx context.Context, client *acme.Client, authz *acme.Authorization, challenge string, solver ACMESolver) error {
+	domain := authz.Identifier.Value
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == challenge {
+			chal = c
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("The ACME server offers no %s challenge for %s", challenge, domain)
+	}
+
+	var value string
+	var err error
+	if challenge == DNS01 {
+		value, err = client.DNS01ChallengeRecord(chal.Token)
+	} else {
+		value, err = client.HTTP01ChallengeResponse(chal.Token)
+	}
+	if err != nil {
+		return err
+	}
+	if err = solver.Present(domain, chal.Token, value); err != nil {
+		return err
+	}
+	if _, err = client.Accept(ctx, chal); err == nil {
+		_, err = client.WaitAuthorization(ctx, authz.URI)
+	}
+	if cleanErr := solver.CleanUp(domain, chal.Token, value); err == nil {
+		err = cleanErr
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot prove the control of %s : %s", domain, err)
+	}
+	return nil
+}
+
+// ParseCertificatePEM parses the first certificate of a PEM chain
+func ParseCertificatePEM(chain []byte) (*x509.Certificate, error) {
+	for {
+		var block *pem.Block
+		block, chain = pem.Decode(chain)
+		if block == nil {
+			return nil, errors.New("No certificate found")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
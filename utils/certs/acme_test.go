@@ -0,0 +1,278 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeACME answers the RFC 8555 flow used by ObtainACME, the signatures of the requests are not checked
+func fakeACME(t *testing.T) *httptest.Server {
+	caKey, err := generateKey(ECDSAP256Key, 0)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while generating CA key : %s\r\n", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	var mutex sync.Mutex
+	accepted := make(map[string]bool)
+	var domains []string
+	var leaf []byte
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Header().Set("Replay-Nonce", "nonce")
+		var jws struct {
+			Payload string `json:"payload"`
+		}
+		json.NewDecoder(r.Body).Decode(&jws)
+		payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+
+		order := func() map[string]interface{} {
+			o := map[string]interface{}{"status": "ready", "finalize": server.URL + "/finalize"}
+			var authz []string
+			for _, d := range domains {
+				authz = append(authz, server.URL+"/authz/"+d)
+				if !accepted[d] {
+					o["status"] = "pending"
+				}
+			}
+			o["authorizations"] = authz
+			if leaf != nil {
+				o["status"] = "valid"
+				o["certificate"] = server.URL + "/cert"
+			}
+			return o
+		}
+
+		switch p := r.URL.Path; {
+		case p == "/dir":
+			json.NewEncoder(w).Encode(map[string]string{
+				"newNonce":   server.URL + "/nonce",
+				"newAccount": server.URL + "/account",
+				"newOrder":   server.URL + "/order",
+			})
+		case p == "/nonce":
+		case p == "/account":
+			w.Header().Set("Location", server.URL+"/account/1")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"status":"valid"}`))
+		case p == "/order":
+			var req struct {
+				Identifiers []struct{ Value string } `json:"identifiers"`
+			}
+			json.Unmarshal(payload, &req)
+			for _, id := range req.Identifiers {
+				domains = append(domains, id.Value)
+			}
+			w.Header().Set("Location", server.URL+"/order/1")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(order())
+		case p == "/order/1":
+			w.Header().Set("Location", server.URL+"/order/1")
+			json.NewEncoder(w).Encode(order())
+		case strings.HasPrefix(p, "/authz/"):
+			d := strings.TrimPrefix(p, "/authz/")
+			status := "pending"
+			if accepted[d] {
+				status = "valid"
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":     status,
+				"identifier": map[string]string{"type": "dns", "value": d},
+				"challenges": []map[string]string{
+					{"type": HTTP01, "url": server.URL + "/chal/" + d, "token": "token-" + d, "status": status},
+					{"type": DNS01, "url": server.URL + "/chal/" + d, "token": "token-" + d, "status": status},
+				},
+			})
+		case strings.HasPrefix(p, "/chal/"):
+			d := strings.TrimPrefix(p, "/chal/")
+			accepted[d] = true
+			json.NewEncoder(w).Encode(map[string]string{"type": HTTP01, "url": server.URL + p, "token": "token-" + d, "status": "valid"})
+		case p == "/finalize":
+			var req struct {
+				CSR string `json:"csr"`
+			}
+			json.Unmarshal(payload, &req)
+			der, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+			csr, err := x509.ParseCertificateRequest(der)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			leaf, _ = x509.CreateCertificate(rand.Reader, &x509.Certificate{
+				SerialNumber: big.NewInt(2),
+				DNSNames:     csr.DNSNames,
+				NotBefore:    time.Now(),
+				NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+			}, caCert, csr.PublicKey, caKey)
+			w.Header().Set("Location", server.URL+"/order/1")
+			json.NewEncoder(w).Encode(order())
+		case p == "/cert":
+			w.Header().Set("Content-Type", "application/pem-certificate-chain")
+			pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: leaf})
+			pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+type recordingSolver struct {
+	tokens    map[string]string
+	presented map[string]string
+	cleaned   []string
+}
+
+func (s *recordingSolver) Present(domain string, token string, value string) error {
+	s.tokens[domain] = token
+	s.presented[domain] = value
+	return nil
+}
+
+func (s *recordingSolver) CleanUp(domain string, token string, value string) error {
+	s.cleaned = append(s.cleaned, domain)
+	return nil
+}
+
+func TestValidateACMEDomains(t *testing.T) {
+	domains, err := ValidateACMEDomains([]string{"Shop.Example.com", "shop.example.com", "*.example.com"}, DNS01)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while validating domains : %s\r\n", err)
+	}
+	if len(domains) != 2 || domains[0] != "shop.example.com" || domains[1] != "*.example.com" {
+		t.Errorf("Unexpected domains %v\r\n", domains)
+	}
+
+	for _, invalid := range [][]string{{"*.example.com"}, {"localhost"}, {"shop.example.com;rm"}, {}} {
+		if _, err = ValidateACMEDomains(invalid, HTTP01); err == nil {
+			t.Errorf("Got no error while an Error was expected (%v)\r\n", invalid)
+		}
+	}
+	if _, err = ValidateACMEDomains([]string{"shop.example.com"}, "tls-alpn-01"); err == nil {
+		t.Errorf("Got no error while an Error was expected (tls-alpn-01)\r\n")
+	}
+}
+
+// checkObtainACME registers an account on the ACME directory, obtains a certificate of two domains and
+// loads the account key back
+func checkObtainACME(t *testing.T, directory string, caFile string) {
+	client, err := NewACMEClient(directory, caFile, nil)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating client : %s\r\n", err)
+	}
+	if _, err = RegisterACME(context.Background(), client, "ops@example.com"); err != nil {
+		t.Fatalf("Got an unexpected error while registering : %s\r\n", err)
+	}
+
+	solver := &recordingSolver{tokens: make(map[string]string), presented: make(map[string]string)}
+	certPEM, keyPEM, err := ObtainACME(context.Background(), client, []string{"shop.example.com", "api.example.com"}, HTTP01, solver)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while obtaining cert : %s\r\n", err)
+	}
+	if len(solver.presented) != 2 || len(solver.cleaned) != 2 {
+		t.Errorf("Expected 2 challenges presented and cleaned up, got %v %v\r\n", solver.presented, solver.cleaned)
+	}
+	for domain, value := range solver.presented {
+		if !strings.HasPrefix(value, solver.tokens[domain]+".") {
+			t.Errorf("Unexpected key authorization %s of %s\r\n", value, domain)
+		}
+	}
+
+	cert, err := ParseCertificatePEM(certPEM)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing cert : %s\r\n", err)
+	}
+	if err = cert.VerifyHostname("api.example.com"); err != nil {
+		t.Errorf("Got an unexpected error while verifying hostname : %s\r\n", err)
+	}
+	if !strings.Contains(string(keyPEM), "EC PRIVATE KEY") {
+		t.Errorf("Unexpected key %s\r\n", keyPEM)
+	}
+
+	// The account key is kept between runs
+	accountKey, err := ACMEAccountKey(client)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while exporting account key : %s\r\n", err)
+	}
+	again, err := NewACMEClient(directory, caFile, accountKey)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while loading account key : %s\r\n", err)
+	}
+	if !again.Key.Public().(*ecdsa.PublicKey).Equal(client.Key.Public()) {
+		t.Errorf("The account key does not load back\r\n")
+	}
+	if _, err = RegisterACME(context.Background(), again, ""); err != nil {
+		t.Errorf("Got an unexpected error while registering the known account : %s\r\n", err)
+	}
+}
+
+func TestObtainACME(t *testing.T) {
+	server := fakeACME(t)
+	defer server.Close()
+	checkObtainACME(t, server.URL+"/dir", "")
+}
+
+// TestPebble runs against a Pebble server checking the nonces and the signatures of the requests :
+//
+//	PEBBLE_VA_ALWAYS_VALID=1 pebble -config test/config/pebble-config.json
+//	PEBBLE_DIRECTORY=https://localhost:14000/dir PEBBLE_CA=test/certs/pebble.minica.pem go test ./utils/certs/
+func TestPebble(t *testing.T) {
+	directory := os.Getenv("PEBBLE_DIRECTORY")
+	if directory == "" {
+		t.Skip("PEBBLE_DIRECTORY is not set")
+	}
+	checkObtainACME(t, directory, os.Getenv("PEBBLE_CA"))
+}
+
+func TestExecDNSSolver(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "mikrodock-acme")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating temp dir : %s\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	hook := path.Join(dir, "hook.sh")
+	ioutil.WriteFile(hook, []byte("#!/bin/sh\necho \"$@\" >> "+path.Join(dir, "calls")+"\n"), 0700)
+	solver := ExecDNSSolver{Hook: hook}
+	if err = solver.Present("example.com", "token", "value"); err != nil {
+		t.Fatalf("Got an unexpected error while presenting : %s\r\n", err)
+	}
+	if err = solver.CleanUp("example.com", "token", "value"); err != nil {
+		t.Fatalf("Got an unexpected error while cleaning up : %s\r\n", err)
+	}
+	calls, _ := ioutil.ReadFile(path.Join(dir, "calls"))
+	if string(calls) != "present _acme-challenge.example.com. value\ncleanup _acme-challenge.example.com. value\n" {
+		t.Errorf("Unexpected hook calls %q\r\n", calls)
+	}
+
+	if err = (ExecDNSSolver{Hook: path.Join(dir, "missing")}).Present("example.com", "token", "value"); err == nil {
+		t.Errorf("Got no error while an Error was expected (missing hook)\r\n")
+	}
+}
@@ -0,0 +1,96 @@
+package certs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// FormatSerial writes a serial number as colon separated hex bytes, like openssl and Vault
+func FormatSerial(serial *big.Int) string {
+	hex := serial.Text(16)
+	if len(hex)%2 == 1 {
+		hex = "0" + hex
+	}
+	parts := make([]string, 0, len(hex)/2)
+	for i := 0; i < len(hex); i += 2 {
+		parts = append(parts, hex[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// ParseSerial is the reverse of FormatSerial, the colons are optional
+func ParseSerial(s string) (*big.Int, error) {
+	serial, ok := new(big.Int).SetString(strings.Replace(s, ":", "", -1), 16)
+	if !ok {
+		return nil, fmt.Errorf("Invalid serial number %s", s)
+	}
+	return serial, nil
+}
+
+// GenerateCRL writes to crlFile one CRL for every CA of cas, listing the revoked serials.
+// Each CA is a certificate file, or a bundle starting with the certificate, and its key file
+func GenerateCRL(crlFile string, cas [][2]string, revoked []*big.Int, validity time.Duration) error {
+	if len(cas) == 0 {
+		return errors.New("No CA to sign the CRL")
+	}
+	if validity == 0 {
+		validity = DefaultValidity
+	}
+
+	now := time.Now()
+	entries := make([]pkix.RevokedCertificate, len(revoked))
+	for i, serial := range revoked {
+		entries[i] = pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: now}
+	}
+
+	var out bytes.Buffer
+	signed := make([][]byte, 0, len(cas))
+	for _, ca := range cas {
+		pair, err := tls.LoadX509KeyPair(ca[0], ca[1])
+		if err != nil {
+			return fmt.Errorf("Cannot load CA %s : %s", ca[0], err)
+		}
+		duplicate := false
+		for _, raw := range signed {
+			duplicate = duplicate || bytes.Equal(raw, pair.Certificate[0])
+		}
+		if duplicate {
+			continue
+		}
+		signed = append(signed, pair.Certificate[0])
+
+		caCert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return err
+		}
+		if caCert.KeyUsage&x509.KeyUsageCRLSign == 0 {
+			return fmt.Errorf("The CA %s cannot sign CRLs, rotate it first", ca[0])
+		}
+
+		der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:              big.NewInt(now.UnixNano()),
+			ThisUpdate:          now.Add(-5 * time.Minute),
+			NextUpdate:          now.Add(validity),
+			RevokedCertificates: entries,
+		}, caCert, pair.PrivateKey.(crypto.Signer))
+		if err != nil {
+			return err
+		}
+		if err = pem.Encode(&out, &pem.Block{Type: "X509 CRL", Bytes: der}); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(crlFile, out.Bytes(), 0644)
+}
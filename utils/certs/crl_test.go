@@ -0,0 +1,86 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestSerial(t *testing.T) {
+	serial := big.NewInt(0x1abcd)
+	if s := FormatSerial(serial); s != "01:ab:cd" {
+		t.Errorf("Expected 01:ab:cd, got %s\r\n", s)
+	}
+	parsed, err := ParseSerial("01:ab:cd")
+	if err != nil || parsed.Cmp(serial) != 0 {
+		t.Errorf("Serial does not parse back : %v %s\r\n", parsed, err)
+	}
+	if _, err = ParseSerial("zz"); err == nil {
+		t.Errorf("Got no error while an Error was expected (zz)\r\n")
+	}
+}
+
+func TestGenerateCRL(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "mikrodock-crl")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating temp dir : %s\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	gen := NewX509CertGenerator()
+	cas := make([][2]string, 0)
+	for _, name := range []string{"old", "new"} {
+		ca := [2]string{path.Join(dir, name+".cert"), path.Join(dir, name+".key")}
+		if err = gen.GenerateCA(&CAOpts{CertFile: ca[0], KeyFile: ca[1], Organization: name, KeyType: ECDSAP256Key}); err != nil {
+			t.Fatalf("Got an unexpected error while generating CA : %s\r\n", err)
+		}
+		cas = append(cas, ca)
+	}
+	// The same CA twice is signed once
+	cas = append(cas, cas[0])
+
+	crlFile := path.Join(dir, "revoked.crl")
+	revoked := []*big.Int{big.NewInt(42), big.NewInt(43)}
+	if err = GenerateCRL(crlFile, cas, revoked, 0); err != nil {
+		t.Fatalf("Got an unexpected error while generating CRL : %s\r\n", err)
+	}
+
+	data, _ := ioutil.ReadFile(crlFile)
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			t.Fatalf("Got an unexpected error while parsing CRL : %s\r\n", err)
+		}
+		ca, _ := ReadCertificate(cas[count][0])
+		if err = crl.CheckSignatureFrom(ca); err != nil {
+			t.Errorf("CRL %d is not signed by its CA : %s\r\n", count, err)
+		}
+		if len(crl.RevokedCertificateEntries) != 2 || crl.RevokedCertificateEntries[0].SerialNumber.Int64() != 42 {
+			t.Errorf("Unexpected revoked entries in CRL %d\r\n", count)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 CRLs, got %d\r\n", count)
+	}
+
+	// A CA without the CRL signing usage is refused
+	legacy := path.Join(dir, "leaf")
+	err = gen.GenerateCert(&CertOpts{CAFile: cas[0][0], CAKeyFile: cas[0][1], CertFile: legacy + ".cert", KeyFile: legacy + ".key", MainHost: "leaf"})
+	if err != nil {
+		t.Fatalf("Got an unexpected error while generating cert : %s\r\n", err)
+	}
+	if err = GenerateCRL(crlFile, [][2]string{{legacy + ".cert", legacy + ".key"}}, revoked, 0); err == nil {
+		t.Errorf("Got no error while an Error was expected (no CRL usage)\r\n")
+	}
+}
@@ -0,0 +1,52 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// FileCAGenerator uses an existing CA, typically issued by a corporate CA, instead of generating one.
+// Leaf certificates are signed locally with its key
+type FileCAGenerator struct {
+	X509CertGenerator
+	CACertFile string
+	CAKeyFile  string
+}
+
+// NewFileCAGenerator checks that certFile is a CA matching keyFile
+func NewFileCAGenerator(certFile string, keyFile string) (*FileCAGenerator, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load CA %s : %s", certFile, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("Cannot parse CA %s : %s", certFile, err)
+	}
+	if !cert.IsCA || cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return nil, fmt.Errorf("%s is not allowed to sign certificates", certFile)
+	}
+	return &FileCAGenerator{CACertFile: certFile, CAKeyFile: keyFile}, nil
+}
+
+func (fGen *FileCAGenerator) GenerateCACert(certFile string, keyFile string, organization string, keyBits int) error {
+	return fGen.GenerateCA(&CAOpts{CertFile: certFile, KeyFile: keyFile})
+}
+
+// GenerateCA copies the existing CA to the given files, the other options are ignored
+func (fGen *FileCAGenerator) GenerateCA(opts *CAOpts) error {
+	cert, err := ioutil.ReadFile(fGen.CACertFile)
+	if err != nil {
+		return err
+	}
+	key, err := ioutil.ReadFile(fGen.CAKeyFile)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(opts.CertFile, cert, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(opts.KeyFile, key, 0600)
+}
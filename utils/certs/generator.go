@@ -1,18 +1,36 @@
 package certs
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"math/big"
 	"net"
 	"os"
 	"time"
 )
 
+// KeyType is the algorithm of a generated private key
+type KeyType string
+
+const (
+	RSAKey       KeyType = "rsa"
+	ECDSAP256Key KeyType = "ecdsa-p256"
+	ECDSAP384Key KeyType = "ecdsa-p384"
+	Ed25519Key   KeyType = "ed25519"
+)
+
+// DefaultValidity is used when no validity is given
+const DefaultValidity = 24 * 1080 * time.Hour
+
 type CertOpts struct {
 	CertFile, KeyFile, CAFile, CAKeyFile, Organization string
 	MainHost                                           string
@@ -20,10 +38,26 @@ type CertOpts struct {
 	AliasIPs                                           []string
 	KeyBits                                            int
 	MasterMode                                         bool
+
+	// KeyType defaults to RSAKey of KeyBits bits
+	KeyType KeyType
+	// PKCS8 writes the key as PKCS#8 instead of PKCS#1 (RSA) or SEC 1 (ECDSA), Ed25519 keys always are
+	PKCS8 bool
+	// Validity defaults to DefaultValidity
+	Validity time.Duration
+}
+
+type CAOpts struct {
+	CertFile, KeyFile, Organization string
+	KeyBits                         int
+	KeyType                         KeyType
+	PKCS8                           bool
+	Validity                        time.Duration
 }
 
 type CertGenerator interface {
 	GenerateCACert(certFile, keyFile, organization string, keyBits int) error
+	GenerateCA(opts *CAOpts) error
 	GenerateCert(opts *CertOpts) error
 }
 
@@ -33,132 +67,125 @@ func NewX509CertGenerator() *X509CertGenerator {
 	return &X509CertGenerator{}
 }
 
-func (xGen *X509CertGenerator) GenerateCACert(certFile string, keyFile string, organization string, keyBits int) error {
-	template, err := xGen.newCertificate(organization)
-	if err != nil {
-		return err
+func ParseKeyType(name string) (KeyType, error) {
+	switch KeyType(name) {
+	case "", RSAKey:
+		return RSAKey, nil
+	case ECDSAP256Key, ECDSAP384Key, Ed25519Key:
+		return KeyType(name), nil
+	default:
+		return "", fmt.Errorf("Unknown key type %s", name)
 	}
+}
 
-	template.IsCA = true
-	template.KeyUsage |= x509.KeyUsageCertSign
-	template.KeyUsage |= x509.KeyUsageKeyEncipherment
-	template.KeyUsage |= x509.KeyUsageKeyAgreement
+func (xGen *X509CertGenerator) GenerateCACert(certFile string, keyFile string, organization string, keyBits int) error {
+	return xGen.GenerateCA(&CAOpts{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		Organization: organization,
+		KeyBits:      keyBits,
+	})
+}
 
-	priv, err := rsa.GenerateKey(rand.Reader, keyBits)
+func (xGen *X509CertGenerator) GenerateCA(opts *CAOpts) error {
+	template, err := xGen.newCertificate(opts.Organization, opts.Validity)
 	if err != nil {
 		return err
 	}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
-	if err != nil {
-		return err
-	}
+	template.Subject.CommonName = opts.Organization
+	template.IsCA = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
 
-	certOut, err := os.Create(certFile)
+	priv, err := generateKey(opts.KeyType, opts.KeyBits)
 	if err != nil {
 		return err
 	}
 
-	pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
-	certOut.Close()
-
-	keyOut, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
 	if err != nil {
 		return err
-
 	}
 
-	pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
-	keyOut.Close()
-
-	return nil
+	return writeCertAndKey(opts.CertFile, opts.KeyFile, derBytes, priv, opts.PKCS8)
 }
 
 func (xGen *X509CertGenerator) GenerateCert(opts *CertOpts) error {
-	template, err := xGen.newCertificate(opts.Organization)
+	template, err := xGen.newCertificate(opts.Organization, opts.Validity)
 	if err != nil {
 		return err
 	}
 
-	// Server certificate
-	if len(opts.AliasIPs) != 1 || opts.AliasIPs[0] != "" || len(opts.AliasHosts) != 1 || opts.AliasHosts[0] != "" {
-		if len(opts.AliasHosts) > 1 && opts.AliasHosts[0] != "" {
-			for _, h := range opts.AliasHosts {
-				template.DNSNames = append(template.DNSNames, h)
-			}
-		}
+	template.Subject.CommonName = opts.MainHost
 
-		if len(opts.AliasIPs) > 1 && opts.AliasIPs[0] != "" {
-			for _, h := range opts.AliasIPs {
-				template.IPAddresses = append(template.IPAddresses, net.ParseIP(h))
-			}
+	// Every host and IP is a SAN, the main host included
+	seen := make(map[string]bool)
+	for _, h := range append([]string{opts.MainHost}, opts.AliasHosts...) {
+		if h == "" || seen[h] {
+			continue
 		}
-
-		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
-
-		if opts.MasterMode {
-			template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+		seen[h] = true
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
 		}
-
-	} else {
-		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
-		template.KeyUsage = x509.KeyUsageDigitalSignature
+	}
+	for _, h := range opts.AliasIPs {
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		ip := net.ParseIP(h)
+		if ip == nil {
+			return fmt.Errorf("Invalid IP address %s", h)
+		}
+		template.IPAddresses = append(template.IPAddresses, ip)
 	}
 
 	if opts.MasterMode {
-		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
-		template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
-
+		// Server certificate, also used to authenticate as a client
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
 	} else {
 		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
 	}
-	// client
 
 	tlsCert, err := tls.LoadX509KeyPair(opts.CAFile, opts.CAKeyFile)
 	if err != nil {
 		return err
 	}
 
-	priv, err := rsa.GenerateKey(rand.Reader, opts.KeyBits)
+	priv, err := generateKey(opts.KeyType, opts.KeyBits)
 	if err != nil {
 		return err
 	}
-
-	x509Cert, err := x509.ParseCertificate(tlsCert.Certificate[0])
-	if err != nil {
-		return err
+	if _, ok := priv.(*rsa.PrivateKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
 	}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, template, x509Cert, &priv.PublicKey, tlsCert.PrivateKey)
+	x509Cert, err := x509.ParseCertificate(tlsCert.Certificate[0])
 	if err != nil {
 		return err
 	}
 
-	certOut, err := os.Create(opts.CertFile)
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, x509Cert, priv.Public(), tlsCert.PrivateKey)
 	if err != nil {
 		return err
 	}
 
-	pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
-	certOut.Close()
+	return writeCertAndKey(opts.CertFile, opts.KeyFile, derBytes, priv, opts.PKCS8)
+}
 
-	keyOut, err := os.OpenFile(opts.KeyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return err
+func (xGen *X509CertGenerator) newCertificate(org string, validity time.Duration) (*x509.Certificate, error) {
+	if validity == 0 {
+		validity = DefaultValidity
 	}
 
-	pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
-	keyOut.Close()
-
-	return nil
-}
-
-func (xGen *X509CertGenerator) newCertificate(org string) (*x509.Certificate, error) {
 	now := time.Now()
 	// need to set notBefore slightly in the past to account for time
 	// skew in the VMs otherwise the certs sometimes are not yet valid
 	notBefore := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute()-5, 0, 0, time.Local)
-	notAfter := notBefore.Add(time.Hour * 24 * 1080)
+	notAfter := notBefore.Add(validity)
 
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
@@ -174,8 +201,81 @@ func (xGen *X509CertGenerator) newCertificate(org string) (*x509.Certificate, er
 		NotBefore: notBefore,
 		NotAfter:  notAfter,
 
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyAgreement,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
 		BasicConstraintsValid: true,
 	}, nil
 
 }
+
+func generateKey(keyType KeyType, keyBits int) (crypto.Signer, error) {
+	switch keyType {
+	case "", RSAKey:
+		if keyBits == 0 {
+			keyBits = 2048
+		}
+		return rsa.GenerateKey(rand.Reader, keyBits)
+	case ECDSAP256Key:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ECDSAP384Key:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case Ed25519Key:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return nil, fmt.Errorf("Unknown key type %s", keyType)
+	}
+}
+
+func marshalKey(priv crypto.Signer, pkcs8 bool) (*pem.Block, error) {
+	switch key := priv.(type) {
+	case *rsa.PrivateKey:
+		if !pkcs8 {
+			return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}, nil
+		}
+	case *ecdsa.PrivateKey:
+		if !pkcs8 {
+			der, err := x509.MarshalECPrivateKey(key)
+			if err != nil {
+				return nil, err
+			}
+			return &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, nil
+		}
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+}
+
+func writeCertAndKey(certFile string, keyFile string, derBytes []byte, priv crypto.Signer, pkcs8 bool) error {
+	keyBlock, err := marshalKey(priv, pkcs8)
+	if err != nil {
+		return err
+	}
+
+	certOut, err := os.Create(certFile)
+	if err != nil {
+		return err
+	}
+
+	if err = pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}); err != nil {
+		certOut.Close()
+		return err
+	}
+	if err = certOut.Close(); err != nil {
+		return err
+	}
+
+	keyOut, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err = pem.Encode(keyOut, keyBlock); err != nil {
+		keyOut.Close()
+		return err
+	}
+	return keyOut.Close()
+}
@@ -0,0 +1,227 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func readPEM(t *testing.T, file string) *pem.Block {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while reading %s : %s\r\n", file, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatalf("No PEM block in %s\r\n", file)
+	}
+	return block
+}
+
+func TestGenerateCert(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "mikrodock-certs")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating temp dir : %s\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caCert := path.Join(dir, "ca.cert")
+	caKey := path.Join(dir, "ca.key")
+	gen := NewX509CertGenerator()
+	if err = gen.GenerateCA(&CAOpts{CertFile: caCert, KeyFile: caKey, Organization: "Test-CA", KeyType: ECDSAP256Key}); err != nil {
+		t.Fatalf("Got an unexpected error while generating CA : %s\r\n", err)
+	}
+	ca, err := ReadCertificate(caCert)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while reading CA : %s\r\n", err)
+	}
+	if !ca.IsCA || ca.Subject.CommonName != "Test-CA" {
+		t.Errorf("CA is not a CA named Test-CA : %v %s\r\n", ca.IsCA, ca.Subject.CommonName)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	tests := []struct {
+		name     string
+		opts     CertOpts
+		keyBlock string
+		dns      []string
+		ips      []string
+		server   bool
+		validity time.Duration
+	}{
+		{
+			name:     "rsa-pkcs1",
+			opts:     CertOpts{MainHost: "node.local", AliasIPs: []string{"10.0.0.1"}, KeyBits: 1024, MasterMode: true},
+			keyBlock: "RSA PRIVATE KEY",
+			dns:      []string{"node.local"},
+			ips:      []string{"10.0.0.1"},
+			server:   true,
+		},
+		{
+			name:     "rsa-pkcs8",
+			opts:     CertOpts{MainHost: "node.local", KeyBits: 1024, PKCS8: true},
+			keyBlock: "PRIVATE KEY",
+			dns:      []string{"node.local"},
+		},
+		{
+			name:     "ecdsa-p256",
+			opts:     CertOpts{MainHost: "node.local", AliasHosts: []string{"a.local", "b.local", "node.local"}, KeyType: ECDSAP256Key, MasterMode: true},
+			keyBlock: "EC PRIVATE KEY",
+			dns:      []string{"node.local", "a.local", "b.local"},
+			server:   true,
+		},
+		{
+			name:     "ecdsa-p384-pkcs8",
+			opts:     CertOpts{MainHost: "node.local", AliasIPs: []string{"10.0.0.1", "127.0.0.1"}, KeyType: ECDSAP384Key, PKCS8: true, MasterMode: true},
+			keyBlock: "PRIVATE KEY",
+			dns:      []string{"node.local"},
+			ips:      []string{"10.0.0.1", "127.0.0.1"},
+			server:   true,
+		},
+		{
+			name:     "ed25519",
+			opts:     CertOpts{MainHost: "10.0.0.2", KeyType: Ed25519Key, Validity: 48 * time.Hour, MasterMode: true},
+			keyBlock: "PRIVATE KEY",
+			ips:      []string{"10.0.0.2"},
+			server:   true,
+			validity: 48 * time.Hour,
+		},
+	}
+
+	for _, test := range tests {
+		opts := test.opts
+		opts.CAFile = caCert
+		opts.CAKeyFile = caKey
+		opts.CertFile = path.Join(dir, test.name+".cert")
+		opts.KeyFile = path.Join(dir, test.name+".key")
+		opts.Organization = "Test"
+
+		if err = gen.GenerateCert(&opts); err != nil {
+			t.Errorf("[%s] Got an unexpected error while generating cert : %s\r\n", test.name, err)
+			continue
+		}
+
+		if block := readPEM(t, opts.KeyFile); block.Type != test.keyBlock {
+			t.Errorf("[%s] Expected a %s block, got %s\r\n", test.name, test.keyBlock, block.Type)
+		}
+		if stat, _ := os.Stat(opts.KeyFile); stat.Mode().Perm() != 0600 {
+			t.Errorf("[%s] Expected key mode 0600, got %o\r\n", test.name, stat.Mode().Perm())
+		}
+
+		pair, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			t.Errorf("[%s] Got an unexpected error while loading key pair : %s\r\n", test.name, err)
+			continue
+		}
+		switch key := pair.PrivateKey.(type) {
+		case *rsa.PrivateKey:
+			if opts.KeyType != "" && opts.KeyType != RSAKey {
+				t.Errorf("[%s] Got an RSA key\r\n", test.name)
+			}
+		case *ecdsa.PrivateKey:
+			if (opts.KeyType == ECDSAP256Key && key.Curve != elliptic.P256()) || (opts.KeyType == ECDSAP384Key && key.Curve != elliptic.P384()) {
+				t.Errorf("[%s] Got a key on curve %s\r\n", test.name, key.Curve.Params().Name)
+			}
+		case ed25519.PrivateKey:
+			if opts.KeyType != Ed25519Key {
+				t.Errorf("[%s] Got an Ed25519 key\r\n", test.name)
+			}
+		default:
+			t.Errorf("[%s] Unexpected key type %T\r\n", test.name, key)
+		}
+
+		cert, err := ReadCertificate(opts.CertFile)
+		if err != nil {
+			t.Errorf("[%s] Got an unexpected error while reading cert : %s\r\n", test.name, err)
+			continue
+		}
+
+		if cert.Subject.CommonName != opts.MainHost {
+			t.Errorf("[%s] Expected CN %s, got %s\r\n", test.name, opts.MainHost, cert.Subject.CommonName)
+		}
+		if len(cert.DNSNames) != len(test.dns) {
+			t.Errorf("[%s] Expected DNS names %v, got %v\r\n", test.name, test.dns, cert.DNSNames)
+		} else {
+			for i := range test.dns {
+				if cert.DNSNames[i] != test.dns[i] {
+					t.Errorf("[%s] Expected DNS names %v, got %v\r\n", test.name, test.dns, cert.DNSNames)
+				}
+			}
+		}
+		if len(cert.IPAddresses) != len(test.ips) {
+			t.Errorf("[%s] Expected IPs %v, got %v\r\n", test.name, test.ips, cert.IPAddresses)
+		} else {
+			for i := range test.ips {
+				if cert.IPAddresses[i].String() != test.ips[i] {
+					t.Errorf("[%s] Expected IPs %v, got %v\r\n", test.name, test.ips, cert.IPAddresses)
+				}
+			}
+		}
+
+		validity := test.validity
+		if validity == 0 {
+			validity = DefaultValidity
+		}
+		if got := cert.NotAfter.Sub(cert.NotBefore); got != validity {
+			t.Errorf("[%s] Expected validity %s, got %s\r\n", test.name, validity, got)
+		}
+
+		usage := x509.ExtKeyUsageClientAuth
+		if test.server {
+			usage = x509.ExtKeyUsageServerAuth
+		}
+		verify := x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{usage}}
+		if test.server {
+			if len(test.dns) > 0 {
+				verify.DNSName = test.dns[0]
+			} else {
+				verify.DNSName = test.ips[0]
+			}
+		}
+		if _, err = cert.Verify(verify); err != nil {
+			t.Errorf("[%s] Got an unexpected error while verifying cert : %s\r\n", test.name, err)
+		}
+		if !test.server {
+			if _, err = cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}); err == nil {
+				t.Errorf("[%s] A client certificate must not be valid for server auth\r\n", test.name)
+			}
+		}
+	}
+}
+
+func TestGenerateCertInvalidIP(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "mikrodock-certs")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating temp dir : %s\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	gen := NewX509CertGenerator()
+	caCert := path.Join(dir, "ca.cert")
+	caKey := path.Join(dir, "ca.key")
+	if err = gen.GenerateCACert(caCert, caKey, "Test-CA", 1024); err != nil {
+		t.Fatalf("Got an unexpected error while generating CA : %s\r\n", err)
+	}
+
+	err = gen.GenerateCert(&CertOpts{
+		CAFile:    caCert,
+		CAKeyFile: caKey,
+		CertFile:  path.Join(dir, "cert.pem"),
+		KeyFile:   path.Join(dir, "key.pem"),
+		MainHost:  "node.local",
+		AliasIPs:  []string{"not-an-ip"},
+	})
+	if err == nil {
+		t.Errorf("Expected an error for an invalid IP\r\n")
+	}
+}
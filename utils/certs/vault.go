@@ -0,0 +1,237 @@
+package certs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultTokenEnv is read when no token is given to the Vault issuer
+const VaultTokenEnv = "VAULT_TOKEN"
+
+// VaultCertGenerator issues the certificates from a Vault PKI secrets engine.
+// The key type and the allowed names are the ones of the Vault role, the CA key never leaves Vault
+type VaultCertGenerator struct {
+	Address string
+	Mount   string
+	// Role issues server certificates, ClientRole client only certificates (Role when empty)
+	Role       string
+	ClientRole string
+	Token      string
+	Client     *http.Client
+}
+
+type vaultIssueResponse struct {
+	Errors []string `json:"errors"`
+	Data   struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"private_key"`
+		IssuingCA   string `json:"issuing_ca"`
+	} `json:"data"`
+}
+
+// ParseVaultURL reads an issuer written http(s)://host:port/<mount>?role=<role>[&client-role=<role>]
+func ParseVaultURL(raw string) (*VaultCertGenerator, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Vault URL %s : %s", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("Invalid Vault URL %s : the scheme must be http or https", raw)
+	}
+	mount := strings.Trim(u.Path, "/")
+	role := u.Query().Get("role")
+	if mount == "" || role == "" {
+		return nil, fmt.Errorf("Invalid Vault URL %s : a PKI mount and a role are needed", raw)
+	}
+	return &VaultCertGenerator{
+		Address:    u.Scheme + "://" + u.Host,
+		Mount:      mount,
+		Role:       role,
+		ClientRole: u.Query().Get("client-role"),
+	}, nil
+}
+
+// URL is the reverse of ParseVaultURL, the token is never part of it
+func (vGen *VaultCertGenerator) URL() string {
+	query := url.Values{}
+	query.Set("role", vGen.Role)
+	if vGen.ClientRole != "" {
+		query.Set("client-role", vGen.ClientRole)
+	}
+	return vGen.Address + "/" + vGen.Mount + "?" + query.Encode()
+}
+
+func (vGen *VaultCertGenerator) GenerateCACert(certFile string, keyFile string, organization string, keyBits int) error {
+	return vGen.GenerateCA(&CAOpts{CertFile: certFile, KeyFile: keyFile})
+}
+
+// GenerateCA fetches the issuing CA of the mount. No key is written, opts.KeyFile is removed if it exists
+func (vGen *VaultCertGenerator) GenerateCA(opts *CAOpts) error {
+	resp, err := vGen.client().Get(vGen.Address + "/v1/" + vGen.Mount + "/ca/pem")
+	if err != nil {
+		return fmt.Errorf("Cannot reach Vault : %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte("CERTIFICATE")) {
+		return fmt.Errorf("Cannot read CA of Vault mount %s : %s %s", vGen.Mount, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err = ioutil.WriteFile(opts.CertFile, append(bytes.TrimSpace(body), '\n'), 0644); err != nil {
+		return err
+	}
+	if err = os.Remove(opts.KeyFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GenerateCert asks Vault to issue a certificate, the CA files of opts are not used
+func (vGen *VaultCertGenerator) GenerateCert(opts *CertOpts) error {
+	role := vGen.Role
+	if !opts.MasterMode && vGen.ClientRole != "" {
+		role = vGen.ClientRole
+	}
+
+	var dns, ips []string
+	for _, h := range opts.AliasHosts {
+		if h != "" && h != opts.MainHost {
+			dns = append(dns, h)
+		}
+	}
+	for _, h := range opts.AliasIPs {
+		if h != "" {
+			ips = append(ips, h)
+		}
+	}
+
+	request := map[string]string{
+		"common_name":        opts.MainHost,
+		"alt_names":          strings.Join(dns, ","),
+		"ip_sans":            strings.Join(ips, ","),
+		"format":             "pem",
+		"private_key_format": "pem",
+	}
+	if opts.PKCS8 {
+		request["private_key_format"] = "pkcs8"
+	}
+	if opts.Validity > 0 {
+		request["ttl"] = fmt.Sprintf("%ds", int64(opts.Validity/time.Second))
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := vGen.do(http.MethodPost, "/issue/"+role, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	issued := vaultIssueResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		return fmt.Errorf("Cannot decode Vault response (%s) : %s", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Vault refused to issue %s : %s", opts.MainHost, strings.Join(issued.Errors, ", "))
+	}
+	if issued.Data.Certificate == "" || issued.Data.PrivateKey == "" {
+		return fmt.Errorf("Vault returned no certificate for %s", opts.MainHost)
+	}
+
+	if err = ioutil.WriteFile(opts.CertFile, []byte(issued.Data.Certificate+"\n"), 0644); err != nil {
+		return err
+	}
+	keyOut, err := os.OpenFile(opts.KeyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = keyOut.WriteString(issued.Data.PrivateKey + "\n"); err != nil {
+		keyOut.Close()
+		return err
+	}
+	return keyOut.Close()
+}
+
+func (vGen *VaultCertGenerator) client() *http.Client {
+	if vGen.Client != nil {
+		return vGen.Client
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// Revoke revokes the certificate of serial (colon separated hex) in Vault
+func (vGen *VaultCertGenerator) Revoke(serial string) error {
+	payload, err := json.Marshal(map[string]string{"serial_number": serial})
+	if err != nil {
+		return err
+	}
+	resp, err := vGen.do(http.MethodPost, "/revoke", payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		issued := vaultIssueResponse{}
+		json.NewDecoder(resp.Body).Decode(&issued)
+		return fmt.Errorf("Vault refused to revoke %s : %s %s", serial, resp.Status, strings.Join(issued.Errors, ", "))
+	}
+	return nil
+}
+
+// FetchCRL writes the CRL of the mount to crlFile.
+// Vault CRLs expire (72h by default), it has to be fetched again before that
+func (vGen *VaultCertGenerator) FetchCRL(crlFile string) error {
+	resp, err := vGen.client().Get(vGen.Address + "/v1/" + vGen.Mount + "/crl/pem")
+	if err != nil {
+		return fmt.Errorf("Cannot reach Vault : %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte("X509 CRL")) {
+		return fmt.Errorf("Cannot read CRL of Vault mount %s : %s", vGen.Mount, resp.Status)
+	}
+	return ioutil.WriteFile(crlFile, append(bytes.TrimSpace(body), '\n'), 0644)
+}
+
+func (vGen *VaultCertGenerator) do(method string, endpoint string, payload []byte) (*http.Response, error) {
+	token := vGen.Token
+	if token == "" {
+		token = os.Getenv(VaultTokenEnv)
+	}
+	if token == "" {
+		return nil, errors.New("No Vault token, set " + VaultTokenEnv)
+	}
+
+	req, err := http.NewRequest(method, vGen.Address+"/v1/"+vGen.Mount+endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := vGen.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot reach Vault : %s", err)
+	}
+	return resp, nil
+}
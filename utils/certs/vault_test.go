@@ -0,0 +1,231 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeVault answers the PKI endpoints used by VaultCertGenerator with a local CA
+func fakeVault(t *testing.T, dir string) *httptest.Server {
+	caCert := path.Join(dir, "vault-ca.cert")
+	caKey := path.Join(dir, "vault-ca.key")
+	if err := NewX509CertGenerator().GenerateCACert(caCert, caKey, "Vault-CA", 1024); err != nil {
+		t.Fatalf("Got an unexpected error while generating CA : %s\r\n", err)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/pki/ca/pem":
+			pem, _ := ioutil.ReadFile(caCert)
+			w.Write(pem)
+		case strings.HasPrefix(r.URL.Path, "/v1/pki/issue/"):
+			if r.Header.Get("X-Vault-Token") != "root" {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			req := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&req)
+			opts := &CertOpts{
+				CAFile:     caCert,
+				CAKeyFile:  caKey,
+				CertFile:   path.Join(dir, "issued.cert"),
+				KeyFile:    path.Join(dir, "issued.key"),
+				MainHost:   req["common_name"],
+				AliasHosts: strings.Split(req["alt_names"], ","),
+				AliasIPs:   strings.Split(req["ip_sans"], ","),
+				KeyType:    ECDSAP256Key,
+				PKCS8:      req["private_key_format"] == "pkcs8",
+				MasterMode: strings.HasSuffix(r.URL.Path, "/server"),
+			}
+			if err := NewX509CertGenerator().GenerateCert(opts); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string][]string{"errors": {err.Error()}})
+				return
+			}
+			cert, _ := ioutil.ReadFile(opts.CertFile)
+			key, _ := ioutil.ReadFile(opts.KeyFile)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]string{"certificate": string(cert), "private_key": string(key)},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+}
+
+func TestParseVaultURL(t *testing.T) {
+	gen, err := ParseVaultURL("https://vault.local:8200/pki/int/?role=server&client-role=client")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing URL : %s\r\n", err)
+	}
+	if gen.Address != "https://vault.local:8200" || gen.Mount != "pki/int" || gen.Role != "server" || gen.ClientRole != "client" {
+		t.Errorf("Unexpected issuer %+v\r\n", gen)
+	}
+	if again, _ := ParseVaultURL(gen.URL()); *again != *gen {
+		t.Errorf("URL %s does not parse back to the same issuer\r\n", gen.URL())
+	}
+
+	for _, raw := range []string{"vault.local/pki?role=r", "https://vault.local?role=r", "https://vault.local/pki"} {
+		if _, err = ParseVaultURL(raw); err == nil {
+			t.Errorf("Got no error while an Error was expected (%s)\r\n", raw)
+		}
+	}
+}
+
+func TestVaultCertGenerator(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "mikrodock-vault")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating temp dir : %s\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := fakeVault(t, dir)
+	defer server.Close()
+
+	gen := &VaultCertGenerator{Address: server.URL, Mount: "pki", Role: "server", ClientRole: "client", Token: "root"}
+	checkVaultGenerator(t, gen, dir)
+}
+
+// checkVaultGenerator fetches the CA of the mount, then issues a server and a client certificate verified against it
+func checkVaultGenerator(t *testing.T, gen *VaultCertGenerator, dir string) {
+	caCert := path.Join(dir, "ca.cert")
+	caKey := path.Join(dir, "ca.key")
+	ioutil.WriteFile(caKey, []byte("stale"), 0600)
+	if err := gen.GenerateCACert(caCert, caKey, "ignored", 2048); err != nil {
+		t.Fatalf("Got an unexpected error while fetching CA : %s\r\n", err)
+	}
+	if _, err := os.Stat(caKey); !os.IsNotExist(err) {
+		t.Errorf("The stale CA key was not removed\r\n")
+	}
+	ca, err := ReadCertificate(caCert)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while reading CA : %s\r\n", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	opts := &CertOpts{
+		CertFile:   path.Join(dir, "cert.pem"),
+		KeyFile:    path.Join(dir, "key.pem"),
+		MainHost:   "node.local",
+		AliasHosts: []string{"node.local", "alias.local"},
+		AliasIPs:   []string{"10.0.0.1"},
+		MasterMode: true,
+	}
+	if err = gen.GenerateCert(opts); err != nil {
+		t.Fatalf("Got an unexpected error while issuing cert : %s\r\n", err)
+	}
+	cert, err := ReadCertificate(opts.CertFile)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while reading cert : %s\r\n", err)
+	}
+	if _, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "alias.local"}); err != nil {
+		t.Errorf("Got an unexpected error while verifying cert : %s\r\n", err)
+	}
+	if stat, _ := os.Stat(opts.KeyFile); stat.Mode().Perm() != 0600 {
+		t.Errorf("Expected key mode 0600, got %o\r\n", stat.Mode().Perm())
+	}
+
+	// Client certificates come from the client role
+	opts.MasterMode = false
+	if err = gen.GenerateCert(opts); err != nil {
+		t.Fatalf("Got an unexpected error while issuing client cert : %s\r\n", err)
+	}
+	cert, _ = ReadCertificate(opts.CertFile)
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+		t.Errorf("Expected a client only certificate, got %v\r\n", cert.ExtKeyUsage)
+	}
+
+	token := gen.Token
+	gen.Token = "wrong"
+	if err = gen.GenerateCert(opts); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected a permission denied error, got %v\r\n", err)
+	}
+	gen.Token = token
+}
+
+// vaultAPI calls the Vault of VAULT_ADDR with VAULT_TOKEN
+func vaultAPI(t *testing.T, method string, endpoint string, body interface{}) {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, os.Getenv("VAULT_ADDR")+"/v1/"+endpoint, strings.NewReader(string(payload)))
+	req.Header.Set("X-Vault-Token", os.Getenv(VaultTokenEnv))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while calling Vault %s : %s\r\n", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("Vault refused %s %s : %s %s\r\n", method, endpoint, resp.Status, msg)
+	}
+}
+
+// TestVaultDevServer issues certificates from a PKI engine mounted on a real Vault, such as `vault server -dev`.
+// It runs when VAULT_ADDR and VAULT_TOKEN are set
+func TestVaultDevServer(t *testing.T) {
+	if os.Getenv("VAULT_ADDR") == "" || os.Getenv(VaultTokenEnv) == "" {
+		t.Skip("VAULT_ADDR and VAULT_TOKEN are not set")
+	}
+	dir, err := ioutil.TempDir("/tmp", "mikrodock-vault")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating temp dir : %s\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	mount := fmt.Sprintf("mikrodock-test-%d", time.Now().UnixNano())
+	vaultAPI(t, http.MethodPost, "sys/mounts/"+mount, map[string]interface{}{"type": "pki", "config": map[string]string{"max_lease_ttl": "8760h"}})
+	defer vaultAPI(t, http.MethodDelete, "sys/mounts/"+mount, nil)
+	vaultAPI(t, http.MethodPost, mount+"/root/generate/internal", map[string]string{"common_name": "Mikrodock Test CA", "ttl": "8760h"})
+	role := map[string]interface{}{"allow_any_name": true, "enforce_hostnames": false, "allow_ip_sans": true, "key_type": "ec", "key_bits": 256}
+	role["server_flag"], role["client_flag"] = true, true
+	vaultAPI(t, http.MethodPost, mount+"/roles/server", role)
+	role["server_flag"] = false
+	vaultAPI(t, http.MethodPost, mount+"/roles/client", role)
+
+	gen, err := ParseVaultURL(os.Getenv("VAULT_ADDR") + "/" + mount + "?role=server&client-role=client")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing URL : %s\r\n", err)
+	}
+	checkVaultGenerator(t, gen, dir)
+
+	// The last certificate issued is revoked and listed by the CRL of the mount
+	cert, err := ReadCertificate(path.Join(dir, "cert.pem"))
+	if err != nil {
+		t.Fatalf("Got an unexpected error while reading cert : %s\r\n", err)
+	}
+	if err = gen.Revoke(FormatSerial(cert.SerialNumber)); err != nil {
+		t.Fatalf("Got an unexpected error while revoking : %s\r\n", err)
+	}
+	crlFile := path.Join(dir, "vault.crl")
+	if err = gen.FetchCRL(crlFile); err != nil {
+		t.Fatalf("Got an unexpected error while fetching CRL : %s\r\n", err)
+	}
+	data, _ := ioutil.ReadFile(crlFile)
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatalf("No CRL in %s\r\n", crlFile)
+	}
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing CRL : %s\r\n", err)
+	}
+	revoked := false
+	for _, entry := range crl.RevokedCertificateEntries {
+		revoked = revoked || entry.SerialNumber.Cmp(cert.SerialNumber) == 0
+	}
+	if !revoked {
+		t.Errorf("The revoked certificate is not in the CRL of the mount\r\n")
+	}
+}
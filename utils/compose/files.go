@@ -0,0 +1,135 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Kinds of the top-level file definitions of a compose file
+const (
+	SecretKind = "secrets"
+	ConfigKind = "configs"
+)
+
+// FileRef is a secret or a config declared at the top level of a compose file
+type FileRef struct {
+	Kind        string
+	Name        string
+	File        string
+	Environment string
+	// External refs are not read locally, they must already exist in the cluster under ExternalName
+	External     bool
+	ExternalName string
+}
+
+type fileDefinition struct {
+	File        string      `yaml:"file"`
+	Environment string      `yaml:"environment"`
+	External    interface{} `yaml:"external"`
+	Name        string      `yaml:"name"`
+}
+
+type composeFiles struct {
+	Secrets map[string]fileDefinition `yaml:"secrets"`
+	Configs map[string]fileDefinition `yaml:"configs"`
+}
+
+// ReadFileRefs returns the secrets and the configs of a compose file, sorted by kind then name.
+// Relative files are resolved from the directory of the compose file
+func ReadFileRefs(composeFile string) ([]FileRef, error) {
+	content, err := ioutil.ReadFile(composeFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFileRefs(content, filepath.Dir(composeFile))
+}
+
+// ParseFileRefs is ReadFileRefs on the content of a compose file
+func ParseFileRefs(content []byte, baseDir string) ([]FileRef, error) {
+	files := composeFiles{}
+	if err := yaml.Unmarshal(content, &files); err != nil {
+		return nil, fmt.Errorf("Cannot parse compose file : %s", err)
+	}
+
+	refs := make([]FileRef, 0, len(files.Secrets)+len(files.Configs))
+	for _, kind := range []string{ConfigKind, SecretKind} {
+		definitions := files.Configs
+		if kind == SecretKind {
+			definitions = files.Secrets
+		}
+		names := make([]string, 0, len(definitions))
+		for name := range definitions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			ref, err := newFileRef(kind, name, definitions[name], baseDir)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+func newFileRef(kind string, name string, def fileDefinition, baseDir string) (FileRef, error) {
+	ref := FileRef{Kind: kind, Name: name, Environment: def.Environment}
+
+	switch external := def.External.(type) {
+	case nil:
+	case bool:
+		ref.External = external
+	case map[interface{}]interface{}:
+		// Compose 3.x legacy form : external: {name: foo}
+		ref.External = true
+		if externalName, ok := external["name"].(string); ok {
+			ref.ExternalName = externalName
+		}
+	default:
+		return ref, fmt.Errorf("Invalid external value for %s %s", kind, name)
+	}
+
+	if ref.External {
+		if ref.ExternalName == "" {
+			ref.ExternalName = def.Name
+		}
+		if ref.ExternalName == "" {
+			ref.ExternalName = name
+		}
+		return ref, nil
+	}
+
+	if def.File != "" {
+		ref.File = def.File
+		if !filepath.IsAbs(ref.File) {
+			ref.File = filepath.Join(baseDir, ref.File)
+		}
+	}
+	if (ref.File == "") == (ref.Environment == "") {
+		return ref, fmt.Errorf("%s %s needs either a file or an environment variable", kind, name)
+	}
+	return ref, nil
+}
+
+// Read returns the content of a local ref
+func (r FileRef) Read() ([]byte, error) {
+	if r.External {
+		return nil, errors.New(r.Name + " is external")
+	}
+	if r.File != "" {
+		return ioutil.ReadFile(r.File)
+	}
+	value, ok := os.LookupEnv(r.Environment)
+	if !ok {
+		return nil, fmt.Errorf("%s is not set", r.Environment)
+	}
+	return []byte(value), nil
+}
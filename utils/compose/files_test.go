@@ -0,0 +1,72 @@
+package compose
+
+import (
+	"os"
+	"testing"
+)
+
+const composeContent = `
+version: "3.5"
+services:
+  web:
+    image: nginx
+    secrets: [db_password]
+secrets:
+  db_password:
+    file: ./db_password.txt
+  api_key:
+    environment: API_KEY
+  shared:
+    external: true
+    name: shared_key
+configs:
+  nginx:
+    file: /etc/nginx.conf
+  legacy:
+    external:
+      name: old_config
+`
+
+func TestParseFileRefs(t *testing.T) {
+	refs, err := ParseFileRefs([]byte(composeContent), "/srv/app")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing compose file : %s\r\n", err)
+	}
+
+	expected := []FileRef{
+		{Kind: ConfigKind, Name: "legacy", External: true, ExternalName: "old_config"},
+		{Kind: ConfigKind, Name: "nginx", File: "/etc/nginx.conf"},
+		{Kind: SecretKind, Name: "api_key", Environment: "API_KEY"},
+		{Kind: SecretKind, Name: "db_password", File: "/srv/app/db_password.txt"},
+		{Kind: SecretKind, Name: "shared", External: true, ExternalName: "shared_key"},
+	}
+	if len(refs) != len(expected) {
+		t.Fatalf("Expected %d refs, got %d\r\n", len(expected), len(refs))
+	}
+	for i := range expected {
+		if refs[i] != expected[i] {
+			t.Errorf("Expected %#v, got %#v\r\n", expected[i], refs[i])
+		}
+	}
+
+	os.Setenv("API_KEY", "s3cr3t")
+	defer os.Unsetenv("API_KEY")
+	value, err := refs[2].Read()
+	if err != nil || string(value) != "s3cr3t" {
+		t.Errorf("Got an unexpected value while reading env ref : %s %v\r\n", value, err)
+	}
+}
+
+func TestParseFileRefsInvalid(t *testing.T) {
+	invalid := []string{
+		"secrets:\n  a: {}\n",
+		"secrets:\n  a:\n    file: a\n    environment: A\n",
+		"configs:\n  a:\n    external: 3\n",
+		"secrets: [",
+	}
+	for _, content := range invalid {
+		if _, err := ParseFileRefs([]byte(content), "/tmp"); err == nil {
+			t.Errorf("Got no error while an Error was expected (%q)\r\n", content)
+		}
+	}
+}
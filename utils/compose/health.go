@@ -0,0 +1,134 @@
+package compose
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Defaults of Docker when the healthcheck does not set them
+const (
+	DefaultHealthInterval = 30 * time.Second
+	DefaultHealthTimeout  = 30 * time.Second
+)
+
+// Healthcheck is the healthcheck of a compose service
+type Healthcheck struct {
+	// Test is the command, CMD-SHELL commands are a single shell string
+	Test     []string
+	Interval time.Duration
+	Timeout  time.Duration
+	Retries  int
+	Disable  bool
+}
+
+type healthcheckDefinition struct {
+	Test     interface{} `yaml:"test"`
+	Interval string      `yaml:"interval"`
+	Timeout  string      `yaml:"timeout"`
+	Retries  int         `yaml:"retries"`
+	Disable  bool        `yaml:"disable"`
+}
+
+type composeServices struct {
+	Services map[string]struct {
+		Healthcheck *healthcheckDefinition `yaml:"healthcheck"`
+	} `yaml:"services"`
+}
+
+// ParseHealthchecks returns the healthcheck of every service of a compose file having one
+func ParseHealthchecks(content []byte) (map[string]Healthcheck, error) {
+	services := composeServices{}
+	if err := yaml.Unmarshal(content, &services); err != nil {
+		return nil, fmt.Errorf("Cannot parse compose file : %s", err)
+	}
+
+	checks := make(map[string]Healthcheck)
+	for name, service := range services.Services {
+		if service.Healthcheck == nil {
+			continue
+		}
+		check, err := newHealthcheck(service.Healthcheck)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid healthcheck of %s : %s", name, err)
+		}
+		checks[name] = check
+	}
+	return checks, nil
+}
+
+func newHealthcheck(def *healthcheckDefinition) (Healthcheck, error) {
+	check := Healthcheck{Interval: DefaultHealthInterval, Timeout: DefaultHealthTimeout, Retries: def.Retries, Disable: def.Disable}
+
+	switch test := def.Test.(type) {
+	case nil:
+	case string:
+		check.Test = []string{"CMD-SHELL", test}
+	case []interface{}:
+		for _, part := range test {
+			check.Test = append(check.Test, fmt.Sprint(part))
+		}
+	default:
+		return check, fmt.Errorf("test must be a string or a list")
+	}
+	if len(check.Test) != 0 && check.Test[0] == "NONE" {
+		check.Disable = true
+	}
+
+	var err error
+	if def.Interval != "" {
+		if check.Interval, err = time.ParseDuration(def.Interval); err != nil {
+			return check, err
+		}
+	}
+	if def.Timeout != "" {
+		if check.Timeout, err = time.ParseDuration(def.Timeout); err != nil {
+			return check, err
+		}
+	}
+	return check, nil
+}
+
+// HTTPTarget returns the scheme, the port and the path of the http or https URL fetched by a curl or wget test,
+// ok is false for the other tests. The port is the one of the container, 80 or 443 when the URL has none
+func (h Healthcheck) HTTPTarget() (scheme string, port uint32, path string, ok bool) {
+	if len(h.Test) < 2 {
+		return "", 0, "", false
+	}
+	words := h.Test[1:]
+	if h.Test[0] == "CMD-SHELL" {
+		words = strings.Fields(h.Test[1])
+	}
+
+	client := false
+	for _, word := range words {
+		word = strings.Trim(word, `"'`)
+		switch {
+		case word == "curl" || word == "wget" || strings.HasSuffix(word, "/curl") || strings.HasSuffix(word, "/wget"):
+			client = true
+		case client && (strings.HasPrefix(word, "http://") || strings.HasPrefix(word, "https://")):
+			target, err := url.Parse(word)
+			if err != nil {
+				return "", 0, "", false
+			}
+			port = 80
+			if target.Scheme == "https" {
+				port = 443
+			}
+			if target.Port() != "" {
+				parsed, err := strconv.ParseUint(target.Port(), 10, 32)
+				if err != nil {
+					return "", 0, "", false
+				}
+				port = uint32(parsed)
+			}
+			path = target.RequestURI()
+			return target.Scheme, port, path, true
+		}
+	}
+	return "", 0, "", false
+}
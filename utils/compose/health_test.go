@@ -0,0 +1,74 @@
+package compose
+
+import (
+	"testing"
+	"time"
+)
+
+const healthContent = `
+services:
+  web:
+    image: nginx
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost:8080/health?full=1"]
+      interval: 10s
+      timeout: 2s
+      retries: 3
+  worker:
+    image: worker
+    healthcheck:
+      test: pgrep worker
+  legacy:
+    image: legacy
+    healthcheck:
+      test: ["NONE"]
+  plain:
+    image: redis
+`
+
+func TestParseHealthchecks(t *testing.T) {
+	checks, err := ParseHealthchecks([]byte(healthContent))
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing healthchecks : %s\r\n", err)
+	}
+	if len(checks) != 3 {
+		t.Fatalf("Expected 3 healthchecks, got %d\r\n", len(checks))
+	}
+
+	web := checks["web"]
+	if web.Interval != 10*time.Second || web.Timeout != 2*time.Second || web.Retries != 3 {
+		t.Errorf("Unexpected timings %#v\r\n", web)
+	}
+	scheme, port, path, ok := web.HTTPTarget()
+	if !ok || scheme != "http" || port != 8080 || path != "/health?full=1" {
+		t.Errorf("Unexpected HTTP target %s %d %s %t\r\n", scheme, port, path, ok)
+	}
+
+	worker := checks["worker"]
+	if worker.Interval != DefaultHealthInterval || len(worker.Test) != 2 || worker.Test[0] != "CMD-SHELL" {
+		t.Errorf("Unexpected shell healthcheck %#v\r\n", worker)
+	}
+	if _, _, _, ok = worker.HTTPTarget(); ok {
+		t.Errorf("Got an HTTP target for a non HTTP test\r\n")
+	}
+
+	if !checks["legacy"].Disable {
+		t.Errorf("NONE test is not disabled\r\n")
+	}
+
+	shell := Healthcheck{Test: []string{"CMD-SHELL", "wget -q -O- http://localhost/ || exit 1"}}
+	if scheme, port, path, ok = shell.HTTPTarget(); !ok || scheme != "http" || port != 80 || path != "/" {
+		t.Errorf("Unexpected HTTP target of a shell test %s %d %s %t\r\n", scheme, port, path, ok)
+	}
+
+	secure := Healthcheck{Test: []string{"CMD", "curl", "-fk", "https://localhost/ready"}}
+	if scheme, port, path, ok = secure.HTTPTarget(); !ok || scheme != "https" || port != 443 || path != "/ready" {
+		t.Errorf("Unexpected HTTPS target %s %d %s %t\r\n", scheme, port, path, ok)
+	}
+}
+
+func TestParseHealthchecksInvalid(t *testing.T) {
+	if _, err := ParseHealthchecks([]byte("services:\n  a:\n    healthcheck:\n      interval: soon\n")); err == nil {
+		t.Errorf("Got no error while an Error was expected (interval)\r\n")
+	}
+}
@@ -0,0 +1,90 @@
+package compose
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Labels of the compose services routed by the ingress
+const (
+	IngressHostLabel = "mikrodock.ingress.host"
+	IngressPortLabel = "mikrodock.ingress.port"
+)
+
+// Ingress is the host routed to a port of a compose service
+type Ingress struct {
+	Host string
+	Port int
+}
+
+type composeLabels struct {
+	Services map[string]struct {
+		Labels interface{} `yaml:"labels"`
+		Deploy struct {
+			Labels interface{} `yaml:"labels"`
+		} `yaml:"deploy"`
+	} `yaml:"services"`
+}
+
+// labelsMap reads compose labels, written as a map or as a list of key=value
+func labelsMap(labels interface{}) (map[string]string, error) {
+	m := make(map[string]string)
+	switch l := labels.(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		for k, v := range l {
+			m[fmt.Sprint(k)] = fmt.Sprint(v)
+		}
+	case []interface{}:
+		for _, item := range l {
+			parts := strings.SplitN(fmt.Sprint(item), "=", 2)
+			if len(parts) == 1 {
+				parts = append(parts, "")
+			}
+			m[parts[0]] = parts[1]
+		}
+	default:
+		return nil, fmt.Errorf("labels must be a map or a list")
+	}
+	return m, nil
+}
+
+// ParseIngresses returns the ingress of every service of a compose file labelled with IngressHostLabel.
+// The labels of the containers take precedence over the deploy ones, the port defaults to 80
+func ParseIngresses(content []byte) (map[string]Ingress, error) {
+	services := composeLabels{}
+	if err := yaml.Unmarshal(content, &services); err != nil {
+		return nil, fmt.Errorf("Cannot parse compose file : %s", err)
+	}
+
+	ingresses := make(map[string]Ingress)
+	for name, service := range services.Services {
+		labels, err := labelsMap(service.Deploy.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid deploy labels of %s : %s", name, err)
+		}
+		containerLabels, err := labelsMap(service.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid labels of %s : %s", name, err)
+		}
+		for k, v := range containerLabels {
+			labels[k] = v
+		}
+
+		host, ok := labels[IngressHostLabel]
+		if !ok {
+			continue
+		}
+		ingress := Ingress{Host: host, Port: 80}
+		if port, ok := labels[IngressPortLabel]; ok {
+			if ingress.Port, err = strconv.Atoi(port); err != nil || ingress.Port < 1 || ingress.Port > 65535 {
+				return nil, fmt.Errorf("Invalid %s of %s : %s", IngressPortLabel, name, port)
+			}
+		}
+		ingresses[name] = ingress
+	}
+	return ingresses, nil
+}
@@ -0,0 +1,44 @@
+package compose
+
+import (
+	"testing"
+)
+
+func TestParseIngresses(t *testing.T) {
+	content := []byte(`
+version: "3.3"
+services:
+  front:
+    image: nginx
+    labels:
+      mikrodock.ingress.host: shop.example.com
+  api:
+    image: api
+    labels:
+      - "mikrodock.ingress.host=api.example.com"
+    deploy:
+      labels:
+        mikrodock.ingress.host: ignored.example.com
+        mikrodock.ingress.port: 8080
+  worker:
+    image: worker
+`)
+	ingresses, err := ParseIngresses(content)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing the ingresses : %s\r\n", err)
+	}
+	if len(ingresses) != 2 {
+		t.Fatalf("Expected 2 ingresses, got %v\r\n", ingresses)
+	}
+	if front := ingresses["front"]; front.Host != "shop.example.com" || front.Port != 80 {
+		t.Errorf("Unexpected ingress of front %#v\r\n", front)
+	}
+	if api := ingresses["api"]; api.Host != "api.example.com" || api.Port != 8080 {
+		t.Errorf("Unexpected ingress of api %#v\r\n", api)
+	}
+
+	_, err = ParseIngresses([]byte("services:\n  front:\n    labels:\n      mikrodock.ingress.host: a.example.com\n      mikrodock.ingress.port: http\n"))
+	if err == nil {
+		t.Errorf("Got no error while an error was expected (port)\r\n")
+	}
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Confirm asks a yes/no question on the terminal, the answer is no unless it starts with y.
+// Without a terminal the question cannot be asked and the caller needs another way to confirm, such as --yes
+func Confirm(question string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, errors.New("No terminal is available to confirm, use --yes")
+	}
+	fmt.Fprint(os.Stderr, question+" [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y"), nil
+}
@@ -0,0 +1,31 @@
+package consulhelpers
+
+import (
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+// ExportEntry is a key in the JSON format of consul kv export, the value is base64 encoded
+type ExportEntry struct {
+	Key   string `json:"key"`
+	Flags uint64 `json:"flags"`
+	Value []byte `json:"value"`
+}
+
+// Export returns the keys of tree in the consul kv export format, sorted by key
+func Export(tree *KVNode) []ExportEntry {
+	entries := Flatten(tree)
+	exported := make([]ExportEntry, 0, len(entries))
+	for _, key := range SortedKeys(entries) {
+		exported = append(exported, ExportEntry{Key: key, Value: entries[key].Value})
+	}
+	return exported
+}
+
+// ImportTree builds the tree rooted at prefix of exported entries, the ones outside prefix are ignored
+func ImportTree(prefix string, entries []ExportEntry) *KVNode {
+	pairs := make(consulAPI.KVPairs, 0, len(entries))
+	for _, entry := range entries {
+		pairs = append(pairs, &consulAPI.KVPair{Key: entry.Key, Flags: entry.Flags, Value: entry.Value})
+	}
+	return TreeFromPairs(prefix, pairs)
+}
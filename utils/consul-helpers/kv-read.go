@@ -0,0 +1,116 @@
+package consulhelpers
+
+import (
+	"sort"
+	"strings"
+
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+// KVEntry is a key of a flattened tree
+type KVEntry struct {
+	Value       []byte
+	ModifyIndex uint64
+}
+
+// ReadTree reads every key under prefix into a tree rooted at prefix
+func (ch *ConsulHelper) ReadTree(prefix string) (*KVNode, error) {
+	prefix = strings.Trim(prefix, "/")
+	listPrefix := prefix
+	if listPrefix != "" {
+		listPrefix += "/"
+	}
+	pairs, _, err := ch.client.KV().List(listPrefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	return TreeFromPairs(prefix, pairs), nil
+}
+
+// Pair returns the pair of key with its index, nil when the key does not exist
+func (ch *ConsulHelper) Pair(key string) (*consulAPI.KVPair, error) {
+	pair, _, err := ch.client.KV().Get(key, nil)
+	return pair, err
+}
+
+// TreeFromPairs builds the tree of the pairs found under prefix, keys ending with a slash are empty subcategories
+func TreeFromPairs(prefix string, pairs consulAPI.KVPairs) *KVNode {
+	root := &KVNode{Key: prefix, Value: []byte{}, Childs: make([]*KVNode, 0), IsDir: true}
+	for _, pair := range pairs {
+		relative := pair.Key
+		if prefix != "" {
+			if !strings.HasPrefix(relative, prefix+"/") {
+				continue
+			}
+			relative = strings.TrimPrefix(relative, prefix+"/")
+		}
+		isDir := strings.HasSuffix(relative, "/")
+		parts := strings.Split(strings.TrimSuffix(relative, "/"), "/")
+		if relative == "" || relative == "/" {
+			continue
+		}
+
+		node := root
+		for _, part := range parts[:len(parts)-1] {
+			child := node.Child(part)
+			if child == nil {
+				child = node.AddSubCategory(part)
+			}
+			node = child
+		}
+		last := parts[len(parts)-1]
+		if isDir {
+			dir := node.Child(last)
+			if dir == nil {
+				dir = node.AddSubCategory(last)
+			}
+			// Deleting the key of an empty subcategory with CAS needs its index
+			dir.ModifyIndex = pair.ModifyIndex
+			continue
+		}
+		value := pair.Value
+		if value == nil {
+			value = []byte{}
+		}
+		leaf := node.AddChild(last, value)
+		leaf.ModifyIndex = pair.ModifyIndex
+	}
+	return root
+}
+
+// Flatten returns the full Consul keys of the tree, empty subcategories end with a slash
+func Flatten(tree *KVNode) map[string]KVEntry {
+	entries := make(map[string]KVEntry)
+	for _, child := range tree.Childs {
+		flatten(tree.Key, child, entries)
+	}
+	return entries
+}
+
+func flatten(context string, node *KVNode, entries map[string]KVEntry) {
+	key := node.Key
+	if context != "" {
+		key = context + "/" + node.Key
+	}
+	if !node.IsDir {
+		entries[key] = KVEntry{Value: node.Value, ModifyIndex: node.ModifyIndex}
+		return
+	}
+	if len(node.Childs) == 0 {
+		entries[key+"/"] = KVEntry{Value: []byte{}, ModifyIndex: node.ModifyIndex}
+		return
+	}
+	for _, child := range node.Childs {
+		flatten(key, child, entries)
+	}
+}
+
+// SortedKeys returns the keys of entries in order
+func SortedKeys(entries map[string]KVEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package consulhelpers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+// TxnBatchSize is the maximum number of operations of a Consul transaction
+const TxnBatchSize = 64
+
+// ChangeOp is the operation of a Change
+type ChangeOp string
+
+const (
+	SetOp    ChangeOp = "set"
+	DeleteOp ChangeOp = "delete"
+)
+
+// Change is a key to set or delete to turn a tree into another.
+// ModifyIndex is the index of the key in the current tree, 0 when the key does not exist yet
+type Change struct {
+	Op          ChangeOp
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+}
+
+func (c Change) String() string {
+	if c.Op == DeleteOp {
+		return "- " + c.Key
+	}
+	return "+ " + c.Key
+}
+
+// Diff returns the changes turning from into to, sorted by key.
+// The keys of from missing in to are only deleted when prune is set
+func Diff(from *KVNode, to *KVNode, prune bool) []Change {
+	current := Flatten(from)
+	wanted := Flatten(to)
+
+	changes := make([]Change, 0)
+	for _, key := range SortedKeys(wanted) {
+		existing, ok := current[key]
+		if ok && bytes.Equal(existing.Value, wanted[key].Value) {
+			continue
+		}
+		changes = append(changes, Change{Op: SetOp, Key: key, Value: wanted[key].Value, ModifyIndex: existing.ModifyIndex})
+	}
+	if prune {
+		for _, key := range SortedKeys(current) {
+			if _, ok := wanted[key]; !ok {
+				changes = append(changes, Change{Op: DeleteOp, Key: key, ModifyIndex: current[key].ModifyIndex})
+			}
+		}
+	}
+	return changes
+}
+
+// TxnOps converts changes to Consul transaction operations. With cas, every operation fails
+// when its key has been modified since it was read, and a new key fails when it already exists
+func TxnOps(changes []Change, cas bool) consulAPI.KVTxnOps {
+	ops := make(consulAPI.KVTxnOps, 0, len(changes))
+	for _, change := range changes {
+		op := &consulAPI.KVTxnOp{Key: change.Key, Value: change.Value}
+		switch {
+		case change.Op == DeleteOp && cas:
+			op.Verb = consulAPI.KVDeleteCAS
+			op.Index = change.ModifyIndex
+		case change.Op == DeleteOp:
+			op.Verb = consulAPI.KVDelete
+		case cas:
+			op.Verb = consulAPI.KVCAS
+			op.Index = change.ModifyIndex
+		default:
+			op.Verb = consulAPI.KVSet
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Apply runs changes in transactions of TxnBatchSize operations.
+// Each batch is atomic, a failed batch stops the next ones but does not undo the previous ones
+func (ch *ConsulHelper) Apply(changes []Change, cas bool) error {
+	ops := TxnOps(changes, cas)
+	for start := 0; start < len(ops); start += TxnBatchSize {
+		end := start + TxnBatchSize
+		if end > len(ops) {
+			end = len(ops)
+		}
+		ok, response, _, err := ch.client.KV().Txn(ops[start:end], nil)
+		if err != nil {
+			return fmt.Errorf("Transaction of keys %d to %d failed : %s", start, end-1, err)
+		}
+		if !ok {
+			reasons := make([]string, 0, len(response.Errors))
+			for _, txnErr := range response.Errors {
+				reasons = append(reasons, ops[start+txnErr.OpIndex].Key+" : "+txnErr.What)
+			}
+			return fmt.Errorf("Transaction of keys %d to %d rolled back : %s", start, end-1, strings.Join(reasons, ", "))
+		}
+	}
+	return nil
+}
+
+// SyncTree makes the keys under the root of tree match it and returns the applied changes.
+// Keys missing from tree are deleted when prune is set
+func (ch *ConsulHelper) SyncTree(tree *KVNode, prune bool, cas bool) ([]Change, error) {
+	current, err := ch.ReadTree(tree.Key)
+	if err != nil {
+		return nil, err
+	}
+	changes := Diff(current, tree, prune)
+	return changes, ch.Apply(changes, cas)
+}
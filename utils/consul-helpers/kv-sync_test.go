@@ -0,0 +1,81 @@
+package consulhelpers
+
+import (
+	"testing"
+
+	consulAPI "github.com/hashicorp/consul/api"
+)
+
+func TestTreeFromPairs(t *testing.T) {
+	pairs := consulAPI.KVPairs{
+		{Key: "mikrodock/nodes/10.0.0.1/name", Value: []byte("konsultant"), ModifyIndex: 5},
+		{Key: "mikrodock/nodes/10.0.0.1/empty", ModifyIndex: 6},
+		{Key: "mikrodock/services/", ModifyIndex: 7},
+		{Key: "other/key", Value: []byte("ignored")},
+	}
+	tree := TreeFromPairs("mikrodock", pairs)
+
+	entries := Flatten(tree)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 keys, got %v\r\n", SortedKeys(entries))
+	}
+	if e := entries["mikrodock/nodes/10.0.0.1/name"]; string(e.Value) != "konsultant" || e.ModifyIndex != 5 {
+		t.Errorf("Unexpected entry %#v\r\n", e)
+	}
+	if _, ok := entries["mikrodock/nodes/10.0.0.1/empty"]; !ok {
+		t.Errorf("The empty leaf is missing\r\n")
+	}
+	if e, ok := entries["mikrodock/services/"]; !ok || e.ModifyIndex != 7 {
+		t.Errorf("Unexpected empty subcategory %#v %t\r\n", e, ok)
+	}
+
+	// Pruning the empty subcategory with CAS deletes it at its index
+	ops := TxnOps(Diff(tree, &KVNode{Key: "mikrodock", IsDir: true}, true), true)
+	for _, op := range ops {
+		if op.Key == "mikrodock/services/" && (op.Verb != consulAPI.KVDeleteCAS || op.Index != 7) {
+			t.Errorf("Unexpected deletion of the empty subcategory %#v\r\n", op)
+		}
+	}
+	if len(ops) != 3 {
+		t.Errorf("Expected 3 deletions, got %d\r\n", len(ops))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := TreeFromPairs("app", consulAPI.KVPairs{
+		{Key: "app/same", Value: []byte("1"), ModifyIndex: 10},
+		{Key: "app/changed", Value: []byte("old"), ModifyIndex: 11},
+		{Key: "app/removed", Value: []byte("x"), ModifyIndex: 12},
+	})
+	to := &KVNode{Key: "app", IsDir: true}
+	to.AddChild("same", []byte("1"))
+	to.AddChild("changed", []byte("new"))
+	to.AddSubCategory("sub").AddChild("added", []byte("y"))
+
+	changes := Diff(from, to, false)
+	expected := []Change{
+		{Op: SetOp, Key: "app/changed", Value: []byte("new"), ModifyIndex: 11},
+		{Op: SetOp, Key: "app/sub/added", Value: []byte("y")},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %v, got %v\r\n", expected, changes)
+	}
+	for i := range expected {
+		if changes[i].Op != expected[i].Op || changes[i].Key != expected[i].Key || string(changes[i].Value) != string(expected[i].Value) || changes[i].ModifyIndex != expected[i].ModifyIndex {
+			t.Errorf("Expected %#v, got %#v\r\n", expected[i], changes[i])
+		}
+	}
+
+	changes = Diff(from, to, true)
+	if last := changes[len(changes)-1]; last.Op != DeleteOp || last.Key != "app/removed" || last.ModifyIndex != 12 {
+		t.Errorf("Expected the deletion of app/removed, got %#v\r\n", last)
+	}
+
+	ops := TxnOps(changes, true)
+	if ops[0].Verb != consulAPI.KVCAS || ops[0].Index != 11 || ops[1].Index != 0 || ops[2].Verb != consulAPI.KVDeleteCAS {
+		t.Errorf("Unexpected CAS operations %#v %#v %#v\r\n", ops[0], ops[1], ops[2])
+	}
+	if ops = TxnOps(changes, false); ops[0].Verb != consulAPI.KVSet || ops[2].Verb != consulAPI.KVDelete {
+		t.Errorf("Unexpected operations %#v %#v\r\n", ops[0], ops[2])
+	}
+}
@@ -15,6 +15,11 @@ type KVNode struct {
 	Key    string
 	Value  []byte
 	Childs []*KVNode
+
+	// IsDir is set on the subcategories, a leaf may have an empty value
+	IsDir bool
+	// ModifyIndex is the Consul index of a leaf read by ReadTree, used to check-and-set
+	ModifyIndex uint64
 }
 
 func NewConsulHelper(client *consulAPI.Client) *ConsulHelper {
@@ -28,6 +33,7 @@ func (ch *ConsulHelper) NewTree(rootKey string) *KVNode {
 		Key:    rootKey,
 		Value:  []byte{},
 		Childs: make([]*KVNode, 0),
+		IsDir:  true,
 	}
 }
 
@@ -44,11 +50,22 @@ func (t *KVNode) AddSubCategory(key string) *KVNode {
 	node := &KVNode{
 		Key:   key,
 		Value: []byte{},
+		IsDir: true,
 	}
 	t.Childs = append(t.Childs, node)
 	return node
 }
 
+// Child returns the direct child named key, nil when there is none
+func (t *KVNode) Child(key string) *KVNode {
+	for _, child := range t.Childs {
+		if child.Key == key {
+			return child
+		}
+	}
+	return nil
+}
+
 func (t *KVNode) HasValue() bool {
 	return len(t.Value) != 0
 }
@@ -68,47 +85,63 @@ func (ch *ConsulHelper) SendTree(tree *KVNode) error {
 }
 
 func (ch *ConsulHelper) walkTree(context string, tree *KVNode) error {
-	if tree.HasValue() {
+	if !tree.IsDir {
 		// It's a leaf
-		kv := &consulAPI.KVPair{
-			Key:   context + "/" + tree.Key,
-			Value: tree.Value,
-		}
-		_, err := ch.client.KV().Put(kv, nil)
-		return err
-	} else {
-		// It's a subdirectory (empty or not)
-		if len(tree.Childs) != 0 {
-			// It's not empty : we doesn't send anything, just explore further
-			for _, child := range tree.Childs {
-				err := ch.walkTree(context+"/"+tree.Key, child)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			// It's empty : register it with a trailing slash to close the path
-			kv := &consulAPI.KVPair{
-				Key:   context + "/" + tree.Key + "/",
-				Value: tree.Value,
+		return ch.sendKV(context, tree)
+	}
+	// It's a subdirectory (empty or not)
+	if len(tree.Childs) != 0 {
+		// It's not empty : we doesn't send anything, just explore further
+		for _, child := range tree.Childs {
+			err := ch.walkTree(context+"/"+tree.Key, child)
+			if err != nil {
+				return err
 			}
-			_, err := ch.client.KV().Put(kv, nil)
-			return err
 		}
+		return nil
 	}
-	return nil
+	// It's empty : register it with a trailing slash to close the path
+	kv := &consulAPI.KVPair{
+		Key:   context + "/" + tree.Key + "/",
+		Value: tree.Value,
+	}
+	_, err := ch.client.KV().Put(kv, nil)
+	return err
 }
 
-func sendKV(client *consulAPI.Client, parentKey string, leaf *KVNode) error {
+func (ch *ConsulHelper) sendKV(parentKey string, leaf *KVNode) error {
 	if len(leaf.Childs) != 0 {
 		return errors.New("Cannot create KV Pair : is not leaf")
-	} else {
-		logger.Debug("Consul.KVTree", "Sending key : "+parentKey+"/"+leaf.Key)
-		_, err := client.KV().Put(&consulAPI.KVPair{
-			Key:   parentKey,
-			Value: leaf.Value,
-		}, nil)
+	}
+	logger.Debug("Consul.KVTree", "Sending key : "+parentKey+"/"+leaf.Key)
+	_, err := ch.client.KV().Put(&consulAPI.KVPair{
+		Key:   parentKey + "/" + leaf.Key,
+		Value: leaf.Value,
+	}, nil)
+	return err
+}
+
+// Keys lists the keys found under prefix
+func (ch *ConsulHelper) Keys(prefix string) ([]string, error) {
+	keys, _, err := ch.client.KV().Keys(prefix, "", nil)
+	return keys, err
+}
+
+// Get returns the value of key, nil when the key does not exist
+func (ch *ConsulHelper) Get(key string) ([]byte, error) {
+	pair, _, err := ch.client.KV().Get(key, nil)
+	if err != nil || pair == nil {
+		return nil, err
+	}
+	return pair.Value, nil
+}
 
+// Delete removes key, and every key under it when recurse is set
+func (ch *ConsulHelper) Delete(key string, recurse bool) error {
+	if recurse {
+		_, err := ch.client.KV().DeleteTree(key, nil)
 		return err
 	}
+	_, err := ch.client.KV().Delete(key, nil)
+	return err
 }
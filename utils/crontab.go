@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ValidCronSpec tells if spec has the 5 fields of a cron schedule, or is a @ shortcut like @daily
+func ValidCronSpec(spec string) bool {
+	if strings.HasPrefix(spec, "@") {
+		switch spec {
+		case "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly":
+			return true
+		}
+		return false
+	}
+	return len(strings.Fields(spec)) == 5
+}
+
+// CrontabQuote quotes an argument of a crontab command for sh, % being a newline for cron unless escaped
+func CrontabQuote(arg string) string {
+	quoted := "'" + strings.Replace(arg, "'", "'\\''", -1) + "'"
+	return strings.Replace(quoted, "%", "\\%", -1)
+}
+
+// CrontabCommand joins the quoted arguments of a crontab command
+func CrontabCommand(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = CrontabQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SetCrontabEntry returns crontab with the line ending with the # tag comment replaced by line,
+// or removed when line is empty. The other lines are kept as they are
+func SetCrontabEntry(crontab string, tag string, line string) string {
+	marker := "# " + tag
+	var buffer bytes.Buffer
+	for _, existing := range strings.SplitAfter(crontab, "\n") {
+		if strings.HasSuffix(strings.TrimSuffix(existing, "\n"), marker) {
+			continue
+		}
+		buffer.WriteString(existing)
+	}
+	if line != "" {
+		if buffer.Len() != 0 && !bytes.HasSuffix(buffer.Bytes(), []byte("\n")) {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(line + " " + marker + "\n")
+	}
+	return buffer.String()
+}
+
+// InstallCrontabEntry sets the tagged line in the crontab of the current user
+func InstallCrontabEntry(tag string, line string) error {
+	current, err := exec.Command("crontab", "-l").Output()
+	if err != nil {
+		// crontab -l fails when the user has no crontab yet
+		if _, ok := err.(*exec.ExitError); !ok {
+			return fmt.Errorf("Cannot read crontab : %s", err)
+		}
+		current = []byte{}
+	}
+
+	cmd := exec.Command("crontab", "-")
+	cmd.Stdin = strings.NewReader(SetCrontabEntry(string(current), tag, line))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return errors.New("Cannot write crontab : " + err.Error() + " " + strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestValidCronSpec(t *testing.T) {
+	cases := []struct {
+		spec  string
+		valid bool
+	}{
+		{"0 3 * * *", true},
+		{"*/15 * * * 1-5", true},
+		{"@daily", true},
+		{"@annually", true},
+		{"@reboot", false},
+		{"@every 1h", false},
+		{"0 3 * *", false},
+		{"0 3 * * * *", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if valid := ValidCronSpec(c.spec); valid != c.valid {
+			t.Errorf("ValidCronSpec(%q) = %t, expected %t\r\n", c.spec, valid, c.valid)
+		}
+	}
+}
+
+func TestCrontabCommand(t *testing.T) {
+	cases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"/usr/bin/mikrodock-cli", "backup", "create", "prod"}, `'/usr/bin/mikrodock-cli' 'backup' 'create' 'prod'`},
+		{[]string{"/home/ops/My Tools/mikrodock-cli"}, `'/home/ops/My Tools/mikrodock-cli'`},
+		{[]string{"/srv/o'brien/backups"}, `'/srv/o'\''brien/backups'`},
+		{[]string{"/srv/100%/backups"}, `'/srv/100\%/backups'`},
+		{[]string{"$(rm -rf /)"}, `'$(rm -rf /)'`},
+	}
+	for _, c := range cases {
+		if command := CrontabCommand(c.args...); command != c.expected {
+			t.Errorf("CrontabCommand(%q) = %s, expected %s\r\n", c.args, command, c.expected)
+		}
+	}
+}
+
+func TestSetCrontabEntry(t *testing.T) {
+	cases := []struct {
+		name     string
+		crontab  string
+		line     string
+		expected string
+	}{
+		{"empty", "", "0 3 * * * backup", "0 3 * * * backup # tag\n"},
+		{"append", "MAILTO=ops\n\n# keep me\n@hourly other\n", "0 3 * * * backup",
+			"MAILTO=ops\n\n# keep me\n@hourly other\n0 3 * * * backup # tag\n"},
+		{"replace", "@hourly other\n\n0 1 * * * old # tag\n\n", "0 3 * * * backup",
+			"@hourly other\n\n\n0 3 * * * backup # tag\n"},
+		{"remove", "@hourly other\n0 1 * * * old # tag\n  \n", "", "@hourly other\n  \n"},
+		{"no final newline", "@hourly other", "0 3 * * * backup", "@hourly other\n0 3 * * * backup # tag\n"},
+		{"other tag", "0 1 * * * old # tag-staging\n", "", "0 1 * * * old # tag-staging\n"},
+	}
+	for _, c := range cases {
+		if crontab := SetCrontabEntry(c.crontab, "tag", c.line); crontab != c.expected {
+			t.Errorf("%s : got %q, expected %q\r\n", c.name, crontab, c.expected)
+		}
+	}
+}
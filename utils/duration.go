@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration is time.ParseDuration also accepting a number of days, like 30d
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err == nil {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
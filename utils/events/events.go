@@ -0,0 +1,266 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dockerEvents "github.com/docker/docker/api/types/events"
+)
+
+// Sources of the events
+const (
+	SourceConsul = "consul"
+	SourceDocker = "docker"
+)
+
+// Types of the events
+const (
+	TypeNode      = "node"
+	TypePartikle  = "partikle"
+	TypeService   = "service"
+	TypeContainer = "container"
+	TypeKV        = "kv"
+)
+
+// ContainerActions are the Docker container events streamed, the others are too verbose
+var ContainerActions = []string{"create", "start", "restart", "stop", "kill", "die", "oom", "destroy", "health_status"}
+
+// Event is a change of the cluster
+type Event struct {
+	Time       time.Time         `json:"time"`
+	Source     string            `json:"source"`
+	Type       string            `json:"type"`
+	Action     string            `json:"action"`
+	Partikle   string            `json:"partikle,omitempty"`
+	Subject    string            `json:"subject"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// String formats the event as a single human readable line
+func (e Event) String() string {
+	line := e.Time.Format(time.RFC3339) + " " + e.Source + " " + e.Type + " " + e.Action + " " + e.Subject
+	if e.Partikle != "" {
+		line += " on " + e.Partikle
+	}
+	if len(e.Attributes) != 0 {
+		keys := make([]string, 0, len(e.Attributes))
+		for k := range e.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		attrs := make([]string, len(keys))
+		for i, k := range keys {
+			attrs[i] = k + "=" + e.Attributes[k]
+		}
+		line += " (" + strings.Join(attrs, ", ") + ")"
+	}
+	return line
+}
+
+// Filter keeps the events matching every key, with any of the values of a key
+type Filter map[string][]string
+
+var filterKeys = []string{"source", "type", "action", "partikle", "subject"}
+
+// ParseFilter reads key=value filters, the keys are source, type, action, partikle and subject
+func ParseFilter(specs []string) (Filter, error) {
+	filter := make(Filter)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid filter %s, expected key=value", spec)
+		}
+		known := false
+		for _, key := range filterKeys {
+			known = known || key == parts[0]
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown filter %s, expected one of %s", parts[0], strings.Join(filterKeys, ", "))
+		}
+		filter[parts[0]] = append(filter[parts[0]], parts[1])
+	}
+	return filter, nil
+}
+
+// Match tells if the event passes the filter
+func (f Filter) Match(e Event) bool {
+	fields := map[string]string{"source": e.Source, "type": e.Type, "action": e.Action, "partikle": e.Partikle, "subject": e.Subject}
+	for key, values := range f {
+		matched := false
+		for _, value := range values {
+			matched = matched || fields[key] == value
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffKV returns the events turning the keys and modify indexes of previous into current
+func DiffKV(previous map[string]uint64, current map[string]uint64, at time.Time) []Event {
+	var events []Event
+	for _, key := range sortedKeys(current) {
+		if index, ok := previous[key]; !ok || index != current[key] {
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypeKV, Action: "set", Subject: key,
+				Attributes: map[string]string{"modify_index": strconv.FormatUint(current[key], 10)}})
+		}
+	}
+	for _, key := range sortedKeys(previous) {
+		if _, ok := current[key]; !ok {
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypeKV, Action: "delete", Subject: key})
+		}
+	}
+	return events
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ServiceState is the number of registered and passing instances of a stack service
+type ServiceState struct {
+	Instances int
+	Passing   int
+}
+
+// HealthState is what the Consul health checks tell about the cluster
+type HealthState struct {
+	// Nodes is the Serf status of each Consul node
+	Nodes map[string]string
+	// Partikles is the aggregated status of the checks of each registered partikle
+	Partikles map[string]string
+	// Services is the state of each stack service, by Consul service name
+	Services map[string]ServiceState
+}
+
+// NewHealthState returns an empty state
+func NewHealthState() HealthState {
+	return HealthState{
+		Nodes:     make(map[string]string),
+		Partikles: make(map[string]string),
+		Services:  make(map[string]ServiceState),
+	}
+}
+
+func statusEvent(at time.Time, kind string, subject string, previous string, status string) Event {
+	return Event{Time: at, Source: SourceConsul, Type: kind, Action: "health_status", Subject: subject,
+		Attributes: map[string]string{"previous": previous, "status": status}}
+}
+
+// DiffHealth returns the events turning the previous health state into the current one.
+// Nodes join and leave, partikles are registered and deregistered and stack services are scaled
+func DiffHealth(previous HealthState, current HealthState, at time.Time) []Event {
+	var events []Event
+
+	for _, node := range sortedStatus(current.Nodes) {
+		status, ok := previous.Nodes[node]
+		switch {
+		case !ok:
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypeNode, Action: "joined", Subject: node,
+				Attributes: map[string]string{"status": current.Nodes[node]}})
+		case status != current.Nodes[node]:
+			events = append(events, statusEvent(at, TypeNode, node, status, current.Nodes[node]))
+		}
+	}
+	for _, node := range sortedStatus(previous.Nodes) {
+		if _, ok := current.Nodes[node]; !ok {
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypeNode, Action: "left", Subject: node})
+		}
+	}
+
+	for _, partikle := range sortedStatus(current.Partikles) {
+		status, ok := previous.Partikles[partikle]
+		switch {
+		case !ok:
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypePartikle, Action: "registered", Subject: partikle, Partikle: partikle,
+				Attributes: map[string]string{"status": current.Partikles[partikle]}})
+		case status != current.Partikles[partikle]:
+			e := statusEvent(at, TypePartikle, partikle, status, current.Partikles[partikle])
+			e.Partikle = partikle
+			events = append(events, e)
+		}
+	}
+	for _, partikle := range sortedStatus(previous.Partikles) {
+		if _, ok := current.Partikles[partikle]; !ok {
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypePartikle, Action: "deregistered", Subject: partikle, Partikle: partikle})
+		}
+	}
+
+	for _, service := range sortedServices(current.Services) {
+		state := current.Services[service]
+		before := previous.Services[service]
+		if state.Instances != before.Instances {
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypeService, Action: "scaled", Subject: service,
+				Attributes: map[string]string{"from": strconv.Itoa(before.Instances), "to": strconv.Itoa(state.Instances)}})
+		}
+		if state.Passing != before.Passing {
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypeService, Action: "health_status", Subject: service,
+				Attributes: map[string]string{"passing": strconv.Itoa(state.Passing) + "/" + strconv.Itoa(state.Instances)}})
+		}
+	}
+	for _, service := range sortedServices(previous.Services) {
+		if _, ok := current.Services[service]; !ok {
+			events = append(events, Event{Time: at, Source: SourceConsul, Type: TypeService, Action: "scaled", Subject: service,
+				Attributes: map[string]string{"from": strconv.Itoa(previous.Services[service].Instances), "to": "0"}})
+		}
+	}
+	return events
+}
+
+func sortedStatus(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedServices(m map[string]ServiceState) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ContainerEvent converts a Docker container event of a partikle
+func ContainerEvent(partikle string, msg dockerEvents.Message) Event {
+	at := time.Unix(msg.Time, 0)
+	if msg.TimeNano != 0 {
+		at = time.Unix(0, msg.TimeNano)
+	}
+	subject := msg.Actor.Attributes["name"]
+	if subject == "" {
+		subject = msg.Actor.ID
+		if len(subject) > 12 {
+			subject = subject[:12]
+		}
+	}
+	attributes := make(map[string]string)
+	for _, key := range []string{"image", "exitCode"} {
+		if value, ok := msg.Actor.Attributes[key]; ok {
+			attributes[key] = value
+		}
+	}
+	action := msg.Action
+	// Health events are "health_status: healthy"
+	if parts := strings.SplitN(action, ": ", 2); len(parts) == 2 {
+		action = parts[0]
+		attributes["status"] = parts[1]
+	}
+	if len(attributes) == 0 {
+		attributes = nil
+	}
+	return Event{Time: at, Source: SourceDocker, Type: TypeContainer, Action: action, Partikle: partikle, Subject: subject, Attributes: attributes}
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	dockerEvents "github.com/docker/docker/api/types/events"
+)
+
+func TestFilter(t *testing.T) {
+	filter, err := ParseFilter([]string{"type=container", "action=start", "action=die"})
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing the filter : %s\r\n", err)
+	}
+	if !filter.Match(Event{Type: TypeContainer, Action: "die"}) {
+		t.Errorf("A die container event should match\r\n")
+	}
+	if filter.Match(Event{Type: TypeContainer, Action: "create"}) {
+		t.Errorf("A create container event should not match\r\n")
+	}
+	if filter.Match(Event{Type: TypeNode, Action: "start"}) {
+		t.Errorf("A node event should not match\r\n")
+	}
+
+	for _, spec := range []string{"type", "type=", "color=red"} {
+		if _, err = ParseFilter([]string{spec}); err == nil {
+			t.Errorf("Got no error while an error was expected (%s)\r\n", spec)
+		}
+	}
+}
+
+func TestDiffKV(t *testing.T) {
+	at := time.Now()
+	events := DiffKV(map[string]uint64{"mikrodock/a": 1, "mikrodock/b": 2}, map[string]uint64{"mikrodock/a": 1, "mikrodock/b": 3, "mikrodock/c": 4}, at)
+	if len(events) != 2 || events[0].Subject != "mikrodock/b" || events[1].Subject != "mikrodock/c" || events[1].Action != "set" {
+		t.Errorf("Unexpected set events %v\r\n", events)
+	}
+	events = DiffKV(map[string]uint64{"mikrodock/a": 1}, map[string]uint64{}, at)
+	if len(events) != 1 || events[0].Action != "delete" {
+		t.Errorf("Unexpected delete events %v\r\n", events)
+	}
+}
+
+func TestDiffHealth(t *testing.T) {
+	previous := NewHealthState()
+	previous.Nodes["konsultant"] = "passing"
+	previous.Nodes["konsultant-2"] = "passing"
+	previous.Partikles["klerk"] = "passing"
+	previous.Services["web-front"] = ServiceState{Instances: 2, Passing: 2}
+
+	current := NewHealthState()
+	current.Nodes["konsultant"] = "critical"
+	current.Nodes["konsultant-3"] = "passing"
+	current.Partikles["klerk"] = "passing"
+	current.Services["web-front"] = ServiceState{Instances: 3, Passing: 3}
+
+	events := DiffHealth(previous, current, time.Now())
+	expected := []string{"node health_status konsultant", "node joined konsultant-3", "node left konsultant-2", "service scaled web-front", "service health_status web-front"}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %v\r\n", len(expected), events)
+	}
+	for i, e := range events {
+		if got := e.Type + " " + e.Action + " " + e.Subject; got != expected[i] {
+			t.Errorf("Expected %s, got %s\r\n", expected[i], got)
+		}
+	}
+	if events[3].Attributes["from"] != "2" || events[3].Attributes["to"] != "3" {
+		t.Errorf("Unexpected scale attributes %v\r\n", events[3].Attributes)
+	}
+}
+
+func TestContainerEvent(t *testing.T) {
+	e := ContainerEvent("klerk", dockerEvents.Message{
+		Type:     "container",
+		Action:   "health_status: unhealthy",
+		Actor:    dockerEvents.Actor{ID: "0123456789abcdef", Attributes: map[string]string{"image": "nginx"}},
+		TimeNano: 1500000000000000000,
+	})
+	if e.Action != "health_status" || e.Attributes["status"] != "unhealthy" || e.Subject != "0123456789ab" || e.Partikle != "klerk" {
+		t.Errorf("Unexpected event %#v\r\n", e)
+	}
+	if e.Time.Unix() != 1500000000 {
+		t.Errorf("Unexpected time %s\r\n", e.Time)
+	}
+}
@@ -1,20 +1,93 @@
 package mssh
 
 import (
-	"io/ioutil"
+	"errors"
+	"net"
+	"os"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
-func PublicKeyFile(file string) ssh.AuthMethod {
-	buffer, err := ioutil.ReadFile(file)
+func PublicKeyFile(file string) (ssh.AuthMethod, error) {
+	key, err := LoadPrivateKey(file)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	return ssh.PublicKeys(key), nil
+}
+
+// AgentAuth authenticates through the ssh-agent listening on SSH_AUTH_SOCK
+func AgentAuth() (ssh.AuthMethod, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, errors.New("No ssh-agent available (SSH_AUTH_SOCK is not set)")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.PublicKeysCallback(agent.NewClient(conn).Signers), nil
+}
+
+// AuthMethods returns the key file and the ssh-agent authentications.
+// The error of the key file is returned even if the agent can still be used
+func AuthMethods(keyFile string) ([]ssh.AuthMethod, error) {
+	methods := make([]ssh.AuthMethod, 0, 2)
+
+	var keyErr error
+	if keyFile != "" {
+		var auth ssh.AuthMethod
+		auth, keyErr = PublicKeyFile(keyFile)
+		if keyErr == nil {
+			methods = append(methods, auth)
+		}
+	}
+
+	if auth, err := AgentAuth(); err == nil {
+		methods = append(methods, auth)
 	}
 
-	key, err := ssh.ParsePrivateKey(buffer)
+	return methods, keyErr
+}
+
+// CheckAuth opens a new connection to addr with only keyFile and runs a no-op command.
+// The hops without a key of their own authenticate with hopKeyFile
+func CheckAuth(hops []JumpHost, addr string, user string, keyFile string, hopKeyFile string) error {
+	_, err := RunJump(hops, addr, user, keyFile, hopKeyFile, "true")
+	return err
+}
+
+// RunJump opens a new connection to addr with only keyFile and runs cmd, returning its combined output.
+// The hops without a key of their own authenticate with hopKeyFile
+func RunJump(hops []JumpHost, addr string, user string, keyFile string, hopKeyFile string, cmd string) (string, error) {
+	auth, err := PublicKeyFile(keyFile)
 	if err != nil {
-		return nil
+		return "", err
+	}
+	withKeys := make([]JumpHost, len(hops))
+	for i, hop := range hops {
+		if hop.KeyPath == "" {
+			hop.KeyPath = hopKeyFile
+		}
+		withKeys[i] = hop
 	}
-	return ssh.PublicKeys(key)
+	client, err := DialJump(withKeys, addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	sess, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer sess.Close()
+
+	out, err := sess.CombinedOutput(cmd)
+	return string(out), err
 }
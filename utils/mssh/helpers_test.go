@@ -13,15 +13,15 @@ func TestPublicKeyFile(t *testing.T) {
 	}
 	defer os.Remove("/tmp/key.key")
 
-	auth := PublicKeyFile("/tmp/key.key")
+	auth, err := PublicKeyFile("/tmp/key.key")
 
-	if auth == nil {
-		t.Errorf("Got an unexpected error while PublicKeyFile\r\n")
+	if auth == nil || err != nil {
+		t.Errorf("Got an unexpected error while PublicKeyFile : %s\r\n", err)
 	}
 
-	auth = PublicKeyFile("/tmp/key2.key")
+	auth, err = PublicKeyFile("/tmp/key2.key")
 
-	if auth != nil {
+	if auth != nil || err == nil {
 		t.Errorf("Got an unexpected result while PublicKeyFile (nofile)\r\n")
 	}
 
@@ -29,9 +29,28 @@ func TestPublicKeyFile(t *testing.T) {
 	ioutil.WriteFile("/tmp/badkey", bigBuff, 0666)
 	defer os.Remove("/tmp/badkey")
 
-	auth = PublicKeyFile("/tmp/badkey")
+	auth, err = PublicKeyFile("/tmp/badkey")
 
-	if auth != nil {
+	if auth != nil || err == nil {
 		t.Errorf("Got an unexpected result while PublicKeyFile (badkey)\r\n")
 	}
 }
+
+func TestAuthMethods(t *testing.T) {
+	err := CreatePrivateKey("/tmp/key.key")
+	if err != nil {
+		t.Errorf("Got an unexpected error while CreatePrivateKey : %s\r\n", err)
+	}
+	defer os.Remove("/tmp/key.key")
+	os.Setenv("SSH_AUTH_SOCK", "")
+
+	methods, err := AuthMethods("/tmp/key.key")
+	if err != nil || len(methods) != 1 {
+		t.Errorf("Got an unexpected result while AuthMethods : %s\r\n", err)
+	}
+
+	methods, err = AuthMethods("/tmp/key2.key")
+	if err == nil || len(methods) != 0 {
+		t.Errorf("Got an unexpected result while AuthMethods (nofile)\r\n")
+	}
+}
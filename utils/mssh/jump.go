@@ -0,0 +1,143 @@
+package mssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// JumpHost is an intermediate SSH server used to reach a host (ProxyJump semantics)
+// An empty User or KeyPath means the ones of the final host are used
+type JumpHost struct {
+	User    string
+	Address string
+	Port    string
+	KeyPath string
+}
+
+// ParseJumpHosts parses a comma separated list of hops, in the order they are crossed
+// Each hop is written [user@]host[:port][=keypath]
+func ParseJumpHosts(spec string) ([]JumpHost, error) {
+	hops := make([]JumpHost, 0)
+	if strings.TrimSpace(spec) == "" {
+		return hops, nil
+	}
+	for _, raw := range strings.Split(spec, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return nil, errors.New("Empty jump host in " + spec)
+		}
+
+		hop := JumpHost{Port: "22"}
+		if i := strings.Index(raw, "="); i != -1 {
+			hop.KeyPath = raw[i+1:]
+			raw = raw[:i]
+		}
+		if i := strings.LastIndex(raw, "@"); i != -1 {
+			hop.User = raw[:i]
+			raw = raw[i+1:]
+		}
+		if host, port, err := net.SplitHostPort(raw); err == nil {
+			hop.Address = host
+			hop.Port = port
+		} else {
+			hop.Address = raw
+		}
+		if hop.Address == "" {
+			return nil, fmt.Errorf("No address for jump host %s", raw)
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// FormatJumpHosts is the reverse of ParseJumpHosts
+func FormatJumpHosts(hops []JumpHost) string {
+	parts := make([]string, len(hops))
+	for i, hop := range hops {
+		parts[i] = hop.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (j JumpHost) String() string {
+	s := net.JoinHostPort(j.Address, j.Port)
+	if j.User != "" {
+		s = j.User + "@" + s
+	}
+	if j.KeyPath != "" {
+		s += "=" + j.KeyPath
+	}
+	return s
+}
+
+// DialJump opens an SSH connection to addr, crossing every hop in order.
+// config is used for the final host and as a fallback for the hops.
+// The connections to the hops are closed with the returned client
+func DialJump(hops []JumpHost, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var client *ssh.Client
+	opened := make([]*ssh.Client, 0, len(hops))
+	closeAll := func() {
+		for i := len(opened) - 1; i >= 0; i-- {
+			opened[i].Close()
+		}
+	}
+
+	for _, hop := range hops {
+		hopConfig := &ssh.ClientConfig{
+			User:            config.User,
+			Auth:            config.Auth,
+			HostKeyCallback: config.HostKeyCallback,
+		}
+		if hop.User != "" {
+			hopConfig.User = hop.User
+		}
+		if hop.KeyPath != "" {
+			auth, err := PublicKeyFile(hop.KeyPath)
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("Cannot load key of jump host %s : %s", hop.Address, err)
+			}
+			hopConfig.Auth = []ssh.AuthMethod{auth}
+		}
+		next, err := dialVia(client, net.JoinHostPort(hop.Address, hop.Port), hopConfig)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("Cannot reach jump host %s : %s", hop.Address, err)
+		}
+		opened = append(opened, next)
+		client = next
+	}
+
+	target, err := dialVia(client, addr, config)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	if len(opened) > 0 {
+		go func() {
+			target.Wait()
+			closeAll()
+		}()
+	}
+	return target, nil
+}
+
+func dialVia(via *ssh.Client, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, config)
+	}
+	conn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
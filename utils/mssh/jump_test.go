@@ -0,0 +1,204 @@
+package mssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type testSSHServer struct {
+	Addr string
+	// Closed receives once per client connection, when it is closed
+	Closed chan struct{}
+}
+
+// newTestSSHServer runs an SSH server forwarding direct-tcpip channels and running every exec request as a no-op.
+// authorized is the only key accepted, any client is accepted when it is nil
+func newTestSSHServer(t *testing.T, authorized ssh.PublicKey) *testSSHServer {
+	_, hostKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating host key : %s\r\n", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: authorized == nil}
+	config.PublicKeyCallback = func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+		if authorized != nil && string(key.Marshal()) == string(authorized.Marshal()) {
+			return nil, nil
+		}
+		return nil, io.EOF
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while listening : %s\r\n", err)
+	}
+	server := &testSSHServer{Addr: listener.Addr().String(), Closed: make(chan struct{}, 10)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		for newChan := range chans {
+			switch newChan.ChannelType() {
+			case "direct-tcpip":
+				var target struct {
+					Host     string
+					Port     uint32
+					OrigHost string
+					OrigPort uint32
+				}
+				ssh.Unmarshal(newChan.ExtraData(), &target)
+				remote, err := net.Dial("tcp", net.JoinHostPort(target.Host, fmt.Sprint(target.Port)))
+				if err != nil {
+					newChan.Reject(ssh.ConnectionFailed, err.Error())
+					continue
+				}
+				channel, requests, _ := newChan.Accept()
+				go ssh.DiscardRequests(requests)
+				go func() {
+					io.Copy(channel, remote)
+					channel.Close()
+				}()
+				go func() {
+					io.Copy(remote, channel)
+					remote.Close()
+				}()
+			case "session":
+				channel, requests, _ := newChan.Accept()
+				go func() {
+					for req := range requests {
+						req.Reply(req.Type == "exec", nil)
+						if req.Type == "exec" {
+							channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+							channel.Close()
+						}
+					}
+				}()
+			default:
+				newChan.Reject(ssh.UnknownChannelType, "")
+			}
+		}
+	}()
+	serverConn.Wait()
+	s.Closed <- struct{}{}
+}
+
+func hostPort(t *testing.T, addr string) (string, string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("Got an unexpected error while splitting %s : %s\r\n", addr, err)
+	}
+	return host, port
+}
+
+func TestParseJumpHosts(t *testing.T) {
+	hops, err := ParseJumpHosts("bastion.example.com,admin@10.0.0.2:2222=/home/me/.ssh/inner")
+	if err != nil {
+		t.Errorf("Got an unexpected error while ParseJumpHosts : %s\r\n", err)
+	}
+	if len(hops) != 2 {
+		t.Fatalf("Expected 2 hops, got %d\r\n", len(hops))
+	}
+
+	if hops[0].Address != "bastion.example.com" || hops[0].Port != "22" || hops[0].User != "" || hops[0].KeyPath != "" {
+		t.Errorf("Got an unexpected first hop : %#v\r\n", hops[0])
+	}
+	if hops[1].Address != "10.0.0.2" || hops[1].Port != "2222" || hops[1].User != "admin" || hops[1].KeyPath != "/home/me/.ssh/inner" {
+		t.Errorf("Got an unexpected second hop : %#v\r\n", hops[1])
+	}
+
+	formatted := FormatJumpHosts(hops)
+	if formatted != "bastion.example.com:22,admin@10.0.0.2:2222=/home/me/.ssh/inner" {
+		t.Errorf("Got an unexpected result while FormatJumpHosts : %s\r\n", formatted)
+	}
+
+	hops, err = ParseJumpHosts("")
+	if err != nil || len(hops) != 0 {
+		t.Errorf("Got an unexpected result while ParseJumpHosts (empty)\r\n")
+	}
+
+	_, err = ParseJumpHosts("bastion,,other")
+	if err == nil {
+		t.Errorf("Got no error while an Error was expected (empty hop)")
+	}
+
+	_, err = ParseJumpHosts("root@:22")
+	if err == nil {
+		t.Errorf("Got no error while an Error was expected (no address)")
+	}
+}
+
+func TestDialJumpClosesHops(t *testing.T) {
+	bastion := newTestSSHServer(t, nil)
+	target := newTestSSHServer(t, nil)
+	host, port := hostPort(t, bastion.Addr)
+
+	client, err := DialJump([]JumpHost{{Address: host, Port: port}}, target.Addr, &ssh.ClientConfig{
+		User:            "mikrodock",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("Got an unexpected error while DialJump : %s\r\n", err)
+	}
+	client.Close()
+
+	select {
+	case <-bastion.Closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("The connection to the jump host was not closed with the client\r\n")
+	}
+}
+
+func TestCheckAuthHopKey(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "mikrodock-jump")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating temp dir : %s\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+	currentKey, newKey := path.Join(dir, "private_key"), path.Join(dir, "private_key.new")
+	publicKeys := make([]ssh.PublicKey, 0)
+	for _, file := range []string{currentKey, newKey} {
+		if err = CreateKey(file, ED25519, nil); err != nil {
+			t.Fatalf("Got an unexpected error while CreateKey : %s\r\n", err)
+		}
+		signer, _ := LoadPrivateKey(file)
+		publicKeys = append(publicKeys, signer.PublicKey())
+	}
+
+	// The bastion only knows the current key, the target already has the new one
+	bastion := newTestSSHServer(t, publicKeys[0])
+	target := newTestSSHServer(t, publicKeys[1])
+	host, port := hostPort(t, bastion.Addr)
+	hops := []JumpHost{{Address: host, Port: port}}
+
+	if err = CheckAuth(hops, target.Addr, "root", newKey, currentKey); err != nil {
+		t.Errorf("Got an unexpected error while CheckAuth : %s\r\n", err)
+	}
+	if err = CheckAuth(hops, target.Addr, "root", newKey, newKey); err == nil {
+		t.Errorf("Got no error while an Error was expected (new key on the bastion)\r\n")
+	}
+}
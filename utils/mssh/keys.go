@@ -1,16 +1,44 @@
 package mssh
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"fmt"
 	"io/ioutil"
 	"os"
 
 	"golang.org/x/crypto/ssh"
 )
 
+// KeyType is the algorithm of a generated SSH key
+type KeyType string
+
+const (
+	RSA     KeyType = "rsa"
+	ECDSA   KeyType = "ecdsa"
+	ED25519 KeyType = "ed25519"
+)
+
+// ParseKeyType returns the KeyType matching name, RSA when name is empty
+func ParseKeyType(name string) (KeyType, error) {
+	switch KeyType(name) {
+	case "", RSA:
+		return RSA, nil
+	case ECDSA:
+		return ECDSA, nil
+	case ED25519:
+		return ED25519, nil
+	default:
+		return "", fmt.Errorf("Unknown SSH key type %s", name)
+	}
+}
+
 func LoadPrivateKey(file string) (ssh.Signer, error) {
 	buf, err := ioutil.ReadFile(file)
 	if err != nil {
@@ -18,6 +46,18 @@ func LoadPrivateKey(file string) (ssh.Signer, error) {
 	}
 
 	key, err := ssh.ParsePrivateKey(buf)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok {
+		passphrase, err := ReadPassphrase(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err = ssh.ParsePrivateKeyWithPassphrase(buf, passphrase)
+		if err != nil {
+			forgetPassphrase(file)
+			return nil, err
+		}
+		return key, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -25,35 +65,70 @@ func LoadPrivateKey(file string) (ssh.Signer, error) {
 	return key, nil
 }
 
+// IsEncryptedKey tells if the private key file needs a passphrase, whatever its format (OpenSSH or PEM)
+func IsEncryptedKey(file string) (bool, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	_, err = ssh.ParseRawPrivateKey(buf)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
 func ComputePublicFingerprint(privKey ssh.Signer) string {
 	return ssh.FingerprintLegacyMD5(privKey.PublicKey())
 }
 
 func CreatePrivateKey(privKeyFile string) error {
+	return CreateKey(privKeyFile, RSA, nil)
+}
+
+// CreateKey generates a private key of the given type, encrypted when passphrase is not empty
+func CreateKey(privKeyFile string, keyType KeyType, passphrase []byte) error {
 	// We only need to save the private key because the public key can be extracted from there
-	reader := rand.Reader
-	bitSize := 2048
-	key, err := rsa.GenerateKey(reader, bitSize)
+	var key crypto.PrivateKey
+	var err error
+	switch keyType {
+	case RSA:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ECDSA:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ED25519:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return fmt.Errorf("Unknown SSH key type %s", keyType)
+	}
 	if err != nil {
 		return err
 	}
 
-	outFile, err := os.Create(privKeyFile)
-	outFile.Chmod(os.FileMode(0600))
+	var privateKey *pem.Block
+	switch {
+	case len(passphrase) != 0:
+		privateKey, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", passphrase)
+	case keyType == RSA:
+		privateKey = &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key.(*rsa.PrivateKey)),
+		}
+	default:
+		privateKey, err = ssh.MarshalPrivateKey(key, "")
+	}
 	if err != nil {
 		return err
 	}
-	defer outFile.Close()
 
-	var privateKey = &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(key),
-	}
-
-	err = pem.Encode(outFile, privateKey)
+	outFile, err := os.OpenFile(privKeyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
+	defer outFile.Close()
 
-	return nil
+	return pem.Encode(outFile, privateKey)
 }
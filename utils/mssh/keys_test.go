@@ -61,3 +61,66 @@ func TestComputePublicFingerprint(t *testing.T) {
 	}
 	ComputePublicFingerprint(signer)
 }
+
+func TestCreateKey(t *testing.T) {
+	for _, keyType := range []KeyType{RSA, ECDSA, ED25519} {
+		err := CreateKey("/tmp/key.key", keyType, nil)
+		if err != nil {
+			t.Errorf("Got an unexpected error while CreateKey (%s) : %s\r\n", keyType, err)
+		}
+
+		_, err = LoadPrivateKey("/tmp/key.key")
+		if err != nil {
+			t.Errorf("Got an unexpected error while LoadPrivateKey (%s) : %s\r\n", keyType, err)
+		}
+		os.Remove("/tmp/key.key")
+	}
+
+	err := CreateKey("/tmp/key.key", KeyType("dsa"), nil)
+	if err == nil {
+		t.Errorf("Got no error while an Error was expected (dsa)")
+	}
+}
+
+func TestLoadEncryptedPrivateKey(t *testing.T) {
+	err := CreateKey("/tmp/key.key", ED25519, []byte("secret"))
+	if err != nil {
+		t.Errorf("Got an unexpected error while CreateKey : %s\r\n", err)
+	}
+	defer os.Remove("/tmp/key.key")
+	defer os.Unsetenv(PassphraseEnv)
+
+	os.Setenv(PassphraseEnv, "secret")
+	_, err = LoadPrivateKey("/tmp/key.key")
+	if err != nil {
+		t.Errorf("Got an unexpected error while LoadPrivateKey : %s\r\n", err)
+	}
+
+	os.Setenv(PassphraseEnv, "wrong")
+	_, err = LoadPrivateKey("/tmp/key.key")
+	if err == nil {
+		t.Errorf("Got no error while an Error was expected (wrong passphrase)")
+	}
+}
+
+func TestIsEncryptedKey(t *testing.T) {
+	defer os.Remove("/tmp/key.key")
+	for _, keyType := range []KeyType{RSA, ED25519} {
+		for _, passphrase := range []string{"", "secret"} {
+			if err := CreateKey("/tmp/key.key", keyType, []byte(passphrase)); err != nil {
+				t.Fatalf("Got an unexpected error while CreateKey : %s\r\n", err)
+			}
+			encrypted, err := IsEncryptedKey("/tmp/key.key")
+			if err != nil {
+				t.Errorf("Got an unexpected error while IsEncryptedKey : %s\r\n", err)
+			}
+			if encrypted != (passphrase != "") {
+				t.Errorf("Expected encrypted %t for a %s key, got %t\r\n", passphrase != "", keyType, encrypted)
+			}
+		}
+	}
+
+	if _, err := IsEncryptedKey("/tmp/nokey.key"); err == nil {
+		t.Errorf("Got no error while an Error was expected (missing)")
+	}
+}
@@ -0,0 +1,66 @@
+package mssh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// PassphraseEnv is the environment variable read before prompting for a key passphrase
+const PassphraseEnv = "MIKRODOCK_SSH_PASSPHRASE"
+
+var passphrases = make(map[string][]byte)
+var passphrasesLock sync.Mutex
+
+// ReadPassphrase returns the passphrase of an encrypted key file.
+// It comes from PassphraseEnv when set, otherwise the user is prompted once per file
+func ReadPassphrase(file string) ([]byte, error) {
+	if env := os.Getenv(PassphraseEnv); env != "" {
+		return []byte(env), nil
+	}
+
+	passphrasesLock.Lock()
+	defer passphrasesLock.Unlock()
+
+	if passphrase, ok := passphrases[file]; ok {
+		return passphrase, nil
+	}
+
+	passphrase, err := PromptPassphrase(fmt.Sprintf("Passphrase for %s: ", file))
+	if err != nil {
+		return nil, err
+	}
+	passphrases[file] = passphrase
+	return passphrase, nil
+}
+
+// PromptPassphrase reads a passphrase on the terminal without echoing it
+func PromptPassphrase(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("The key is encrypted and no terminal is available, set " + PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	return passphrase, nil
+}
+
+// RememberPassphrase sets the passphrase of a key file, it is not prompted for afterwards
+func RememberPassphrase(file string, passphrase []byte) {
+	passphrasesLock.Lock()
+	passphrases[file] = passphrase
+	passphrasesLock.Unlock()
+}
+
+func forgetPassphrase(file string) {
+	passphrasesLock.Lock()
+	delete(passphrases, file)
+	passphrasesLock.Unlock()
+}
@@ -0,0 +1,232 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mikrodock-cli/utils/mssh"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+)
+
+// cipher encrypts the whole secrets file
+type cipher interface {
+	encrypt(plain []byte) ([]byte, error)
+	decrypt(encrypted []byte) ([]byte, error)
+}
+
+// FileProvider stores the secrets as an encrypted JSON object
+type FileProvider struct {
+	File   string
+	ref    string
+	cipher cipher
+	lock   sync.Mutex
+}
+
+// NewAgeProvider encrypts file with the age identity file, or with a passphrase when identity is empty
+func NewAgeProvider(file string, identity string) *FileProvider {
+	ref := "age:" + file
+	if identity != "" {
+		ref += ":" + identity
+	}
+	return &FileProvider{File: file, ref: ref, cipher: &ageCipher{file: file, identityFile: identity}}
+}
+
+// NewGPGProvider encrypts file for the gpg recipient, the gpg binary must be installed
+func NewGPGProvider(file string, recipient string) *FileProvider {
+	return &FileProvider{File: file, ref: "gpg:" + file + ":" + recipient, cipher: &gpgCipher{file: file, recipient: recipient}}
+}
+
+func (f *FileProvider) Get(name string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[name]
+	if !ok {
+		return "", fmt.Errorf("Secret %s not found in %s", name, f.File)
+	}
+	return value, nil
+}
+
+func (f *FileProvider) Set(name string, value string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return err
+	}
+	values[name] = value
+	return f.save(values)
+}
+
+func (f *FileProvider) Delete(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	values, err := f.load()
+	if err != nil {
+		return err
+	}
+	delete(values, name)
+	return f.save(values)
+}
+
+func (f *FileProvider) Ref() string {
+	return f.ref
+}
+
+func (f *FileProvider) load() (map[string]string, error) {
+	values := make(map[string]string)
+	encrypted, err := ioutil.ReadFile(f.File)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	plain, err := f.cipher.decrypt(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot decrypt %s : %s", f.File, err)
+	}
+	if err = json.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("Cannot read %s : %s", f.File, err)
+	}
+	return values, nil
+}
+
+func (f *FileProvider) save(values map[string]string) error {
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	encrypted, err := f.cipher.encrypt(plain)
+	if err != nil {
+		return fmt.Errorf("Cannot encrypt %s : %s", f.File, err)
+	}
+	if err = os.MkdirAll(filepath.Dir(f.File), 0700); err != nil {
+		return err
+	}
+	tmp := f.File + ".tmp"
+	if err = ioutil.WriteFile(tmp, encrypted, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.File)
+}
+
+type ageCipher struct {
+	file         string
+	identityFile string
+	passphrase   string
+}
+
+func (a *ageCipher) keys() ([]age.Identity, []age.Recipient, error) {
+	if a.identityFile != "" {
+		file, err := os.Open(a.identityFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+		identities, err := age.ParseIdentities(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		recipients := make([]age.Recipient, 0, len(identities))
+		for _, identity := range identities {
+			if x, ok := identity.(*age.X25519Identity); ok {
+				recipients = append(recipients, x.Recipient())
+			}
+		}
+		if len(recipients) == 0 {
+			return nil, nil, errors.New("No X25519 identity in " + a.identityFile)
+		}
+		return identities, recipients, nil
+	}
+
+	if a.passphrase == "" {
+		a.passphrase = os.Getenv(PassphraseEnv)
+	}
+	if a.passphrase == "" {
+		passphrase, err := mssh.PromptPassphrase(fmt.Sprintf("Passphrase for %s: ", a.file))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s (or set %s)", err, PassphraseEnv)
+		}
+		a.passphrase = string(passphrase)
+	}
+	identity, err := age.NewScryptIdentity(a.passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	recipient, err := age.NewScryptRecipient(a.passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []age.Identity{identity}, []age.Recipient{recipient}, nil
+}
+
+func (a *ageCipher) encrypt(plain []byte) ([]byte, error) {
+	_, recipients, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	w, err := age.Encrypt(&out, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (a *ageCipher) decrypt(encrypted []byte) ([]byte, error) {
+	identities, _, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(bytes.NewReader(encrypted), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+type gpgCipher struct {
+	file      string
+	recipient string
+}
+
+func (g *gpgCipher) run(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("gpg", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg : %s %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (g *gpgCipher) encrypt(plain []byte) ([]byte, error) {
+	return g.run(plain, "--batch", "--yes", "--quiet", "--trust-model", "always", "--encrypt", "--recipient", g.recipient, "--output", "-")
+}
+
+func (g *gpgCipher) decrypt(encrypted []byte) ([]byte, error) {
+	return g.run(encrypted, "--quiet", "--decrypt", "--output", "-")
+}
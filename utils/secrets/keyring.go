@@ -0,0 +1,30 @@
+package secrets
+
+import (
+	"github.com/zalando/go-keyring"
+)
+
+// KeyringProvider stores the secrets in the OS keyring (Secret Service, macOS Keychain, Windows Credential Manager)
+type KeyringProvider struct {
+	Service string
+}
+
+func (k *KeyringProvider) Get(name string) (string, error) {
+	return keyring.Get(k.Service, name)
+}
+
+func (k *KeyringProvider) Set(name string, value string) error {
+	return keyring.Set(k.Service, name, value)
+}
+
+func (k *KeyringProvider) Delete(name string) error {
+	err := keyring.Delete(k.Service, name)
+	if err == keyring.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+func (k *KeyringProvider) Ref() string {
+	return "keyring"
+}
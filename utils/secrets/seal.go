@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// GenerateSealKey returns a new random AES-256 key, hex encoded
+func GenerateSealKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func newGCM(key string) (gocipher.AEAD, error) {
+	raw, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return gocipher.NewGCM(block)
+}
+
+// Seal encrypts plain with AES-GCM, the nonce is prepended to the result
+func Seal(key string, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// Unseal decrypts a value encrypted by Seal
+func Unseal(key string, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("Sealed value is too short")
+	}
+	nonce, encrypted := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, encrypted, nil)
+}
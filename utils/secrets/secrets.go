@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AccessToken is the secret of the driver API token
+const AccessToken = "access-token"
+
+// PassphraseEnv is read before prompting for the passphrase of an age file
+const PassphraseEnv = "MIKRODOCK_SECRETS_PASSPHRASE"
+
+// ErrUnknownProvider is returned by Open when ref is not a provider reference
+var ErrUnknownProvider = errors.New("Unknown secret provider")
+
+// Provider stores the secrets of a cluster outside of its data.mk
+type Provider interface {
+	Get(name string) (string, error)
+	Set(name string, value string) error
+	Delete(name string) error
+	// Ref is written in data.mk to open the provider again
+	Ref() string
+}
+
+// Open returns the provider of ref :
+//
+//	keyring                     the OS keyring
+//	env                         environment variables, see EnvName
+//	age:<file>[:<identity>]     a file encrypted with age, with a passphrase or an identity file
+//	gpg:<file>:<recipient>      a file encrypted with gpg for recipient
+func Open(ref string, cluster string) (Provider, error) {
+	parts := strings.SplitN(ref, ":", 3)
+	switch parts[0] {
+	case "keyring":
+		if len(parts) != 1 {
+			break
+		}
+		return &KeyringProvider{Service: "mikrodock-" + cluster}, nil
+	case "env":
+		if len(parts) != 1 {
+			break
+		}
+		return &EnvProvider{}, nil
+	case "age":
+		if len(parts) < 2 || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid secret provider %s : age:<file>[:<identity>]", ref)
+		}
+		identity := ""
+		if len(parts) == 3 {
+			identity = parts[2]
+		}
+		return NewAgeProvider(parts[1], identity), nil
+	case "gpg":
+		if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("Invalid secret provider %s : gpg:<file>:<recipient>", ref)
+		}
+		return NewGPGProvider(parts[1], parts[2]), nil
+	}
+	return nil, ErrUnknownProvider
+}
+
+// EnvName is the environment variable of a secret, access-token is read from MIKRODOCK_ACCESS_TOKEN
+func EnvName(name string) string {
+	return "MIKRODOCK_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// EnvProvider reads the secrets from the environment, it cannot store new ones
+type EnvProvider struct{}
+
+func (e *EnvProvider) Get(name string) (string, error) {
+	value := os.Getenv(EnvName(name))
+	if value == "" {
+		return "", fmt.Errorf("%s is not set", EnvName(name))
+	}
+	return value, nil
+}
+
+// Set only checks that the environment already holds value
+func (e *EnvProvider) Set(name string, value string) error {
+	if os.Getenv(EnvName(name)) != value {
+		return fmt.Errorf("The env secret provider cannot store secrets, export %s", EnvName(name))
+	}
+	return nil
+}
+
+func (e *EnvProvider) Delete(name string) error {
+	return nil
+}
+
+func (e *EnvProvider) Ref() string {
+	return "env"
+}
+
+// PlainProvider is the access token written in clear in the data.mk of older clusters
+type PlainProvider struct {
+	Token string
+}
+
+func (p *PlainProvider) Get(name string) (string, error) {
+	if name != AccessToken {
+		return "", fmt.Errorf("Secret %s not found, the cluster has no secret provider", name)
+	}
+	return p.Token, nil
+}
+
+func (p *PlainProvider) Set(name string, value string) error {
+	return errors.New("The cluster has no secret provider, run credentials migrate first")
+}
+
+func (p *PlainProvider) Delete(name string) error {
+	return p.Set(name, "")
+}
+
+func (p *PlainProvider) Ref() string {
+	return p.Token
+}
+
+// Migrate copies the secrets of names from one provider to the other
+func Migrate(from Provider, to Provider, names []string) error {
+	for _, name := range names {
+		value, err := from.Get(name)
+		if err != nil {
+			return fmt.Errorf("Cannot read %s : %s", name, err)
+		}
+		if err = to.Set(name, value); err != nil {
+			return fmt.Errorf("Cannot store %s : %s", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestOpen(t *testing.T) {
+	refs := map[string]string{
+		"keyring":                        "keyring",
+		"env":                            "env",
+		"age:/tmp/s.age":                 "age:/tmp/s.age",
+		"age:/tmp/s.age:/tmp/id":         "age:/tmp/s.age:/tmp/id",
+		"gpg:/tmp/s.gpg:ops@example.com": "gpg:/tmp/s.gpg:ops@example.com",
+	}
+	for ref, expected := range refs {
+		provider, err := Open(ref, "test")
+		if err != nil {
+			t.Errorf("Got an unexpected error while opening %s : %s\r\n", ref, err)
+			continue
+		}
+		if provider.Ref() != expected {
+			t.Errorf("Expected ref %s, got %s\r\n", expected, provider.Ref())
+		}
+	}
+
+	if _, err := Open("dop_v1_0123456789abcdef", "test"); err != ErrUnknownProvider {
+		t.Errorf("Expected ErrUnknownProvider for a plain token, got %v\r\n", err)
+	}
+	for _, ref := range []string{"age:", "gpg:/tmp/s.gpg", "keyring:x"} {
+		if _, err := Open(ref, "test"); err == nil {
+			t.Errorf("Got no error while an Error was expected (%s)\r\n", ref)
+		}
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	os.Setenv("MIKRODOCK_ACCESS_TOKEN", "abc")
+	defer os.Unsetenv("MIKRODOCK_ACCESS_TOKEN")
+
+	provider := &EnvProvider{}
+	if value, err := provider.Get(AccessToken); err != nil || value != "abc" {
+		t.Errorf("Expected abc, got %s %v\r\n", value, err)
+	}
+	if err := provider.Set(AccessToken, "abc"); err != nil {
+		t.Errorf("Got an unexpected error while setting the env value : %s\r\n", err)
+	}
+	if err := provider.Set(AccessToken, "other"); err == nil {
+		t.Errorf("Got no error while an Error was expected (other value)\r\n")
+	}
+	if _, err := provider.Get("gossip-key"); err == nil {
+		t.Errorf("Got no error while an Error was expected (unset)\r\n")
+	}
+}
+
+func TestAgeProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "mikrodock-secrets")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while creating temp dir : %s\r\n", err)
+	}
+	defer os.RemoveAll(dir)
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("Got an unexpected error while generating identity : %s\r\n", err)
+	}
+	identityFile := path.Join(dir, "identity")
+	ioutil.WriteFile(identityFile, []byte(identity.String()+"\n"), 0600)
+
+	os.Setenv(PassphraseEnv, "correct horse battery staple")
+	defer os.Unsetenv(PassphraseEnv)
+
+	providers := map[string]Provider{
+		"passphrase": NewAgeProvider(path.Join(dir, "pass.age"), ""),
+		"identity":   NewAgeProvider(path.Join(dir, "id.age"), identityFile),
+	}
+	for name, provider := range providers {
+		if err = provider.Set(AccessToken, "secret-token"); err != nil {
+			t.Fatalf("[%s] Got an unexpected error while setting : %s\r\n", name, err)
+		}
+		if err = provider.Set("other", "value"); err != nil {
+			t.Fatalf("[%s] Got an unexpected error while setting : %s\r\n", name, err)
+		}
+
+		file := provider.(*FileProvider).File
+		raw, _ := ioutil.ReadFile(file)
+		if strings.Contains(string(raw), "secret-token") {
+			t.Errorf("[%s] The secret is stored in clear\r\n", name)
+		}
+		if stat, _ := os.Stat(file); stat.Mode().Perm() != 0600 {
+			t.Errorf("[%s] Expected mode 0600, got %o\r\n", name, stat.Mode().Perm())
+		}
+
+		// A new provider reads the file again
+		again, _ := Open(provider.Ref(), "test")
+		if value, err := again.Get(AccessToken); err != nil || value != "secret-token" {
+			t.Errorf("[%s] Expected secret-token, got %s %v\r\n", name, value, err)
+		}
+		if err = again.Delete("other"); err != nil {
+			t.Errorf("[%s] Got an unexpected error while deleting : %s\r\n", name, err)
+		}
+		if _, err = again.Get("other"); err == nil {
+			t.Errorf("[%s] Got no error while an Error was expected (deleted)\r\n", name)
+		}
+	}
+
+	os.Setenv(PassphraseEnv, "wrong")
+	if _, err = NewAgeProvider(path.Join(dir, "pass.age"), "").Get(AccessToken); err == nil {
+		t.Errorf("Got no error while an Error was expected (wrong passphrase)\r\n")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	os.Setenv("MIKRODOCK_ACCESS_TOKEN", "abc")
+	defer os.Unsetenv("MIKRODOCK_ACCESS_TOKEN")
+
+	from := &PlainProvider{Token: "abc"}
+	if err := Migrate(from, &EnvProvider{}, []string{AccessToken}); err != nil {
+		t.Errorf("Got an unexpected error while migrating : %s\r\n", err)
+	}
+	if err := Migrate(&PlainProvider{Token: "other"}, &EnvProvider{}, []string{AccessToken}); err == nil {
+		t.Errorf("Got no error while an Error was expected (env mismatch)\r\n")
+	}
+}
+
+func TestSeal(t *testing.T) {
+	key, err := GenerateSealKey()
+	if err != nil {
+		t.Fatalf("Got an unexpected error while generating key : %s\r\n", err)
+	}
+	sealed, err := Seal(key, []byte("s3cr3t"))
+	if err != nil {
+		t.Fatalf("Got an unexpected error while sealing : %s\r\n", err)
+	}
+	if strings.Contains(string(sealed), "s3cr3t") {
+		t.Errorf("Sealed value contains the plain text\r\n")
+	}
+	plain, err := Unseal(key, sealed)
+	if err != nil || string(plain) != "s3cr3t" {
+		t.Errorf("Got an unexpected value while unsealing : %s %v\r\n", plain, err)
+	}
+
+	other, _ := GenerateSealKey()
+	if _, err = Unseal(other, sealed); err == nil {
+		t.Errorf("Got no error while an Error was expected (wrong key)\r\n")
+	}
+	if _, err = Unseal(key, sealed[:4]); err == nil {
+		t.Errorf("Got no error while an Error was expected (short value)\r\n")
+	}
+}
@@ -0,0 +1,96 @@
+package subnets
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Parse reads a CIDR, it must be the address of the network
+func Parse(cidr string) (*net.IPNet, error) {
+	ip, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CIDR %s : %s", cidr, err)
+	}
+	if !ip.Equal(subnet.IP) {
+		return nil, fmt.Errorf("Invalid CIDR %s, the network address is %s", cidr, subnet.String())
+	}
+	return subnet, nil
+}
+
+// broadcast returns the last address of the subnet
+func broadcast(subnet *net.IPNet) net.IP {
+	last := make(net.IP, len(subnet.IP))
+	for i := range subnet.IP {
+		last[i] = subnet.IP[i] | ^subnet.Mask[i]
+	}
+	return last
+}
+
+// DefaultGateway returns the first host address of the subnet, as Docker does
+func DefaultGateway(subnet *net.IPNet) string {
+	gateway := make(net.IP, len(subnet.IP))
+	copy(gateway, subnet.IP)
+	gateway[len(gateway)-1]++
+	return gateway.String()
+}
+
+// ValidateGateway checks that the gateway is a host address of the subnet
+func ValidateGateway(subnet *net.IPNet, gateway string) error {
+	ip := net.ParseIP(gateway)
+	if ip == nil {
+		return fmt.Errorf("Invalid gateway %s", gateway)
+	}
+	if ip4 := ip.To4(); ip4 != nil && len(subnet.IP) == net.IPv4len {
+		ip = ip4
+	}
+	if !subnet.Contains(ip) {
+		return fmt.Errorf("The gateway %s is not in %s", gateway, subnet.String())
+	}
+	if ip.Equal(subnet.IP) || ip.Equal(broadcast(subnet)) {
+		return fmt.Errorf("The gateway %s is not a host address of %s", gateway, subnet.String())
+	}
+	return nil
+}
+
+// Overlaps tells if two subnets share addresses
+func Overlaps(a *net.IPNet, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// FindOverlap returns the first owner, by name, of a subnet overlapping with subnet
+func FindOverlap(subnet *net.IPNet, used map[string][]*net.IPNet) (string, *net.IPNet, bool) {
+	owners := make([]string, 0, len(used))
+	for owner := range used {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	for _, owner := range owners {
+		for _, other := range used[owner] {
+			if Overlaps(subnet, other) {
+				return owner, other, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// ParseHostSubnets reads the subnets of the output of ip -o addr show, the loopback is skipped
+func ParseHostSubnets(output string) []*net.IPNet {
+	var subnets []*net.IPNet
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] != "inet" && fields[i] != "inet6" {
+				continue
+			}
+			ip, subnet, err := net.ParseCIDR(fields[i+1])
+			if err == nil && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() {
+				subnets = append(subnets, subnet)
+			}
+			break
+		}
+	}
+	return subnets
+}
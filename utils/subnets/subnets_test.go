@@ -0,0 +1,75 @@
+package subnets
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	subnet, err := Parse("172.143.0.0/16")
+	if err != nil {
+		t.Fatalf("Got an unexpected error while parsing the CIDR : %s\r\n", err)
+	}
+	if gw := DefaultGateway(subnet); gw != "172.143.0.1" {
+		t.Errorf("Expected the gateway 172.143.0.1, got %s\r\n", gw)
+	}
+	if _, err = Parse("172.143.0.1/16"); err == nil {
+		t.Errorf("Got no error while an error was expected (host address)\r\n")
+	}
+	if _, err = Parse("172.143.0.0"); err == nil {
+		t.Errorf("Got no error while an error was expected (no mask)\r\n")
+	}
+}
+
+func TestValidateGateway(t *testing.T) {
+	subnet, _ := Parse("10.20.0.0/24")
+	if err := ValidateGateway(subnet, "10.20.0.254"); err != nil {
+		t.Errorf("Got an unexpected error while validating the gateway : %s\r\n", err)
+	}
+	for _, gateway := range []string{"10.20.1.1", "10.20.0.0", "10.20.0.255", "gateway"} {
+		if err := ValidateGateway(subnet, gateway); err == nil {
+			t.Errorf("Got no error while an error was expected (%s)\r\n", gateway)
+		}
+	}
+}
+
+func TestFindOverlap(t *testing.T) {
+	_, mikroverlay, _ := net.ParseCIDR("172.142.0.0/16")
+	_, host, _ := net.ParseCIDR("10.0.0.0/24")
+	used := map[string][]*net.IPNet{
+		"network mikroverlay": {mikroverlay},
+		"host klerk":          {host},
+	}
+
+	subnet, _ := Parse("172.142.10.0/24")
+	owner, other, ok := FindOverlap(subnet, used)
+	if !ok || owner != "network mikroverlay" || other != mikroverlay {
+		t.Errorf("Expected an overlap with mikroverlay, got %s %v\r\n", owner, other)
+	}
+	subnet, _ = Parse("10.0.0.0/8")
+	if owner, _, ok = FindOverlap(subnet, used); !ok || owner != "host klerk" {
+		t.Errorf("Expected an overlap with the klerk, got %s\r\n", owner)
+	}
+	subnet, _ = Parse("172.143.0.0/16")
+	if owner, _, ok = FindOverlap(subnet, used); ok {
+		t.Errorf("Got an unexpected overlap with %s\r\n", owner)
+	}
+}
+
+func TestParseHostSubnets(t *testing.T) {
+	output := `1: lo    inet 127.0.0.1/8 scope host lo\       valid_lft forever preferred_lft forever
+2: eth0    inet 159.89.10.20/20 brd 159.89.15.255 scope global eth0\       valid_lft forever preferred_lft forever
+2: eth0    inet 10.17.0.5/16 brd 10.17.255.255 scope global eth0\       valid_lft forever preferred_lft forever
+3: docker0    inet 172.17.0.1/16 scope global docker0\       valid_lft forever preferred_lft forever
+`
+	subnets := ParseHostSubnets(output)
+	expected := []string{"159.89.0.0/20", "10.17.0.0/16", "172.17.0.0/16"}
+	if len(subnets) != len(expected) {
+		t.Fatalf("Expected %v, got %v\r\n", expected, subnets)
+	}
+	for i, subnet := range subnets {
+		if subnet.String() != expected[i] {
+			t.Errorf("Expected %s, got %s\r\n", expected[i], subnet)
+		}
+	}
+}